@@ -1,8 +1,12 @@
 package backend
 
 import (
+	"context"
 	"errors"
+	"maps"
 	"net"
+	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
@@ -10,17 +14,93 @@ import (
 // ErrBackendDown is returned when a backend is simulated down.
 var ErrBackendDown = errors.New("backend is down")
 
+// drainPollInterval is how often Drain checks for remaining active connections.
+const drainPollInterval = 100 * time.Millisecond
+
+// DownReason explains why a backend is not alive.
+type DownReason string
+
+const (
+	ReasonNone           DownReason = ""                // Backend is alive
+	ReasonSimulated      DownReason = "simulated"       // Backend is down due to simulation
+	ReasonPassiveFailure DownReason = "passive_failure" // A client connection failed to dial the backend
+	ReasonProbeFailure   DownReason = "probe_failure"   // The last health check failed
+	ReasonDraining       DownReason = "draining"        // Backend is draining and takes no new connections
+	ReasonBreakerOpen    DownReason = "breaker_open"    // Circuit breaker is open after repeated dial failures
+	ReasonOutlier        DownReason = "outlier"         // Ejected for a high error rate on proxied connections
+	ReasonBindFailed     DownReason = "bind_failed"     // The built-in echo server could not listen on the address
+)
+
 // Backend represents a backend server that receives proxied connections.
 type Backend struct {
-	Address          string                // The backend address in "host:port" format
-	Weight           int                   // Weight for weighted round-robin algorithm
-	Alive            bool                  // Whether the backend is currently healthy
-	SimulatedDown    bool                  // True if backend is down due to simulation (health check won't override)
-	connections      map[net.Conn]struct{} // Set of currently active connections
-	TotalConnections int64                 // Total connections handled (for stats)
-	LastHealthCheck  time.Time             // When the last health check was performed
-	mu               sync.RWMutex          // Protects all mutable fields above
-	cond             *sync.Cond            // Condition variable for simulating backend failure
+	Address           string                // The backend address in "host:port" format
+	HealthAddress     string                // Address health checks connect to, empty to use Address
+	Weight            int                   // Weight for weighted round-robin algorithm
+	Tags              []string              // Tags used by listeners to select a backend subset
+	Zone              string                // Zone or region the backend runs in, empty if unknown
+	Labels            map[string]string     // Arbitrary labels for organizing backends in stats and metrics
+	MaxConnections    int                   // Maximum simultaneous connections, 0 means unlimited
+	Cost              int                   // Static latency/cost hint, lower is preferred
+	Alive             bool                  // Whether the backend is currently healthy
+	SimulatedDown     bool                  // True if backend is down due to simulation (health check won't override)
+	Draining          bool                  // True if backend is draining and receives no new connections
+	downReason        DownReason            // Why the backend was last marked not alive
+	serverErr         error                 // Why the built-in echo server failed to start, if it did
+	connections       map[net.Conn]struct{} // Set of currently active connections
+	TotalConnections  int64                 // Total connections handled (for stats)
+	BytesSent         int64                 // Total bytes proxied from clients to this backend
+	BytesReceived     int64                 // Total bytes proxied from this backend to clients
+	IdleTimeouts      int64                 // Connections closed for being idle
+	LifetimeTimeouts  int64                 // Connections closed for exceeding their maximum lifetime
+	DialFailures      int64                 // Failed client connection dials
+	ReusedConnections int64                 // Client connections served over a pooled idle connection
+	LastHealthCheck   time.Time             // When the last health check was performed
+	LastResponseTime  time.Duration         // How long the last health check took
+
+	// Circuit breaker state
+	failureThreshold    int           // Consecutive dial failures that open the breaker, 0 disables it
+	breakerCooldown     time.Duration // How long the breaker stays open before a trial connection
+	consecutiveFailures int           // Dial failures since the last success
+	breakerState        BreakerState  // Current breaker state
+	breakerOpenedAt     time.Time     // When the breaker last opened
+	trialInFlight       bool          // Whether the half-open trial connection is in progress
+
+	dialer        Dialer // Opens backend connections, nil for the default net.Dialer
+	unhealthyHook func() // Called when a failed connection or health check marks a live backend dead
+
+	// Health check backoff state
+	probeFailures   int       // Consecutive failed health checks
+	nextHealthCheck time.Time // When the backend is next due a health check
+
+	healthClient     *http.Client // Sends HTTP health checks, created on first use
+	healthClientOnce sync.Once    // Guards creating healthClient
+
+	dialLatency LatencyHistogram // Successful dial durations, updated atomically without mu
+
+	// Outlier detection state
+	outlierRatio       float64       // Failure ratio beyond which the backend is ejected, 0 disables it
+	outlierWindow      time.Duration // How long results are counted before the counts reset
+	outlierEjection    time.Duration // How long an ejected backend is held out
+	outlierWindowStart time.Time     // When the current counting window started
+	windowSuccesses    int           // Connections completed successfully in the window
+	windowFailures     int           // Connections that failed mid-transfer in the window
+	ejectedUntil       time.Time     // When an ejected backend is reinstated
+
+	// Slow start state
+	slowStart   time.Duration // How long a recovered backend takes to reach full weight, 0 disables it
+	recoveredAt time.Time     // When the backend last went from dead to alive
+
+	// Idle connection pool
+	maxIdleConns int        // Finished connections kept open for reuse, 0 disables reuse
+	idleConns    []net.Conn // Idle connections, most recently used last
+
+	// Availability tracking
+	stateSince    time.Time     // When the backend entered its current alive/down state
+	healthyTime   time.Duration // Cumulative time spent alive, excluding the current state
+	unhealthyTime time.Duration // Cumulative time spent down, excluding the current state
+
+	mu   sync.RWMutex // Protects all mutable fields above
+	cond *sync.Cond   // Condition variable for simulating backend failure
 }
 
 // NewBackend creates a new Backend with the given address.
@@ -31,6 +111,7 @@ func NewBackend(address string) *Backend {
 		Alive:           true,
 		connections:     make(map[net.Conn]struct{}),
 		LastHealthCheck: time.Now(),
+		stateSince:      time.Now(),
 	}
 	b.cond = sync.NewCond(&b.mu)
 	return b
@@ -44,6 +125,7 @@ func NewBackendWithWeight(address string, weight int) *Backend {
 		Alive:           true,
 		connections:     make(map[net.Conn]struct{}),
 		LastHealthCheck: time.Now(),
+		stateSince:      time.Now(),
 	}
 	b.cond = sync.NewCond(&b.mu)
 	return b
@@ -57,6 +139,26 @@ func (b *Backend) getAddress() string {
 	return b.Address
 }
 
+// SetHealthAddress sets the address health checks connect to, for backends that
+// serve health on a different port than traffic. An empty address uses Address.
+func (b *Backend) SetHealthAddress(address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.HealthAddress = address
+}
+
+// GetHealthAddress returns the address health checks connect to.
+func (b *Backend) GetHealthAddress() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.HealthAddress == "" {
+		return b.Address
+	}
+	return b.HealthAddress
+}
+
 // GetWeight returns the backend weight.
 func (b *Backend) GetWeight() int {
 	b.mu.RLock()
@@ -65,6 +167,111 @@ func (b *Backend) GetWeight() int {
 	return b.Weight
 }
 
+// GetTags returns a copy of the backend's tags.
+func (b *Backend) GetTags() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	tags := make([]string, len(b.Tags))
+	copy(tags, b.Tags)
+
+	return tags
+}
+
+// IsBackup reports whether the backend is a backup, i.e. has a weight of zero.
+// Backups only receive traffic while no primary backend is healthy.
+func (b *Backend) IsBackup() bool {
+	return b.GetWeight() == 0
+}
+
+// SetWeight updates the backend's weight.
+func (b *Backend) SetWeight(weight int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.Weight = weight
+}
+
+// IsDraining returns whether the backend is draining.
+func (b *Backend) IsDraining() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.Draining
+}
+
+// SetDraining marks whether the backend is draining. Draining backends are
+// excluded from selection but keep their existing connections; only idle pooled
+// connections are closed.
+func (b *Backend) SetDraining(draining bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.Draining = draining
+	if draining {
+		for _, conn := range b.idleConns {
+			conn.Close()
+		}
+		b.idleConns = nil
+	}
+}
+
+// Drain marks the backend as draining and waits for its active connections to finish.
+// Connections still open when the timeout elapses are closed, and their count is returned.
+func (b *Backend) Drain(timeout time.Duration) int {
+	b.SetDraining(true)
+
+	deadline := time.Now().Add(timeout)
+	for b.GetActiveConnections() > 0 {
+		if !b.IsDraining() {
+			return 0 // Draining was cancelled
+		}
+		if time.Now().After(deadline) {
+			return b.CloseConnections()
+		}
+		time.Sleep(drainPollInterval)
+	}
+
+	return 0
+}
+
+// GetLabels returns a copy of the backend's labels.
+func (b *Backend) GetLabels() map[string]string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return maps.Clone(b.Labels)
+}
+
+// GetZone returns the zone the backend runs in.
+func (b *Backend) GetZone() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.Zone
+}
+
+// HasTags reports whether the backend has all of the given tags.
+func (b *Backend) HasTags(tags []string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, tag := range tags {
+		found := false
+		for _, t := range b.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
 // IsAlive returns whether the backend is healthy.
 func (b *Backend) IsAlive() bool {
 	b.mu.RLock()
@@ -73,16 +280,64 @@ func (b *Backend) IsAlive() bool {
 	return b.Alive
 }
 
+// GetDownReason returns why the backend is not receiving new connections, or
+// ReasonNone if it is.
+func (b *Backend) GetDownReason() DownReason {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if !b.Alive {
+		if b.SimulatedDown {
+			return ReasonSimulated
+		}
+		if b.serverErr != nil {
+			return ReasonBindFailed
+		}
+		return b.downReason
+	}
+	if b.Draining {
+		return ReasonDraining
+	}
+	if b.breakerState == BreakerOpen {
+		return ReasonBreakerOpen
+	}
+	if time.Now().Before(b.ejectedUntil) {
+		return ReasonOutlier
+	}
+
+	return ReasonNone
+}
+
+// IsSelectable reports whether the backend may be chosen for a new connection:
+// alive, not draining, and not held out by its circuit breaker or as an outlier.
+func (b *Backend) IsSelectable() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.Alive && !b.Draining && b.breakerSelectable() && !time.Now().Before(b.ejectedUntil)
+}
+
 // SetAlive updates the backend's health status.
 func (b *Backend) SetAlive(alive bool) {
 	b.mu.Lock()
-	defer b.mu.Unlock()
 
-	b.Alive = alive
+	wentDown := b.Alive && !alive
+	hook := b.unhealthyHook
+	defer func() {
+		b.mu.Unlock()
+		if wentDown && hook != nil {
+			hook()
+		}
+	}()
+
+	b.setAlive(alive)
 
 	if alive {
+		b.downReason = ReasonNone
 		b.cond.Broadcast() // wake up waiting goroutines
 	} else {
+		b.downReason = ReasonPassiveFailure
+
 		// If we are "killing" the server, strictly close all current connections
 		for conn := range b.connections {
 			conn.Close()
@@ -92,6 +347,114 @@ func (b *Backend) SetAlive(alive bool) {
 	}
 }
 
+// MarkUnreachable marks the backend dead after a failed dial, like SetAlive(false),
+// but leaves its tracked connections open. In HTTP mode those are client
+// connections whose other requests are unaffected by the failed dial.
+func (b *Backend) MarkUnreachable() {
+	b.mu.Lock()
+
+	wentDown := b.Alive
+	hook := b.unhealthyHook
+	defer func() {
+		b.mu.Unlock()
+		if wentDown && hook != nil {
+			hook()
+		}
+	}()
+
+	b.setAlive(false)
+	b.downReason = ReasonPassiveFailure
+}
+
+// setAlive updates Alive and, on a state change, adds the time spent in the
+// previous state to the availability totals. Caller must hold mu.
+func (b *Backend) setAlive(alive bool) {
+	if alive == b.Alive {
+		return
+	}
+
+	now := time.Now()
+	if b.Alive {
+		b.healthyTime += now.Sub(b.stateSince)
+	} else {
+		b.unhealthyTime += now.Sub(b.stateSince)
+	}
+	b.Alive = alive
+	b.stateSince = now
+	if alive {
+		b.recoveredAt = now
+	}
+}
+
+// slowStartMinFactor is the share of its weight a backend gets right after recovering.
+const slowStartMinFactor = 0.05
+
+// SetSlowStart sets how long the backend takes to ramp up to its full weight after
+// recovering. A duration of 0 disables slow start.
+func (b *Backend) SetSlowStart(duration time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.slowStart = duration
+}
+
+// SlowStartFactor returns the fraction of its weight the backend should receive,
+// rising linearly from near zero to 1 over the slow start window after recovery.
+func (b *Backend) SlowStartFactor() float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.slowStart <= 0 || b.recoveredAt.IsZero() {
+		return 1
+	}
+
+	elapsed := time.Since(b.recoveredAt)
+	if elapsed >= b.slowStart {
+		return 1
+	}
+
+	factor := float64(elapsed) / float64(b.slowStart)
+	if factor < slowStartMinFactor {
+		return slowStartMinFactor
+	}
+	return factor
+}
+
+// setUnhealthyHook sets the function called when a failed connection or health
+// check marks the backend dead, keeping any hook already set.
+func (b *Backend) setUnhealthyHook(hook func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.unhealthyHook == nil {
+		b.unhealthyHook = hook
+	}
+}
+
+// GetAvailability returns the fraction of time the backend has been alive since
+// it was created, including the time spent in its current state.
+func (b *Backend) GetAvailability() float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	healthy, unhealthy := b.healthyTime, b.unhealthyTime
+	if b.Alive {
+		healthy += time.Since(b.stateSince)
+	} else {
+		unhealthy += time.Since(b.stateSince)
+	}
+
+	total := healthy + unhealthy
+	if total <= 0 {
+		if b.Alive {
+			return 1
+		}
+		return 0
+	}
+
+	return float64(healthy) / float64(total)
+}
+
 // SetSimulatedDown marks the backend as down for testing.
 // Dial() will fail when simulated down, but Alive is discovered through connection attempts.
 func (b *Backend) SetSimulatedDown(down bool) {
@@ -107,6 +470,10 @@ func (b *Backend) SetSimulatedDown(down bool) {
 			conn.Close()
 		}
 		b.connections = make(map[net.Conn]struct{})
+		for _, conn := range b.idleConns {
+			conn.Close()
+		}
+		b.idleConns = nil
 	} else {
 		// Recovering: just clear SimulatedDown, let health check set Alive=true
 		// Wake up waiting goroutines so they can serve new connections
@@ -131,6 +498,55 @@ func (b *Backend) RemoveConnection(conn net.Conn) {
 	delete(b.connections, conn)
 }
 
+// AddBytes adds to the backend's transferred byte counters.
+func (b *Backend) AddBytes(sent int64, received int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.BytesSent += sent
+	b.BytesReceived += received
+}
+
+// GetBytes returns the total bytes sent to and received from the backend.
+func (b *Backend) GetBytes() (int64, int64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.BytesSent, b.BytesReceived
+}
+
+// RecordIdleTimeout counts a connection closed for being idle.
+func (b *Backend) RecordIdleTimeout() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.IdleTimeouts++
+}
+
+// RecordLifetimeTimeout counts a connection closed for exceeding its maximum lifetime.
+func (b *Backend) RecordLifetimeTimeout() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.LifetimeTimeouts++
+}
+
+// GetTimeouts returns the number of connections closed by the idle and lifetime limits.
+func (b *Backend) GetTimeouts() (int64, int64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.IdleTimeouts, b.LifetimeTimeouts
+}
+
+// GetDialFailures returns the number of failed client connection dials.
+func (b *Backend) GetDialFailures() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.DialFailures
+}
+
 // GetActiveConnections returns the current number of active connections.
 func (b *Backend) GetActiveConnections() int {
 	b.mu.RLock()
@@ -139,6 +555,44 @@ func (b *Backend) GetActiveConnections() int {
 	return len(b.connections)
 }
 
+// CloseConnections closes all active connections and returns how many were closed.
+func (b *Backend) CloseConnections() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	closed := len(b.connections)
+	for conn := range b.connections {
+		conn.Close()
+	}
+	b.connections = make(map[net.Conn]struct{})
+
+	return closed
+}
+
+// GetMaxConnections returns the backend's connection cap, 0 meaning unlimited.
+func (b *Backend) GetMaxConnections() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.MaxConnections
+}
+
+// GetCost returns the backend's static cost hint.
+func (b *Backend) GetCost() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.Cost
+}
+
+// AtCapacity reports whether the backend has reached its connection cap.
+func (b *Backend) AtCapacity() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.MaxConnections > 0 && len(b.connections) >= b.MaxConnections
+}
+
 // GetStats returns a snapshot of the backend's statistics.
 func (b *Backend) GetStats() (string, bool, int, int64) {
 	b.mu.RLock()
@@ -155,30 +609,213 @@ func (b *Backend) GetLastHealthCheck() time.Time {
 	return b.LastHealthCheck
 }
 
+// GetLastResponseTime returns how long the last health check took.
+func (b *Backend) GetLastResponseTime() time.Duration {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.LastResponseTime
+}
+
 // CheckHealth attempts a TCP connection and updates health status accordingly.
 func (b *Backend) CheckHealth(timeout time.Duration) bool {
-	// Use Dial() to respect SimulatedDown flag
-	conn, err := b.Dial(timeout)
+	start := time.Now()
+
+	// Use dialHealth() to respect SimulatedDown flag
+	conn, err := b.dialHealth(timeout)
+	responseTime := time.Since(start)
+	if err == nil {
+		conn.Close()
+	}
 
+	// A failed dial (server down or SimulatedDown) marks the backend unhealthy
+	return b.recordHealthCheck(err == nil, responseTime)
+}
+
+// CheckHealthHTTP issues an HTTP GET for path and marks the backend alive only on a 2xx response.
+func (b *Backend) CheckHealthHTTP(path string, timeout time.Duration) bool {
+	b.mu.RLock()
+	simulatedDown := b.SimulatedDown
+	b.mu.RUnlock()
+
+	if simulatedDown {
+		return b.recordHealthCheck(false, 0)
+	}
+
+	if path == "" {
+		path = "/"
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+b.GetHealthAddress()+path, nil)
+	if err != nil {
+		return b.recordHealthCheck(false, 0)
+	}
+	resp, err := b.getHealthClient().Do(req)
+	responseTime := time.Since(start)
+	if err != nil {
+		return b.recordHealthCheck(false, responseTime)
+	}
+	resp.Body.Close()
+
+	return b.recordHealthCheck(resp.StatusCode >= 200 && resp.StatusCode < 300, responseTime)
+}
+
+// getHealthClient returns the client HTTP health checks are sent with, so every
+// probe of the backend shares one transport. Keep-alives are off so each probe
+// dials the backend afresh.
+func (b *Backend) getHealthClient() *http.Client {
+	b.healthClientOnce.Do(func() {
+		b.healthClient = &http.Client{
+			Transport: &http.Transport{
+				DialContext:       b.dialContext,
+				DisableKeepAlives: true,
+			},
+		}
+	})
+
+	return b.healthClient
+}
+
+// maxExpectResponse caps how much of a backend's reply CheckHealthExpect reads
+// while looking for the expected string.
+const maxExpectResponse = 4096
+
+// CheckHealthExpect connects to the backend, sends probe if it is not empty, and
+// marks the backend alive only if its reply contains expect within the timeout.
+// Only the first maxExpectResponse bytes of the reply are searched.
+func (b *Backend) CheckHealthExpect(probe string, expect string, timeout time.Duration) bool {
+	start := time.Now()
+
+	// Use dialHealth() to respect SimulatedDown flag
+	conn, err := b.dialHealth(timeout)
+	if err != nil {
+		return b.recordHealthCheck(false, time.Since(start))
+	}
+	defer conn.Close()
+
+	if timeout > 0 {
+		conn.SetDeadline(start.Add(timeout))
+	}
+
+	if probe != "" {
+		if _, err := conn.Write([]byte(probe)); err != nil {
+			return b.recordHealthCheck(false, time.Since(start))
+		}
+	}
+
+	// Read until the expected string shows up, the backend closes or the deadline passes
+	var reply []byte
+	buf := make([]byte, 512)
+	for len(reply) < maxExpectResponse {
+		n, err := conn.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if strings.Contains(string(reply), expect) {
+			return b.recordHealthCheck(true, time.Since(start))
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	return b.recordHealthCheck(false, time.Since(start))
+}
+
+// recordHealthCheck stores the result and duration of a health check and returns the result.
+func (b *Backend) recordHealthCheck(healthy bool, responseTime time.Duration) bool {
 	b.mu.Lock()
-	defer b.mu.Unlock()
+
+	wentDown := b.Alive && !healthy
+	hook := b.unhealthyHook
+	defer func() {
+		b.mu.Unlock()
+		if wentDown && hook != nil {
+			hook()
+		}
+	}()
 
 	b.LastHealthCheck = time.Now()
+	b.LastResponseTime = responseTime
+	b.setAlive(healthy)
 
-	if err == nil {
-		conn.Close()
-		b.Alive = true
+	if healthy {
+		b.downReason = ReasonNone
+		b.probeFailures = 0
 		b.cond.Broadcast() // Wake up any goroutines waiting for recovery
-		return true
+	} else {
+		b.downReason = ReasonProbeFailure
+		b.probeFailures++
+	}
+
+	return healthy
+}
+
+// HealthCheckDue reports whether the backend is due a health check at the given time.
+func (b *Backend) HealthCheckDue(at time.Time) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return !at.Before(b.nextHealthCheck)
+}
+
+// ScheduleHealthCheck sets when the backend is next due a health check. The delay
+// is interval while checks pass and doubles with each consecutive failure, up to
+// maxInterval.
+func (b *Backend) ScheduleHealthCheck(from time.Time, interval, maxInterval time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delay := interval
+	for i := 1; i < b.probeFailures && delay < maxInterval; i++ {
+		delay *= 2
 	}
+	if delay > maxInterval {
+		delay = maxInterval
+	}
+
+	b.nextHealthCheck = from.Add(delay)
+}
+
+// GetNextHealthCheck returns when the backend is next due a health check.
+func (b *Backend) GetNextHealthCheck() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 
-	// Dial failed (server down or SimulatedDown) - mark unhealthy
-	b.Alive = false
-	return false
+	return b.nextHealthCheck
 }
 
 // Dial creates a TCP connection to the backend, returning ErrBackendDown if simulated down.
 func (b *Backend) Dial(timeout time.Duration) (net.Conn, error) {
+	return b.DialNetwork("tcp", timeout)
+}
+
+// DialNetwork connects to the backend over the given network, such as "tcp" or
+// "udp", returning ErrBackendDown if simulated down.
+func (b *Backend) DialNetwork(network string, timeout time.Duration) (net.Conn, error) {
+	return b.DialNetworkContext(context.Background(), network, timeout)
+}
+
+// DialNetworkContext is like DialNetwork, but abandons the dial when ctx is done.
+func (b *Backend) DialNetworkContext(ctx context.Context, network string, timeout time.Duration) (net.Conn, error) {
+	return b.dialAddress(ctx, network, b.getAddress(), timeout)
+}
+
+// dialHealth connects to the backend's health address for a health check,
+// returning ErrBackendDown if simulated down.
+func (b *Backend) dialHealth(timeout time.Duration) (net.Conn, error) {
+	return b.dialAddress(context.Background(), "tcp", b.GetHealthAddress(), timeout)
+}
+
+// dialAddress dials address on behalf of the backend, returning ErrBackendDown
+// if simulated down.
+func (b *Backend) dialAddress(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
 	b.mu.RLock()
 	if b.SimulatedDown {
 		b.mu.RUnlock()
@@ -186,5 +823,29 @@ func (b *Backend) Dial(timeout time.Duration) (net.Conn, error) {
 	}
 	b.mu.RUnlock()
 
-	return net.DialTimeout("tcp", b.Address, timeout)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return b.dialContext(ctx, network, address)
+}
+
+// dialContext dials through the backend's dialer, recording the latency of
+// successful dials.
+func (b *Backend) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	start := time.Now()
+	conn, err := b.getDialer().DialContext(ctx, network, address)
+	if err == nil {
+		b.dialLatency.Observe(time.Since(start))
+	}
+
+	return conn, err
+}
+
+// GetDialLatency returns a summary of successful dial latencies from both client
+// connections and health checks.
+func (b *Backend) GetDialLatency() LatencySummary {
+	return b.dialLatency.Summary()
 }
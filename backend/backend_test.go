@@ -0,0 +1,107 @@
+package backend
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// closedAddr returns a loopback address nothing is listening on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	return addr
+}
+
+func TestDownReasons(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, b *Backend)
+		want  DownReason
+	}{
+		{"alive", func(t *testing.T, b *Backend) {}, ReasonNone},
+		{"simulated", func(t *testing.T, b *Backend) {
+			b.SetSimulatedDown(true)
+			b.CheckHealth(time.Second)
+		}, ReasonSimulated},
+		{"passive failure", func(t *testing.T, b *Backend) {
+			b.SetAlive(false)
+		}, ReasonPassiveFailure},
+		{"probe failure", func(t *testing.T, b *Backend) {
+			b.Address = closedAddr(t)
+			b.CheckHealth(time.Second)
+		}, ReasonProbeFailure},
+		{"draining", func(t *testing.T, b *Backend) {
+			b.SetDraining(true)
+		}, ReasonDraining},
+		{"breaker open", func(t *testing.T, b *Backend) {
+			b.SetCircuitBreaker(1, time.Minute)
+			b.RecordDialFailure()
+		}, ReasonBreakerOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBackend("127.0.0.1:1")
+			tt.setup(t, b)
+
+			if got := b.GetDownReason(); got != tt.want {
+				t.Errorf("GetDownReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkUnreachableKeepsConnections(t *testing.T) {
+	b := NewBackend("127.0.0.1:1")
+	client, server := net.Pipe()
+	defer server.Close()
+	b.AddConnection(client)
+
+	b.MarkUnreachable()
+
+	if b.IsAlive() || b.GetDownReason() != ReasonPassiveFailure {
+		t.Errorf("alive = %v, reason %q after MarkUnreachable, want down for a passive failure", b.IsAlive(), b.GetDownReason())
+	}
+	if n := b.GetActiveConnections(); n != 1 {
+		t.Errorf("%d tracked connections after MarkUnreachable, want 1", n)
+	}
+	client.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, err := client.Read(make([]byte, 1)); errors.Is(err, io.ErrClosedPipe) {
+		t.Error("MarkUnreachable closed a tracked connection")
+	}
+}
+
+func TestAvailabilityRatio(t *testing.T) {
+	b := NewBackend("127.0.0.1:1")
+
+	// Backdate the start so the backend was up for 30s, then has been down for
+	// the last 10s; the ongoing downtime counts too
+	b.mu.Lock()
+	b.stateSince = time.Now().Add(-40 * time.Second)
+	b.mu.Unlock()
+	b.SetAlive(false)
+	b.mu.Lock()
+	b.healthyTime = 30 * time.Second
+	b.stateSince = time.Now().Add(-10 * time.Second)
+	b.mu.Unlock()
+
+	if got := b.GetAvailability(); got < 0.74 || got > 0.76 {
+		t.Errorf("availability = %.3f, want 0.75", got)
+	}
+
+	// Coming back up stops the downtime from growing
+	b.SetAlive(true)
+	if got := b.GetAvailability(); got < 0.74 || got > 0.76 {
+		t.Errorf("availability after recovery = %.3f, want 0.75", got)
+	}
+}
@@ -0,0 +1,129 @@
+package backend
+
+import "time"
+
+// BreakerState is the state of a backend's circuit breaker.
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Traffic flows normally
+	BreakerOpen                         // Backend is skipped until the cooldown elapses
+	BreakerHalfOpen                     // A single trial connection is allowed
+)
+
+// String returns the breaker state name.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// SetCircuitBreaker configures the breaker to open after threshold consecutive
+// dial failures and stay open for cooldown. A threshold of 0 disables it.
+func (b *Backend) SetCircuitBreaker(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failureThreshold = threshold
+	b.breakerCooldown = cooldown
+}
+
+// GetBreakerState returns the current circuit breaker state.
+func (b *Backend) GetBreakerState() BreakerState {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.breakerState
+}
+
+// GetConsecutiveFailures returns the number of dial failures since the last successful dial.
+func (b *Backend) GetConsecutiveFailures() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.consecutiveFailures
+}
+
+// breakerSelectable reports whether the breaker would let a connection through,
+// without changing its state. Caller must hold mu.
+func (b *Backend) breakerSelectable() bool {
+	switch b.breakerState {
+	case BreakerOpen:
+		return time.Since(b.breakerOpenedAt) >= b.breakerCooldown
+	case BreakerHalfOpen:
+		return !b.trialInFlight
+	default:
+		return true
+	}
+}
+
+// AllowConnection reports whether the breaker lets a new connection through. Once
+// the cooldown has elapsed an open breaker turns half-open and allows a single
+// trial connection, whose outcome must be reported with RecordDialSuccess,
+// RecordDialFailure or AbandonTrial.
+func (b *Backend) AllowConnection() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.breakerState {
+	case BreakerOpen:
+		if time.Since(b.breakerOpenedAt) < b.breakerCooldown {
+			return false
+		}
+		b.breakerState = BreakerHalfOpen
+		b.trialInFlight = true
+		return true
+	case BreakerHalfOpen:
+		if b.trialInFlight {
+			return false
+		}
+		b.trialInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordDialSuccess resets the failure count and closes the breaker.
+func (b *Backend) RecordDialSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures = 0
+	b.breakerState = BreakerClosed
+	b.trialInFlight = false
+}
+
+// AbandonTrial gives up a half-open trial whose outcome is unknown, e.g. because
+// the client went away first, so the next connection can be the trial instead.
+func (b *Backend) AbandonTrial() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialInFlight = false
+}
+
+// RecordDialFailure counts a failed dial towards the backend's DialFailures, opening
+// the breaker once the threshold is reached or when a half-open trial fails.
+func (b *Backend) RecordDialFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures++
+	b.DialFailures++
+	b.trialInFlight = false
+
+	if b.failureThreshold <= 0 {
+		return
+	}
+
+	if b.breakerState == BreakerHalfOpen || b.consecutiveFailures >= b.failureThreshold {
+		b.breakerState = BreakerOpen
+		b.breakerOpenedAt = time.Now()
+	}
+}
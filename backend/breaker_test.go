@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	b := NewBackend("127.0.0.1:1")
+	b.SetCircuitBreaker(3, cooldown)
+
+	expect := func(step string, want BreakerState) {
+		t.Helper()
+		if got := b.GetBreakerState(); got != want {
+			t.Fatalf("%s: state = %s, want %s", step, got, want)
+		}
+	}
+
+	// Closed: failures below the threshold keep traffic flowing
+	b.RecordDialFailure()
+	b.RecordDialFailure()
+	expect("two failures", BreakerClosed)
+	if !b.AllowConnection() {
+		t.Fatal("closed breaker refused a connection")
+	}
+
+	// Open: the threshold is reached and the backend is held out
+	b.RecordDialFailure()
+	expect("three failures", BreakerOpen)
+	if b.AllowConnection() || b.IsSelectable() {
+		t.Fatal("open breaker let a connection through during the cooldown")
+	}
+
+	// Half-open: after the cooldown exactly one trial is allowed
+	time.Sleep(cooldown)
+	if !b.AllowConnection() {
+		t.Fatal("breaker refused the trial after the cooldown")
+	}
+	expect("trial", BreakerHalfOpen)
+	if b.AllowConnection() {
+		t.Fatal("half-open breaker allowed a second trial")
+	}
+
+	// A failed trial reopens the breaker for another cooldown
+	b.RecordDialFailure()
+	expect("failed trial", BreakerOpen)
+	if b.AllowConnection() {
+		t.Fatal("reopened breaker let a connection through")
+	}
+
+	// A successful trial closes it again
+	time.Sleep(cooldown)
+	if !b.AllowConnection() {
+		t.Fatal("breaker refused the second trial")
+	}
+	b.RecordDialSuccess()
+	expect("successful trial", BreakerClosed)
+	if b.GetConsecutiveFailures() != 0 {
+		t.Errorf("consecutive failures = %d after success, want 0", b.GetConsecutiveFailures())
+	}
+	if !b.AllowConnection() {
+		t.Error("closed breaker refused a connection")
+	}
+}
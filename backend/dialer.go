@@ -0,0 +1,35 @@
+package backend
+
+import (
+	"context"
+	"net"
+)
+
+// Dialer opens connections to backends. *net.Dialer satisfies it, and custom
+// implementations can add DNS overrides, happy-eyeballs or proxy tunnelling.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// defaultDialer is used by backends that have no dialer set.
+var defaultDialer Dialer = &net.Dialer{}
+
+// SetDialer sets the dialer used for proxied connections and health checks.
+// A nil dialer restores the default net.Dialer.
+func (b *Backend) SetDialer(d Dialer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dialer = d
+}
+
+// getDialer returns the backend's dialer, falling back to the default.
+func (b *Backend) getDialer() Dialer {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.dialer == nil {
+		return defaultDialer
+	}
+	return b.dialer
+}
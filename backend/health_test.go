@@ -0,0 +1,210 @@
+package backend
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startHTTPBackend starts an HTTP server answering path with status and returns
+// a backend for it.
+func startHTTPBackend(t *testing.T, path string, status int) *Backend {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(ts.Close)
+
+	return NewBackend(strings.TrimPrefix(ts.URL, "http://"))
+}
+
+func TestCheckHealthHTTP(t *testing.T) {
+	tests := []struct {
+		status int
+		alive  bool
+	}{
+		{http.StatusOK, true},
+		{http.StatusNoContent, true},
+		{http.StatusServiceUnavailable, false},
+		{http.StatusInternalServerError, false},
+	}
+
+	for _, tt := range tests {
+		b := startHTTPBackend(t, "/healthz", tt.status)
+		if got := b.CheckHealthHTTP("/healthz", time.Second); got != tt.alive {
+			t.Errorf("status %d: CheckHealthHTTP = %v, want %v", tt.status, got, tt.alive)
+		}
+		if b.IsAlive() != tt.alive {
+			t.Errorf("status %d: IsAlive = %v, want %v", tt.status, b.IsAlive(), tt.alive)
+		}
+	}
+}
+
+func TestCheckHealthHTTPUsesPath(t *testing.T) {
+	b := startHTTPBackend(t, "/healthz", http.StatusOK)
+
+	if b.CheckHealthHTTP("/other", time.Second) {
+		t.Error("check of a path answering 404 passed")
+	}
+	if !b.CheckHealthHTTP("/healthz", time.Second) {
+		t.Error("check of the health path failed")
+	}
+}
+
+func TestFailedHTTPCheckEmitsUnhealthyEvent(t *testing.T) {
+	pool := NewPool()
+	b := startHTTPBackend(t, "/healthz", http.StatusServiceUnavailable)
+	pool.AddBackend(b)
+
+	var mu sync.Mutex
+	var events []PoolEvent
+	pool.SetEventCallback(func(event PoolEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+
+	b.CheckHealthHTTP("/healthz", time.Second)
+	// Already dead, so no second event
+	b.CheckHealthHTTP("/healthz", time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].Type != EventBackendUnhealthy || events[0].Backend != b.Address {
+		t.Errorf("event = %+v, want EventBackendUnhealthy for %s", events[0], b.Address)
+	}
+}
+
+// slowDialer delays every dial, standing in for a slow network path.
+type slowDialer struct {
+	delay time.Duration
+}
+
+// DialContext sleeps for the delay and then dials normally.
+func (d slowDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	time.Sleep(d.delay)
+	return (&net.Dialer{}).DialContext(ctx, network, address)
+}
+
+func TestHealthCheckRecordsResponseTime(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const delay = 20 * time.Millisecond
+	b := NewBackend(ln.Addr().String())
+	b.SetDialer(slowDialer{delay: delay})
+
+	if !b.CheckHealth(time.Second) {
+		t.Fatal("health check failed")
+	}
+	if got := b.GetLastResponseTime(); got < delay {
+		t.Errorf("response time = %v, want at least %v", got, delay)
+	}
+}
+
+// startEchoServer starts the built-in echo server handler on a loopback listener
+// and returns its address.
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handleConnection(conn, ln.Addr().String())
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestCheckHealthExpect(t *testing.T) {
+	addr := startEchoServer(t)
+
+	tests := []struct {
+		name   string
+		probe  string
+		expect string
+		alive  bool
+	}{
+		{"banner matches", "", "Connected to Backend", true},
+		{"probe reply matches", "ping\n", "Echo: ping", true},
+		{"banner does not match", "", "Service Ready", false},
+		{"probe reply does not match", "ping\n", "Echo: pong", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBackend(addr)
+			if got := b.CheckHealthExpect(tt.probe, tt.expect, 300*time.Millisecond); got != tt.alive {
+				t.Errorf("CheckHealthExpect = %v, want %v", got, tt.alive)
+			}
+			if b.IsAlive() != tt.alive {
+				t.Errorf("IsAlive = %v, want %v", b.IsAlive(), tt.alive)
+			}
+		})
+	}
+}
+
+func TestHealthAddressDeterminesAliveness(t *testing.T) {
+	service, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer service.Close()
+	health, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBackend(service.Addr().String())
+	if got := b.GetHealthAddress(); got != b.Address {
+		t.Errorf("default health address = %q, want the service address", got)
+	}
+
+	b.SetHealthAddress(health.Addr().String())
+	if !b.CheckHealth(time.Second) {
+		t.Error("backend down while its health port is listening")
+	}
+
+	// A closed health port marks the backend down even though the service port is up
+	health.Close()
+	if b.CheckHealth(time.Second) {
+		t.Error("backend alive while its health port is closed")
+	}
+
+	// Live traffic still goes to the service port
+	conn, err := b.Dial(time.Second)
+	if err != nil {
+		t.Fatalf("Dial = %v, want the service port", err)
+	}
+	defer conn.Close()
+	if got := conn.RemoteAddr().String(); got != service.Addr().String() {
+		t.Errorf("Dial connected to %s, want %s", got, service.Addr())
+	}
+}
@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram buckets.
+var latencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// LatencyHistogram counts latencies in fixed buckets using atomic counters, so
+// recording never takes a lock.
+type LatencyHistogram struct {
+	counts [len(latencyBuckets) + 1]atomic.Int64 // One per bucket in latencyBuckets, plus one for larger values
+	count  atomic.Int64
+	sum    atomic.Int64 // Total of all observations in nanoseconds
+}
+
+// LatencySummary is a snapshot of a LatencyHistogram. Percentiles are the upper
+// bound of the bucket they fall in; values beyond the last bucket report its bound.
+type LatencySummary struct {
+	Count int64
+	Sum   time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+}
+
+// Observe records a latency.
+func (h *LatencyHistogram) Observe(latency time.Duration) {
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+
+	h.counts[bucket].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(latency))
+}
+
+// Summary returns the observation count, total and p50/p95/p99 latencies.
+func (h *LatencyHistogram) Summary() LatencySummary {
+	var counts [len(latencyBuckets) + 1]int64
+	var total int64
+	for i := range counts {
+		counts[i] = h.counts[i].Load()
+		total += counts[i]
+	}
+
+	return LatencySummary{
+		Count: total,
+		Sum:   time.Duration(h.sum.Load()),
+		P50:   percentile(counts[:], total, 0.50),
+		P95:   percentile(counts[:], total, 0.95),
+		P99:   percentile(counts[:], total, 0.99),
+	}
+}
+
+// percentile returns the upper bound of the bucket holding the given quantile.
+func percentile(counts []int64, total int64, quantile float64) time.Duration {
+	if total == 0 {
+		return 0
+	}
+
+	rank := int64(quantile*float64(total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			if i < len(latencyBuckets) {
+				return latencyBuckets[i]
+			}
+			break
+		}
+	}
+
+	return latencyBuckets[len(latencyBuckets)-1]
+}
@@ -0,0 +1,45 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogramPercentiles(t *testing.T) {
+	var h LatencyHistogram
+
+	observe := func(n int, latency time.Duration) {
+		for range n {
+			h.Observe(latency)
+		}
+	}
+	observe(50, 3*time.Millisecond)
+	observe(45, 40*time.Millisecond)
+	observe(4, 200*time.Millisecond)
+	observe(1, 10*time.Second)
+
+	got := h.Summary()
+	want := LatencySummary{
+		Count: 100,
+		Sum:   50*3*time.Millisecond + 45*40*time.Millisecond + 4*200*time.Millisecond + 10*time.Second,
+		P50:   5 * time.Millisecond,
+		P95:   50 * time.Millisecond,
+		P99:   250 * time.Millisecond,
+	}
+	if got != want {
+		t.Errorf("Summary() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLatencyHistogramEmptyAndOverflow(t *testing.T) {
+	var h LatencyHistogram
+	if got := h.Summary(); got != (LatencySummary{}) {
+		t.Errorf("empty Summary() = %+v, want zero", got)
+	}
+
+	// Values beyond the last bucket report its bound
+	h.Observe(time.Minute)
+	if got := h.Summary(); got.P50 != 5*time.Second || got.P99 != 5*time.Second {
+		t.Errorf("overflow Summary() = %+v, want percentiles at the last bound", got)
+	}
+}
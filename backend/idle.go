@@ -0,0 +1,127 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// idleCheckTimeout is how long a pooled connection is read to check the backend
+// has not closed it.
+const idleCheckTimeout = time.Millisecond
+
+// SetMaxIdleConns sets how many finished connections are kept open for reuse,
+// closing any beyond the new limit. 0 disables connection reuse.
+func (b *Backend) SetMaxIdleConns(n int) {
+	b.mu.Lock()
+	b.maxIdleConns = n
+	var excess []net.Conn
+	if len(b.idleConns) > n {
+		excess = b.idleConns[n:]
+		b.idleConns = b.idleConns[:n:n]
+	}
+	b.mu.Unlock()
+
+	for _, conn := range excess {
+		conn.Close()
+	}
+}
+
+// DialReusable returns a pooled idle connection to the backend if one is still
+// open, otherwise it dials a new one like DialNetworkContext. It also reports
+// whether the connection was reused.
+func (b *Backend) DialReusable(ctx context.Context, timeout time.Duration) (net.Conn, bool, error) {
+	for {
+		b.mu.Lock()
+		if b.SimulatedDown {
+			b.mu.Unlock()
+			return nil, false, ErrBackendDown
+		}
+		if len(b.idleConns) == 0 {
+			b.mu.Unlock()
+			break
+		}
+		// Take the most recently used connection, the least likely to have timed out
+		conn := b.idleConns[len(b.idleConns)-1]
+		b.idleConns = b.idleConns[:len(b.idleConns)-1]
+		b.mu.Unlock()
+
+		if !idleConnAlive(conn) {
+			conn.Close()
+			continue
+		}
+
+		b.mu.Lock()
+		b.ReusedConnections++
+		b.mu.Unlock()
+
+		return conn, true, nil
+	}
+
+	conn, err := b.DialNetworkContext(ctx, "tcp", timeout)
+	return conn, false, err
+}
+
+// PutIdleConn offers a finished connection back to the pool, returning false if
+// it was not kept, in which case the caller should close it. Connections are
+// refused when the pool is full or the backend is draining or down.
+func (b *Backend) PutIdleConn(conn net.Conn) bool {
+	if !idleConnAlive(conn) {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.SimulatedDown || b.Draining || len(b.idleConns) >= b.maxIdleConns {
+		return false
+	}
+	b.idleConns = append(b.idleConns, conn)
+
+	return true
+}
+
+// CloseIdleConns closes all pooled idle connections and returns how many were closed.
+func (b *Backend) CloseIdleConns() int {
+	b.mu.Lock()
+	idle := b.idleConns
+	b.idleConns = nil
+	b.mu.Unlock()
+
+	for _, conn := range idle {
+		conn.Close()
+	}
+
+	return len(idle)
+}
+
+// GetIdleConns returns the number of pooled idle connections.
+func (b *Backend) GetIdleConns() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.idleConns)
+}
+
+// GetReusedConnections returns how many client connections were served over a
+// pooled backend connection.
+func (b *Backend) GetReusedConnections() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.ReusedConnections
+}
+
+// idleConnAlive reports whether an idle connection is still usable: a short read
+// must time out, since data or EOF means the backend closed or desynchronised it.
+func idleConnAlive(conn net.Conn) bool {
+	var buf [1]byte
+
+	conn.SetReadDeadline(time.Now().Add(idleCheckTimeout))
+	_, err := conn.Read(buf[:])
+	conn.SetReadDeadline(time.Time{})
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,78 @@
+package backend
+
+import "time"
+
+// outlierMinRequests is how many results a window needs before its error ratio is trusted.
+const outlierMinRequests = 5
+
+// defaultOutlierPeriod is the window and ejection time used when they are not set.
+const defaultOutlierPeriod = 30 * time.Second
+
+// SetOutlierDetection ejects the backend for ejection once more than ratio of
+// the connections completed within a window have failed. A ratio of 0 disables it,
+// and a non-positive window or ejection uses defaultOutlierPeriod.
+func (b *Backend) SetOutlierDetection(ratio float64, window, ejection time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if window <= 0 {
+		window = defaultOutlierPeriod
+	}
+	if ejection <= 0 {
+		ejection = defaultOutlierPeriod
+	}
+
+	b.outlierRatio = ratio
+	b.outlierWindow = window
+	b.outlierEjection = ejection
+}
+
+// RecordResult counts a finished connection towards the backend's error ratio,
+// ejecting the backend when the ratio exceeds the outlier threshold. It returns
+// true if this result caused an ejection.
+func (b *Backend) RecordResult(success bool) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.outlierRatio <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	if now.Sub(b.outlierWindowStart) >= b.outlierWindow {
+		b.outlierWindowStart = now
+		b.windowSuccesses = 0
+		b.windowFailures = 0
+	}
+
+	if success {
+		b.windowSuccesses++
+		return false
+	}
+	b.windowFailures++
+
+	total := b.windowSuccesses + b.windowFailures
+	if total < outlierMinRequests || now.Before(b.ejectedUntil) {
+		return false
+	}
+
+	if float64(b.windowFailures)/float64(total) > b.outlierRatio {
+		b.ejectedUntil = now.Add(b.outlierEjection)
+
+		// Start afresh once reinstated
+		b.outlierWindowStart = b.ejectedUntil
+		b.windowSuccesses = 0
+		b.windowFailures = 0
+		return true
+	}
+
+	return false
+}
+
+// IsEjected reports whether the backend is currently ejected as an outlier.
+func (b *Backend) IsEjected() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return time.Now().Before(b.ejectedUntil)
+}
@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutlierEjectionAndReinstatement(t *testing.T) {
+	b := NewBackend("127.0.0.1:9001")
+	b.SetOutlierDetection(0.5, time.Minute, 100*time.Millisecond)
+	pool := NewPool()
+	pool.AddBackend(b)
+
+	// 2 failures in 5 stays under the ratio
+	for _, success := range []bool{true, true, true, false, false} {
+		if b.RecordResult(success) {
+			t.Fatal("ejected below the error ratio")
+		}
+	}
+
+	// 3 failures in 6 is not above the ratio, 4 in 7 is
+	if b.RecordResult(false) {
+		t.Fatal("ejected at exactly the error ratio")
+	}
+	if !b.RecordResult(false) {
+		t.Fatal("not ejected above the error ratio")
+	}
+
+	if !b.IsEjected() {
+		t.Error("IsEjected() = false after ejection")
+	}
+	if len(pool.GetSelectableBackends()) != 0 {
+		t.Error("ejected backend is still selectable")
+	}
+	if !b.IsAlive() {
+		t.Error("ejection should not mark the backend dead")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if b.IsEjected() {
+		t.Error("still ejected after the ejection time")
+	}
+	if len(pool.GetSelectableBackends()) != 1 {
+		t.Error("reinstated backend is not selectable")
+	}
+}
+
+func TestOutlierDetectionDisabled(t *testing.T) {
+	b := NewBackend("127.0.0.1:9001")
+	for range 20 {
+		if b.RecordResult(false) {
+			t.Fatal("ejected with outlier detection disabled")
+		}
+	}
+}
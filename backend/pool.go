@@ -1,17 +1,30 @@
 package backend
 
 import (
+	"context"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 )
 
+// Default failure simulation timing, used for any timing not set with SetSimulationTiming.
+const (
+	defaultSimInitialDelay = 5 * time.Second  // Delay before the first pause
+	defaultSimPauseMin     = 15 * time.Second // Shortest pause
+	defaultSimPauseMax     = 20 * time.Second // Longest pause
+	defaultSimGap          = 25 * time.Second // Time between the end of one pause and the next
+)
+
 // EventType represents the type of pool event
 type EventType int
 
 const (
 	EventBackendDown EventType = iota
 	EventBackendRecovered
+	EventBackendUnhealthy // Backend was marked unhealthy after a failed connection or health check
+	EventBackendPaused    // Backend was manually paused by an operator
+	EventBackendResumed   // Backend was manually resumed by an operator
 )
 
 // PoolEvent represents an event that occurred in the pool
@@ -26,24 +39,65 @@ type EventCallback func(event PoolEvent)
 
 // Pool manages a collection of backend servers.
 type Pool struct {
-	backends      []*Backend    // All configured backends
-	mu            sync.RWMutex  // Protects the backends slice
-	eventCallback EventCallback // Optional callback for events
+	backends      []*Backend      // All configured backends
+	mu            sync.RWMutex    // Protects the backends slice
+	eventCallback EventCallback   // Optional callback for events
+	filter        SelectionFilter // Optional, narrows the healthy backends offered for selection
 
 	// Simulation state
-	pausedBackend    string    // Address of currently paused backend (empty if none)
-	pauseStartTime   time.Time // When the current pause started
-	pauseDuration    time.Duration // How long the current pause will last
-	nextPauseTime    time.Time // When the next pause cycle will start
+	pausedBackend   string          // Address of currently paused backend (empty if none)
+	pauseStartTime  time.Time       // When the current pause started
+	pauseDuration   time.Duration   // How long the current pause will last
+	nextPauseTime   time.Time       // When the next pause cycle will start
+	manualPauses    map[string]bool // Addresses of backends paused by an operator
+	simInitialDelay time.Duration   // Delay before the first pause
+	simPauseMin     time.Duration   // Shortest pause
+	simPauseMax     time.Duration   // Longest pause
+	simGap          time.Duration   // Time between pauses
+	rng             *rand.Rand      // Source for simulation choices, nil for the global source
+	rngMu           sync.Mutex      // Protects rng, which is not safe for concurrent use
 }
 
 // NewPool creates a new empty backend pool.
 func NewPool() *Pool {
 	return &Pool{
-		nextPauseTime: time.Now().Add(5 * time.Second), // First pause after 5s initial delay
+		nextPauseTime:   time.Now().Add(defaultSimInitialDelay),
+		manualPauses:    make(map[string]bool),
+		simInitialDelay: defaultSimInitialDelay,
+		simPauseMin:     defaultSimPauseMin,
+		simPauseMax:     defaultSimPauseMax,
+		simGap:          defaultSimGap,
 	}
 }
 
+// SetSimulationTiming sets the failure simulation's delay before the first pause,
+// the range pause lengths are picked from and the gap between pauses. Non-positive
+// values keep the defaults of 5s, 15-20s and 25s; a pauseMax below pauseMin is
+// raised to it. It should be called before the simulation loop is started.
+func (p *Pool) SetSimulationTiming(initialDelay, pauseMin, pauseMax, gap time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if initialDelay <= 0 {
+		initialDelay = defaultSimInitialDelay
+	}
+	if pauseMin <= 0 {
+		pauseMin = defaultSimPauseMin
+	}
+	if pauseMax <= 0 {
+		pauseMax = defaultSimPauseMax
+	}
+	if gap <= 0 {
+		gap = defaultSimGap
+	}
+
+	p.simInitialDelay = initialDelay
+	p.simPauseMin = pauseMin
+	p.simPauseMax = max(pauseMax, pauseMin)
+	p.simGap = gap
+	p.nextPauseTime = time.Now().Add(initialDelay)
+}
+
 // SetEventCallback sets the callback function for pool events.
 func (p *Pool) SetEventCallback(callback EventCallback) {
 	p.mu.Lock()
@@ -51,6 +105,26 @@ func (p *Pool) SetEventCallback(callback EventCallback) {
 	p.eventCallback = callback
 }
 
+// SetSimulationSeed makes the failure simulation's choice of backends and pause
+// lengths reproducible by drawing them from a source seeded with seed.
+func (p *Pool) SetSimulationSeed(seed int64) {
+	p.rngMu.Lock()
+	defer p.rngMu.Unlock()
+
+	p.rng = rand.New(rand.NewSource(seed))
+}
+
+// int63n returns a random number in [0, n) from the simulation's source.
+func (p *Pool) int63n(n int64) int64 {
+	p.rngMu.Lock()
+	defer p.rngMu.Unlock()
+
+	if p.rng == nil {
+		return rand.Int63n(n)
+	}
+	return p.rng.Int63n(n)
+}
+
 // GetPauseState returns the current pause simulation state.
 func (p *Pool) GetPauseState() (string, time.Time, time.Duration, time.Time) {
 	p.mu.RLock()
@@ -77,7 +151,34 @@ func (p *Pool) emitEvent(eventType EventType, backendAddr string) {
 func (p *Pool) AddBackend(b *Backend) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.addBackendLocked(b)
+}
+
+// AddBackendIfAbsent adds b unless a backend with the same address is already in
+// the pool, checking and adding under one lock so concurrent adds of the same
+// address cannot both succeed. It reports whether b was added.
+func (p *Pool) AddBackendIfAbsent(b *Backend) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, existing := range p.backends {
+		if existing.Address == b.Address {
+			return false
+		}
+	}
+
+	p.addBackendLocked(b)
+	return true
+}
+
+// addBackendLocked appends b to the pool. The caller must hold p.mu.
+func (p *Pool) addBackendLocked(b *Backend) {
 	p.backends = append(p.backends, b)
+
+	// The first pool a backend joins reports its passive failures
+	b.setUnhealthyHook(func() {
+		p.emitEvent(EventBackendUnhealthy, b.Address)
+	})
 }
 
 // RemoveBackend removes a backend from the pool, returning true if found.
@@ -95,6 +196,21 @@ func (p *Pool) RemoveBackend(address string) bool {
 	return false
 }
 
+// DrainBackend stops new connections to a backend, waits up to timeout for its
+// active connections to finish, then removes it from the pool. Connections still
+// open at the timeout are closed; their count is returned with whether the
+// backend was found.
+func (p *Pool) DrainBackend(address string, timeout time.Duration) (int, bool) {
+	b := p.GetBackendByAddress(address)
+	if b == nil {
+		return 0, false
+	}
+
+	forced := b.Drain(timeout)
+
+	return forced, p.RemoveBackend(address)
+}
+
 // GetBackends returns a copy of all backends in the pool.
 func (p *Pool) GetBackends() []*Backend {
 	p.mu.RLock()
@@ -106,14 +222,15 @@ func (p *Pool) GetBackends() []*Backend {
 	return backendsCopy
 }
 
-// GetHealthyBackends returns only the backends that are currently alive.
+// GetHealthyBackends returns only the backends that can currently be selected:
+// alive, not draining, and not held out by an open circuit breaker or as an outlier.
 func (p *Pool) GetHealthyBackends() []*Backend {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
 	var healthy []*Backend
 	for _, b := range p.backends {
-		if b.IsAlive() {
+		if b.IsSelectable() {
 			healthy = append(healthy, b)
 		}
 	}
@@ -121,6 +238,53 @@ func (p *Pool) GetHealthyBackends() []*Backend {
 	return healthy
 }
 
+// SelectionFilter narrows the healthy backends offered to algorithms, returning the
+// candidates that remain.
+type SelectionFilter func(backends []*Backend) []*Backend
+
+// SetSelectionFilter sets the filter applied to healthy backends before algorithms
+// choose from them. A nil filter offers every healthy backend.
+func (p *Pool) SetSelectionFilter(filter SelectionFilter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.filter = filter
+}
+
+// GetSelectableBackends returns the healthy backends algorithms should choose from,
+// narrowed by the selection filter if one is set. Backup backends (weight 0) are
+// only returned when the filter leaves no primary backend. Primaries and backups
+// are filtered separately, so a filter preferring some backends, such as local
+// ones, never promotes a backup over a primary.
+func (p *Pool) GetSelectableBackends() []*Backend {
+	p.mu.RLock()
+	filter := p.filter
+	p.mu.RUnlock()
+	if filter == nil {
+		filter = func(backends []*Backend) []*Backend { return backends }
+	}
+
+	var primaries, backups []*Backend
+	for _, b := range p.GetHealthyBackends() {
+		if b.IsBackup() {
+			backups = append(backups, b)
+		} else {
+			primaries = append(primaries, b)
+		}
+	}
+
+	if len(primaries) > 0 {
+		if selected := filter(primaries); len(selected) > 0 {
+			return selected
+		}
+	}
+	if len(backups) == 0 {
+		return nil
+	}
+
+	return filter(backups)
+}
+
 // GetBackendByAddress finds a backend by address, returning nil if not found.
 func (p *Pool) GetBackendByAddress(address string) *Backend {
 	p.mu.RLock()
@@ -135,17 +299,77 @@ func (p *Pool) GetBackendByAddress(address string) *Backend {
 	return nil
 }
 
-// GetRandomBackend returns a random backend from the pool.
+// GetRandomBackend returns a random backend from the pool, excluding manually paused ones.
 func (p *Pool) GetRandomBackend() *Backend {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	backends := p.backends
+	var backends []*Backend
+	for _, b := range p.backends {
+		if !p.manualPauses[b.Address] {
+			backends = append(backends, b)
+		}
+	}
 	if len(backends) == 0 {
 		return nil
 	}
 
-	return backends[rand.Intn(len(backends))]
+	return backends[p.int63n(int64(len(backends)))]
+}
+
+// ToggleManualPause pauses or resumes a backend by address, independently of the
+// random simulation, and returns whether it is now paused. It returns false if
+// the backend is not in the pool.
+func (p *Pool) ToggleManualPause(address string) bool {
+	b := p.GetBackendByAddress(address)
+	if b == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	paused := !p.manualPauses[address]
+	if paused {
+		p.manualPauses[address] = true
+	} else {
+		delete(p.manualPauses, address)
+	}
+	randomlyPaused := p.pausedBackend == address
+	p.mu.Unlock()
+
+	if paused {
+		b.SetSimulatedDown(true)
+		p.emitEvent(EventBackendPaused, address)
+	} else {
+		// Leave the backend down if the random simulation also has it paused
+		if !randomlyPaused {
+			b.SetSimulatedDown(false)
+		}
+		p.emitEvent(EventBackendResumed, address)
+	}
+
+	return paused
+}
+
+// GetManualPauses returns the addresses of manually paused backends.
+func (p *Pool) GetManualPauses() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	addresses := make([]string, 0, len(p.manualPauses))
+	for address := range p.manualPauses {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	return addresses
+}
+
+// isManuallyPaused reports whether an operator has paused the backend.
+func (p *Pool) isManuallyPaused(address string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.manualPauses[address]
 }
 
 // Size returns the total number of backends in the pool.
@@ -171,18 +395,17 @@ func (p *Pool) HealthyCount() int {
 	return healthyCount
 }
 
-// simulateRandomBackendFailureAndRecovery simulates a random backend failure and recovery.
-func (p *Pool) simulateRandomBackendFailureAndRecovery() {
+// simulateRandomBackendFailureAndRecovery simulates a random backend failure and
+// recovery. If ctx is done during the pause, the backend is recovered early.
+func (p *Pool) simulateRandomBackendFailureAndRecovery(ctx context.Context) {
 	randomBackend := p.GetRandomBackend()
 	if randomBackend == nil {
 		return
 	}
 
-	// Calculate pause duration (15-20 seconds)
-	pauseDuration := time.Duration(15+rand.Intn(6)) * time.Second
-
-	// Update pause state
+	// Update pause state, picking a pause duration within the configured range
 	p.mu.Lock()
+	pauseDuration := p.simPauseMin + time.Duration(p.int63n(int64(p.simPauseMax-p.simPauseMin)+1))
 	p.pausedBackend = randomBackend.Address
 	p.pauseStartTime = time.Now()
 	p.pauseDuration = pauseDuration
@@ -193,11 +416,13 @@ func (p *Pool) simulateRandomBackendFailureAndRecovery() {
 	p.emitEvent(EventBackendDown, randomBackend.Address)
 
 	// Wait for pause duration
-	time.Sleep(pauseDuration)
+	sleepContext(ctx, pauseDuration)
 
-	// Recover backend from simulated down
-	randomBackend.SetSimulatedDown(false)
-	p.emitEvent(EventBackendRecovered, randomBackend.Address)
+	// Recover backend from simulated down, unless an operator has paused it meanwhile
+	if !p.isManuallyPaused(randomBackend.Address) {
+		randomBackend.SetSimulatedDown(false)
+		p.emitEvent(EventBackendRecovered, randomBackend.Address)
+	}
 
 	// Clear pause state
 	p.mu.Lock()
@@ -205,10 +430,17 @@ func (p *Pool) simulateRandomBackendFailureAndRecovery() {
 	p.mu.Unlock()
 }
 
-// SimulateRandomBackendFailureAndRecoveryLoop simulates a random backend failure and recovery in a loop.
-func (p *Pool) SimulateRandomBackendFailureAndRecoveryLoop() {
+// SimulateRandomBackendFailureAndRecoveryLoop simulates a random backend failure and
+// recovery in a loop until ctx is done.
+func (p *Pool) SimulateRandomBackendFailureAndRecoveryLoop(ctx context.Context) {
+	p.mu.RLock()
+	initialDelay := p.simInitialDelay
+	p.mu.RUnlock()
+
 	// Initial delay before first pause
-	time.Sleep(5 * time.Second)
+	if !sleepContext(ctx, initialDelay) {
+		return
+	}
 
 	for {
 		// Update next pause time
@@ -216,14 +448,30 @@ func (p *Pool) SimulateRandomBackendFailureAndRecoveryLoop() {
 		p.nextPauseTime = time.Now()
 		p.mu.Unlock()
 
-		p.simulateRandomBackendFailureAndRecovery()
+		p.simulateRandomBackendFailureAndRecovery(ctx)
 
 		// Update next pause time for the gap
 		p.mu.Lock()
-		p.nextPauseTime = time.Now().Add(25 * time.Second)
+		gap := p.simGap
+		p.nextPauseTime = time.Now().Add(gap)
 		p.mu.Unlock()
 
-		time.Sleep(25 * time.Second)
+		if !sleepContext(ctx, gap) {
+			return
+		}
+	}
+}
+
+// sleepContext waits for d, returning false if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
 	}
 }
 
@@ -232,11 +480,11 @@ func (p *Pool) RestartSimulation() {
 	p.mu.Lock()
 	pausedAddr := p.pausedBackend
 	p.pausedBackend = ""
-	p.nextPauseTime = time.Now().Add(5 * time.Second)
+	p.nextPauseTime = time.Now().Add(p.simInitialDelay)
 	p.mu.Unlock()
 
-	// If a backend was paused, recover it
-	if pausedAddr != "" {
+	// If a backend was paused, recover it unless an operator paused it too
+	if pausedAddr != "" && !p.isManuallyPaused(pausedAddr) {
 		backend := p.GetBackendByAddress(pausedAddr)
 		if backend != nil {
 			backend.SetSimulatedDown(false)
@@ -245,13 +493,12 @@ func (p *Pool) RestartSimulation() {
 	}
 }
 
-// MarkAllHealthy sets all backends to alive status.
+// MarkAllHealthy sets all backends to alive status. Each backend is updated
+// through SetAlive under its own lock, without holding the pool lock, so it is
+// safe alongside health checks and connection handling.
 func (p *Pool) MarkAllHealthy() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	for i := 0; i < len(p.backends); i++ {
-		p.backends[i].Alive = true
+	for _, b := range p.GetBackends() {
+		b.SetAlive(true)
 	}
 }
 
@@ -264,11 +511,30 @@ func (p *Pool) GetAllStats() []BackendStats {
 	for _, b := range p.backends {
 
 		address, alive, activeConnections, totalConnections := b.GetStats()
+		bytesSent, bytesReceived := b.GetBytes()
+		reason := b.GetDownReason()
+		responseTime := b.GetLastResponseTime()
+		idleTimeouts, lifetimeTimeouts := b.GetTimeouts()
+		dialFailures := b.GetDialFailures()
+		reusedConnections := b.GetReusedConnections()
+		availability := b.GetAvailability()
+		dialLatency := b.GetDialLatency()
 		backendStats = append(backendStats, BackendStats{
 			Address:           address,
+			Labels:            b.GetLabels(),
 			Alive:             alive,
 			ActiveConnections: activeConnections,
 			TotalConnections:  totalConnections,
+			BytesSent:         bytesSent,
+			BytesReceived:     bytesReceived,
+			Reason:            reason,
+			HealthCheckTime:   responseTime,
+			IdleTimeouts:      idleTimeouts,
+			LifetimeTimeouts:  lifetimeTimeouts,
+			DialFailures:      dialFailures,
+			ReusedConnections: reusedConnections,
+			Availability:      availability,
+			DialLatency:       dialLatency,
 		})
 	}
 
@@ -278,7 +544,18 @@ func (p *Pool) GetAllStats() []BackendStats {
 // BackendStats holds a snapshot of a backend's statistics.
 type BackendStats struct {
 	Address           string
+	Labels            map[string]string
 	Alive             bool
 	ActiveConnections int
 	TotalConnections  int64
+	BytesSent         int64
+	BytesReceived     int64
+	Reason            DownReason
+	HealthCheckTime   time.Duration
+	IdleTimeouts      int64
+	LifetimeTimeouts  int64
+	DialFailures      int64
+	ReusedConnections int64
+	Availability      float64
+	DialLatency       LatencySummary
 }
@@ -0,0 +1,212 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPassiveFailureEmitsUnhealthyEvent(t *testing.T) {
+	pool := NewPool()
+	b := NewBackend("127.0.0.1:9001")
+	pool.AddBackend(b)
+
+	var mu sync.Mutex
+	var events []PoolEvent
+	pool.SetEventCallback(func(event PoolEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+
+	b.SetAlive(false)
+	// Already dead, so no second event
+	b.SetAlive(false)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].Type != EventBackendUnhealthy || events[0].Backend != b.Address {
+		t.Errorf("event = %+v, want EventBackendUnhealthy for %s", events[0], b.Address)
+	}
+}
+
+func TestBackupUsedOnlyAfterPrimariesFail(t *testing.T) {
+	primary1 := NewBackendWithWeight("primary1:1", 1)
+	primary2 := NewBackendWithWeight("primary2:1", 2)
+	backup := NewBackendWithWeight("backup:1", 0)
+
+	pool := NewPool()
+	for _, b := range []*Backend{primary1, backup, primary2} {
+		pool.AddBackend(b)
+	}
+
+	selectable := func() map[string]bool {
+		got := make(map[string]bool)
+		for _, b := range pool.GetSelectableBackends() {
+			got[b.Address] = true
+		}
+		return got
+	}
+
+	if got := selectable(); len(got) != 2 || got[backup.Address] {
+		t.Errorf("all up: selectable = %v, want both primaries", got)
+	}
+
+	primary1.SetAlive(false)
+	if got := selectable(); len(got) != 1 || !got[primary2.Address] {
+		t.Errorf("one primary down: selectable = %v, want the other primary", got)
+	}
+
+	primary2.SetAlive(false)
+	if got := selectable(); len(got) != 1 || !got[backup.Address] {
+		t.Errorf("both primaries down: selectable = %v, want the backup", got)
+	}
+
+	primary1.SetAlive(true)
+	if got := selectable(); len(got) != 1 || !got[primary1.Address] {
+		t.Errorf("primary recovered: selectable = %v, want the recovered primary", got)
+	}
+}
+
+func TestZeroSimulationTimingNeverPausesBackend(t *testing.T) {
+	pool := NewPool()
+	b := NewBackend("127.0.0.1:9001")
+	pool.AddBackend(b)
+
+	var mu sync.Mutex
+	var events []PoolEvent
+	pool.SetEventCallback(func(event PoolEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	})
+
+	// Zeroed timing falls back to the defaults rather than pausing immediately
+	pool.SetSimulationTiming(0, 0, 0, 0)
+	if pool.simInitialDelay != defaultSimInitialDelay || pool.simGap != defaultSimGap {
+		t.Fatalf("timing = %v/%v, want the defaults", pool.simInitialDelay, pool.simGap)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	pool.SimulateRandomBackendFailureAndRecoveryLoop(ctx)
+
+	if paused, _, _, _ := pool.GetPauseState(); paused != "" {
+		t.Errorf("backend %s paused during the initial delay", paused)
+	}
+	b.mu.RLock()
+	down := b.SimulatedDown
+	b.mu.RUnlock()
+	if down {
+		t.Error("backend simulated down during the initial delay")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 0 {
+		t.Errorf("got events %+v, want none", events)
+	}
+}
+
+// TestMarkAllHealthyConcurrentWithHealthChecks is meant to run under -race.
+func TestMarkAllHealthyConcurrentWithHealthChecks(t *testing.T) {
+	pool := NewPool()
+	for range 4 {
+		// Nothing listens on these, so every check marks its backend down
+		pool.AddBackend(NewBackend(closedAddr(t)))
+	}
+
+	var wg sync.WaitGroup
+	for _, b := range pool.GetBackends() {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				b.CheckHealth(100 * time.Millisecond)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				b.IsAlive()
+				b.GetDownReason()
+			}
+		}()
+	}
+	for range 20 {
+		pool.MarkAllHealthy()
+		pool.HealthyCount()
+	}
+	wg.Wait()
+
+	pool.MarkAllHealthy()
+	if n := pool.HealthyCount(); n != pool.Size() {
+		t.Errorf("%d of %d backends healthy after MarkAllHealthy", n, pool.Size())
+	}
+}
+
+// pausedSequence runs the failure simulation on a pool of five backends seeded
+// with seed, and returns the addresses of the first n backends it paused.
+func pausedSequence(t *testing.T, seed int64, n int) []string {
+	t.Helper()
+
+	pool := NewPool()
+	for i := range 5 {
+		pool.AddBackend(NewBackend(fmt.Sprintf("10.0.0.%d:80", i+1)))
+	}
+	pool.SetSimulationSeed(seed)
+	pool.SetSimulationTiming(time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond)
+
+	paused := make(chan string, n)
+	pool.SetEventCallback(func(event PoolEvent) {
+		if event.Type == EventBackendDown {
+			select {
+			case paused <- event.Backend:
+			default:
+			}
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.SimulateRandomBackendFailureAndRecoveryLoop(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	var sequence []string
+	for range n {
+		select {
+		case addr := <-paused:
+			sequence = append(sequence, addr)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %d pauses before timing out", len(sequence))
+		}
+	}
+	return sequence
+}
+
+func TestSeededSimulationIsReproducible(t *testing.T) {
+	first := pausedSequence(t, 42, 10)
+	second := pausedSequence(t, 42, 10)
+
+	if !slices.Equal(first, second) {
+		t.Errorf("same seed paused %v, then %v", first, second)
+	}
+
+	// Ten picks from five backends should not all land on one
+	if distinct := slices.Compact(slices.Sorted(slices.Values(first))); len(distinct) < 2 {
+		t.Errorf("seeded simulation only ever paused %v", distinct)
+	}
+}
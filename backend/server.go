@@ -3,14 +3,62 @@ package backend
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"strings"
 )
 
+// EchoMode controls how the backend echo server replies.
+type EchoMode int
+
+const (
+	EchoPrefixed EchoMode = iota // Send a welcome banner and prefix each echoed line with the backend address
+	EchoRaw                      // Echo exactly the received bytes, so bytes in equal bytes out
+)
+
+// WelcomeBanner is the format of the first line the prefixed echo server sends,
+// with a single %s replaced by the backend address. It may be changed before
+// servers are started; ParseWelcome understands any format with one %s.
+var WelcomeBanner = "Connected to Backend %s\n"
+
+// FormatWelcome returns the welcome banner for a backend address.
+func FormatWelcome(address string) string {
+	return fmt.Sprintf(WelcomeBanner, address)
+}
+
+// ParseWelcome extracts the backend address from a welcome banner line, reporting
+// whether the line matched WelcomeBanner. Surrounding whitespace is ignored.
+func ParseWelcome(line string) (string, bool) {
+	prefix, suffix, found := strings.Cut(strings.TrimSpace(WelcomeBanner), "%s")
+	if !found {
+		return "", false
+	}
+
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, prefix) || !strings.HasSuffix(line, suffix) ||
+		len(line) < len(prefix)+len(suffix) {
+		return "", false
+	}
+
+	return line[len(prefix) : len(line)-len(suffix)], true
+}
+
 // StartServer starts an echo server on the backend address.
 func StartServer(b *Backend) error {
+	return StartServerWithMode(b, EchoPrefixed)
+}
+
+// StartServerWithMode starts an echo server on the backend address using the given echo mode.
+// It only returns if the address cannot be bound, in which case the backend reports
+// ReasonBindFailed while it is down.
+func StartServerWithMode(b *Backend, mode EchoMode) error {
 	listener, err := net.Listen("tcp", b.getAddress())
 	if err != nil {
+		b.mu.Lock()
+		b.serverErr = err
+		b.mu.Unlock()
+
 		return fmt.Errorf("failed to start backend server: %w", err)
 	}
 	defer listener.Close()
@@ -24,14 +72,20 @@ func StartServer(b *Backend) error {
 			continue
 		}
 
-		// Check if the server is "alive". If not, wait until it is resurrected.
+		// Check if the server is simulated down. If so, wait until it is resurrected.
+		// Waiting on SimulatedDown rather than Alive lets health checks that expect a
+		// reply reach the server and mark it alive again.
 		b.mu.Lock()
-		for !b.Alive {
+		for b.SimulatedDown {
 			b.cond.Wait()
 		}
 		b.mu.Unlock()
 
-		go handleConnection(conn, b.getAddress())
+		if mode == EchoRaw {
+			go handleRawConnection(conn, b.getAddress())
+		} else {
+			go handleConnection(conn, b.getAddress())
+		}
 	}
 }
 
@@ -42,8 +96,7 @@ func handleConnection(conn net.Conn, address string) {
 	clientAddr := conn.RemoteAddr().String()
 	log.Printf("[Backend %s] New connection from %s", address, clientAddr)
 
-	welcome := fmt.Sprintf("Connected to Backend %s\n", address)
-	conn.Write([]byte(welcome))
+	conn.Write([]byte(FormatWelcome(address)))
 
 	scanner := bufio.NewScanner(conn)
 	for scanner.Scan() {
@@ -56,3 +109,18 @@ func handleConnection(conn net.Conn, address string) {
 
 	log.Printf("[Backend %s] Connection closed from %s", address, clientAddr)
 }
+
+// handleRawConnection echoes received bytes back to the client unchanged.
+func handleRawConnection(conn net.Conn, address string) {
+	defer conn.Close()
+
+	clientAddr := conn.RemoteAddr().String()
+	log.Printf("[Backend %s] New raw connection from %s", address, clientAddr)
+
+	n, err := io.Copy(conn, conn)
+	if err != nil {
+		log.Printf("[Backend %s] Echo error from %s: %v", address, clientAddr, err)
+	}
+
+	log.Printf("[Backend %s] Connection closed from %s after echoing %d bytes", address, clientAddr, n)
+}
@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// tcpPair returns the two ends of a loopback TCP connection. Both are closed
+// when the test ends.
+func tcpPair(t *testing.T) (*net.TCPConn, *net.TCPConn) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	dialed, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		dialed.Close()
+		accepted.Close()
+	})
+
+	return dialed.(*net.TCPConn), accepted.(*net.TCPConn)
+}
+
+func TestRawEchoBytePerfectParity(t *testing.T) {
+	client, server := tcpPair(t)
+	go handleRawConnection(server, "test")
+
+	// Binary data with newlines, which the prefixed mode would rewrite
+	payload := make([]byte, 64*1024)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+
+	go func() {
+		client.Write(payload)
+		client.CloseWrite()
+	}()
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(payload) {
+		t.Fatalf("echoed %d bytes, want %d", len(got), len(payload))
+	}
+	if !bytes.Equal(got, payload) {
+		t.Error("echoed bytes differ from the payload")
+	}
+}
+
+func TestWelcomeBannerRoundTrip(t *testing.T) {
+	defer func(format string) { WelcomeBanner = format }(WelcomeBanner)
+
+	for _, format := range []string{"Connected to Backend %s\n", "[%s] ready\r\n", "%s\n"} {
+		WelcomeBanner = format
+
+		address, ok := ParseWelcome(FormatWelcome("10.0.0.1:9001"))
+		if !ok || address != "10.0.0.1:9001" {
+			t.Errorf("format %q: ParseWelcome = %q, %v, want the address", format, address, ok)
+		}
+	}
+
+	WelcomeBanner = "Connected to Backend %s\n"
+	for _, line := range []string{"Hello there", "Backend 10.0.0.1:9001", ""} {
+		if address, ok := ParseWelcome(line); ok {
+			t.Errorf("ParseWelcome(%q) = %q, want no match", line, address)
+		}
+	}
+}
+
+func TestServerSendsParsableBanner(t *testing.T) {
+	client, server := tcpPair(t)
+	go handleConnection(server, "127.0.0.1:9001")
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(client).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if address, ok := ParseWelcome(line); !ok || address != "127.0.0.1:9001" {
+		t.Errorf("ParseWelcome(%q) = %q, %v, want the server's address", line, address, ok)
+	}
+}
+
+func TestStartServerReportsBindFailure(t *testing.T) {
+	// The first backend's server holds the address
+	first, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+
+	second := NewBackend(first.Addr().String())
+	done := make(chan error, 1)
+	go func() { done <- StartServer(second) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("StartServer returned nil for an address already in use")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("StartServer did not report the bind failure")
+	}
+
+	second.SetAlive(false)
+	if reason := second.GetDownReason(); reason != ReasonBindFailed {
+		t.Errorf("down reason = %q, want %q", reason, ReasonBindFailed)
+	}
+}
@@ -8,21 +8,162 @@ import (
 	"time"
 )
 
-// Config holds load balancer configuration.
+// Config holds load balancer configuration. Durations may be given as numbers of
+// the unit in their JSON name (seconds, or milliseconds for _ms fields) or as Go
+// duration strings such as "500ms" or "2m".
 type Config struct {
-	ListenAddr          string          `json:"listen_addr"`
-	Backends            []BackendConfig `json:"backends"`
-	HealthCheckInterval time.Duration   `json:"health_check_interval_seconds"`
-	ConnectTimeout      time.Duration   `json:"connect_timeout_seconds"`
+	Version        int              `json:"version"`                 // Schema version, see CurrentVersion; older files are migrated, 0 means current
+	ListenAddr     string           `json:"listen_addr"`             // Address of the main listener
+	Listeners      []ListenerConfig `json:"listeners"`               // Additional listeners
+	Backends       []BackendConfig  `json:"backends"`                // Backends to balance across
+	ConnectTimeout time.Duration    `json:"connect_timeout_seconds"` // How long a backend dial may take
+	ReusePort      bool             `json:"reuse_port"`              // Bind with SO_REUSEPORT (Linux only) so a new instance can take over
+	ListenBacklog  int              `json:"listen_backlog"`          // Accept queue length per TCP listener (Linux only), 0 for the system default
+	TCPKeepAlive   time.Duration    `json:"tcp_keepalive_seconds"`   // Keepalive probe period on both sides of proxied connections, 0 disables probes
+
+	// Protocol selects TCP (the default), UDP or HTTP balancing. UDP clients are
+	// mapped to a backend per source address until no datagrams flow for
+	// UDPSessionTimeout; TCP health checks are skipped for UDP backends, so use
+	// HTTP checks or none. HTTP balances each request, so requests on one
+	// keep-alive connection can reach different backends; it cannot be combined
+	// with ConnectionReuse, SendProxyProtocol or StickyByFirstLine.
+	Protocol              string        `json:"protocol"`
+	UDPSessionTimeout     time.Duration `json:"udp_session_timeout_seconds"` // How long a UDP client keeps its backend without traffic
+	HTTPForwardedDisabled bool          `json:"http_forwarded_disabled"`     // Don't set X-Forwarded-For, -Proto and -Host on HTTP requests
+
+	// Health checks
+	HealthCheckInterval   time.Duration `json:"health_check_interval_seconds"`    // 0 disables active checks, leaving only passive detection on failed dials
+	HealthCheckType       string        `json:"health_check_type"`                // HealthCheckTCP (default), HealthCheckHTTP or HealthCheckExpect
+	HealthCheckPath       string        `json:"health_check_path"`                // Path of HTTP checks, which pass only on a 2xx response
+	HealthCheckSend       string        `json:"health_check_send"`                // Sent by expect checks after connecting, if set
+	HealthCheckExpect     string        `json:"health_check_expect"`              // Reply an expect check must contain to pass
+	HealthCheckTimeout    time.Duration `json:"health_check_timeout_seconds"`     // How long each check may take, ConnectTimeout when unset
+	HealthCheckMaxBackoff time.Duration `json:"health_check_max_backoff_seconds"` // Cap on the doubling delay between checks of a failing backend, 8 intervals when unset
+
+	// Connection limits and timeouts
+	IdleTimeout                  time.Duration `json:"idle_timeout_seconds"`              // Close connections idle this long, 0 for no limit
+	MaxConnectionDuration        time.Duration `json:"max_connection_duration_seconds"`   // Close connections open this long, 0 for no limit
+	ReadTimeout                  time.Duration `json:"read_timeout_seconds"`              // Close once either side sends nothing this long, even while the other is active
+	WriteTimeout                 time.Duration `json:"write_timeout_seconds"`             // Close once a write to a side that stopped reading blocks this long
+	MaxConcurrentConnections     int           `json:"max_concurrent_connections"`        // Client connections handled at once, 0 means unlimited
+	HighPriorityReserve          int           `json:"high_priority_reserve"`             // Connection slots only high priority connections can use
+	QoSRules                     []QoSRule     `json:"qos_rules"`                         // Rules assigning connection priorities
+	MaxConnectionsPerSecondPerIP int           `json:"max_connections_per_second_per_ip"` // New connections allowed per client IP per second, 0 means unlimited
+
+	// Retries and failure handling
+	MaxRetries          int           `json:"max_retries"`              // Backend attempts per connection, 0 means one per backend in the pool
+	RetryBackoff        time.Duration `json:"retry_backoff_ms"`         // Wait between attempts
+	MaxRetriesPerSecond int           `json:"max_retries_per_second"`   // Retries across all connections, 0 means unlimited
+	ErrorBanner         string        `json:"error_banner"`             // Written to the client when every attempt fails
+	NoBackendMessage    string        `json:"no_backend_message"`       // Replaces ErrorBanner when no backend was available at all
+	FailureThreshold    int           `json:"failure_threshold"`        // Consecutive dial failures that open a backend's circuit breaker, 0 disables it
+	BreakerCooldown     time.Duration `json:"breaker_cooldown_seconds"` // How long the breaker stays open before a trial connection
+	OutlierErrorRatio   float64       `json:"outlier_error_ratio"`      // Failure ratio of proxied connections that ejects a backend, 0 disables it
+	OutlierWindow       time.Duration `json:"outlier_window_seconds"`   // Window the failure ratio is counted over, 30s when unset
+	OutlierEjection     time.Duration `json:"outlier_ejection_seconds"` // How long an outlier is ejected, even if health checks pass, 30s when unset
+	SlowStart           time.Duration `json:"slow_start_seconds"`       // How long a recovered backend's weight ramps up from near zero, 0 disables it
+
+	// Backend selection
+	StickyByFirstLine      bool          `json:"sticky_by_first_line"`      // Hash the first line a TCP client sends to pick its backend; the line is still forwarded
+	StickyMaxLineBytes     int           `json:"sticky_max_line_bytes"`     // Clients with a longer first line are disconnected, 4096 when unset
+	LocalZone              string        `json:"local_zone"`                // Prefer backends in this zone, spilling over only while none is available
+	MinShareEvery          int           `json:"min_share_every"`           // Give every healthy backend at least one of this many selections, 0 disables it
+	LoadWeightConnections  float64       `json:"load_weight_connections"`   // least_loaded score per active connection
+	LoadWeightLatencyMs    float64       `json:"load_weight_latency_ms"`    // least_loaded score per millisecond of health check response time
+	LoadWeightFailures     float64       `json:"load_weight_failures"`      // least_loaded score per consecutive dial failure; with all weights 0, 1, 0.1 and 5 are used
+	DrainOnZeroWeight      bool          `json:"drain_on_zero_weight"`      // Drain a backend whose weight is set to zero at runtime
+	ZeroWeightDrainTimeout time.Duration `json:"zero_weight_drain_seconds"` // How long a zero weight backend's connections may finish
+
+	// Proxying
+	CloseOnEOF        bool  `json:"close_on_eof"`        // Close both directions once either side reaches EOF, for request/response protocols
+	BufferSize        int   `json:"buffer_size"`         // Size in bytes of the pooled copy buffers, 0 means 32KB
+	PerConnectionBPS  int64 `json:"per_connection_bps"`  // Throttle each direction of a TCP connection to this many bytes per second, 0 means unlimited
+	SendProxyProtocol bool  `json:"send_proxy_protocol"` // Prefix backend connections with a PROXY protocol v1 header
+
+	// ConnectionReuse keeps a TCP backend connection open once its client closes
+	// cleanly and hands it to the backend's next client. A connection is only
+	// reused once the backend has replied and gone quiet, and a backend greeting
+	// only reaches the first client. It cannot be combined with SendProxyProtocol
+	// or CloseOnEOF.
+	ConnectionReuse        bool `json:"connection_reuse"`
+	MaxIdleConnsPerBackend int  `json:"max_idle_conns_per_backend"` // Idle connections kept per backend, 4 when unset
+
+	// Scaling recommendations
+	ScaleUpUtilization   float64       `json:"scale_up_utilization"`   // Utilization that recommends scaling up, 0 disables recommendations
+	ScaleDownUtilization float64       `json:"scale_down_utilization"` // Utilization that recommends scaling down
+	ScaleSustain         time.Duration `json:"scale_sustain_seconds"`  // How long utilization must stay beyond a watermark
+
+	// Stats server and state
+	StatsAddr            string        `json:"stats_addr"`                     // Listen address of the stats and admin server, not started when empty
+	StatsShutdownTimeout time.Duration `json:"stats_shutdown_timeout_seconds"` // How long shutdown waits for in-flight requests, 5s when unset
+	StatsStreamInterval  time.Duration `json:"stats_stream_interval_seconds"`  // How often /stats/stream pushes a snapshot, 1s when unset
+	StateFile            string        `json:"state_file"`                     // Records paused backends across restarts; a missing or corrupt file is ignored
+
+	// TUI and failure simulation
+	SimInitialDelay    time.Duration `json:"sim_initial_delay_seconds"` // Delay before the first pause, 5s when unset
+	SimPauseMin        time.Duration `json:"sim_pause_min_seconds"`     // Shortest pause, 15s when unset
+	SimPauseMax        time.Duration `json:"sim_pause_max_seconds"`     // Longest pause, 20s when unset
+	SimGap             time.Duration `json:"sim_gap_seconds"`           // Time between pauses, 25s when unset
+	SimDisabled        bool          `json:"sim_disabled"`              // Neither pause backends nor start echo servers, to monitor real ones
+	SimSeed            int64         `json:"sim_seed"`                  // Non-zero to pick the same backends and pause lengths on every run
+	TUIRefreshInterval time.Duration `json:"tui_refresh_interval_ms"`   // How often the TUI redraws, 200ms when unset; + and - change it at runtime
+	SparklineSamples   int           `json:"sparkline_samples"`         // Refresh ticks of active connections the status bar sparkline shows, 0 means 40
 }
 
+// Protocols for Config.Protocol. An empty protocol means TCP.
+const (
+	ProtocolTCP  = "tcp"
+	ProtocolUDP  = "udp"
+	ProtocolHTTP = "http"
+)
+
+// Health check types for Config.HealthCheckType. An empty type means TCP.
+const (
+	HealthCheckTCP    = "tcp"
+	HealthCheckHTTP   = "http"
+	HealthCheckExpect = "expect"
+)
+
 // BackendConfig holds backend server configuration.
 type BackendConfig struct {
-	Address string `json:"address"`
-	Weight  int    `json:"weight"`
+	Address        string            `json:"address"`
+	HealthAddress  string            `json:"health_address"`  // Where health checks connect, for health served on another port; empty means Address
+	Weight         int               `json:"weight"`          // 0 makes a backup, only used while no backend with a positive weight is healthy
+	Tags           []string          `json:"tags"`            // Tags listeners select backends by
+	Zone           string            `json:"zone"`            // Zone or region the backend runs in, see Config.LocalZone
+	Labels         map[string]string `json:"labels"`          // Labels to filter /stats by and export in /metrics; keys must be valid Prometheus label names
+	MaxConnections int               `json:"max_connections"` // Connection cap, 0 means none
+	Cost           int               `json:"cost"`            // Static latency/cost hint, lower is preferred
+}
+
+// QoS priorities for QoSRule.Priority.
+const (
+	PriorityHigh = "high"
+	PriorityLow  = "low"
+)
+
+// QoSRule assigns a priority to client connections from a network and/or on a listener.
+// Empty fields match anything; the first matching rule wins and unmatched connections are low priority.
+type QoSRule struct {
+	CIDR       string `json:"cidr"`
+	ListenAddr string `json:"listen_addr"`
+	Priority   string `json:"priority"`
+}
+
+// ListenerConfig holds configuration for an additional listener.
+// A listener with tags only routes to backends that have all of those tags, and
+// one with backends only routes to those addresses; without either it shares the
+// whole pool. A listener without an algorithm uses the load balancer's algorithm.
+type ListenerConfig struct {
+	ListenAddr string   `json:"listen_addr"`
+	Algorithm  string   `json:"algorithm"`
+	Tags       []string `json:"tags"`
+	Backends   []string `json:"backends"`
 }
 
-// LoadConfig reads configuration from a JSON file.
+// LoadConfig reads configuration from a JSON file, migrates it to CurrentVersion,
+// applies any environment variable overrides (see EnvListenAddr and EnvBackends),
+// and validates it.
 func LoadConfig(path string) (*Config, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
@@ -39,8 +180,17 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	config.HealthCheckInterval *= time.Second
-	config.ConnectTimeout *= time.Second
+	if err = config.migrate(); err != nil {
+		return nil, err
+	}
+
+	if err = config.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err = config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 
 	return config, nil
 }
@@ -48,6 +198,7 @@ func LoadConfig(path string) (*Config, error) {
 // DefaultConfig returns default configuration values.
 func DefaultConfig() *Config {
 	return &Config{
+		Version:    CurrentVersion,
 		ListenAddr: ":8080",
 		Backends: []BackendConfig{
 			{Address: "localhost:9001", Weight: 1},
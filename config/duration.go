@@ -0,0 +1,150 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a config duration that accepts either a number of units (seconds
+// for Duration) or a Go duration string such as "500ms" or "2m".
+type Duration time.Duration
+
+// UnmarshalJSON accepts numeric seconds or a Go duration string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	parsed, err := parseDuration(data, time.Second)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// millisDuration is like Duration but treats bare numbers as milliseconds, for
+// fields whose name carries an _ms suffix.
+type millisDuration time.Duration
+
+// UnmarshalJSON accepts numeric milliseconds or a Go duration string.
+func (d *millisDuration) UnmarshalJSON(data []byte) error {
+	parsed, err := parseDuration(data, time.Millisecond)
+	if err != nil {
+		return err
+	}
+
+	*d = millisDuration(parsed)
+	return nil
+}
+
+// parseDuration decodes a JSON number of units or a Go duration string.
+func parseDuration(data []byte, unit time.Duration) (time.Duration, error) {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		return time.Duration(number * float64(unit)), nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return 0, fmt.Errorf("duration must be a number or a string, got %s", data)
+	}
+
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", text, err)
+	}
+
+	return parsed, nil
+}
+
+// UnmarshalJSON decodes a Config, reading its duration fields with Duration so
+// they may be given as numbers or duration strings.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	// rawConfig has Config's fields without its methods, avoiding recursion
+	type rawConfig Config
+
+	// Fields declared here shadow the embedded ones with the same JSON names
+	aux := struct {
+		*rawConfig
+		HealthCheckInterval    Duration       `json:"health_check_interval_seconds"`
+		ConnectTimeout         Duration       `json:"connect_timeout_seconds"`
+		IdleTimeout            Duration       `json:"idle_timeout_seconds"`
+		MaxConnectionDuration  Duration       `json:"max_connection_duration_seconds"`
+		ScaleSustain           Duration       `json:"scale_sustain_seconds"`
+		ZeroWeightDrainTimeout Duration       `json:"zero_weight_drain_seconds"`
+		BreakerCooldown        Duration       `json:"breaker_cooldown_seconds"`
+		UDPSessionTimeout      Duration       `json:"udp_session_timeout_seconds"`
+		RetryBackoff           millisDuration `json:"retry_backoff_ms"`
+		SlowStart              Duration       `json:"slow_start_seconds"`
+		HealthCheckMaxBackoff  Duration       `json:"health_check_max_backoff_seconds"`
+		OutlierWindow          Duration       `json:"outlier_window_seconds"`
+		OutlierEjection        Duration       `json:"outlier_ejection_seconds"`
+		HealthCheckTimeout     Duration       `json:"health_check_timeout_seconds"`
+		TCPKeepAlive           Duration       `json:"tcp_keepalive_seconds"`
+		SimInitialDelay        Duration       `json:"sim_initial_delay_seconds"`
+		SimPauseMin            Duration       `json:"sim_pause_min_seconds"`
+		SimPauseMax            Duration       `json:"sim_pause_max_seconds"`
+		SimGap                 Duration       `json:"sim_gap_seconds"`
+		ReadTimeout            Duration       `json:"read_timeout_seconds"`
+		WriteTimeout           Duration       `json:"write_timeout_seconds"`
+		StatsShutdownTimeout   Duration       `json:"stats_shutdown_timeout_seconds"`
+		StatsStreamInterval    Duration       `json:"stats_stream_interval_seconds"`
+		TUIRefreshInterval     millisDuration `json:"tui_refresh_interval_ms"`
+	}{
+		rawConfig:              (*rawConfig)(c),
+		HealthCheckInterval:    Duration(c.HealthCheckInterval),
+		ConnectTimeout:         Duration(c.ConnectTimeout),
+		IdleTimeout:            Duration(c.IdleTimeout),
+		MaxConnectionDuration:  Duration(c.MaxConnectionDuration),
+		ScaleSustain:           Duration(c.ScaleSustain),
+		ZeroWeightDrainTimeout: Duration(c.ZeroWeightDrainTimeout),
+		BreakerCooldown:        Duration(c.BreakerCooldown),
+		UDPSessionTimeout:      Duration(c.UDPSessionTimeout),
+		RetryBackoff:           millisDuration(c.RetryBackoff),
+		SlowStart:              Duration(c.SlowStart),
+		HealthCheckMaxBackoff:  Duration(c.HealthCheckMaxBackoff),
+		OutlierWindow:          Duration(c.OutlierWindow),
+		OutlierEjection:        Duration(c.OutlierEjection),
+		HealthCheckTimeout:     Duration(c.HealthCheckTimeout),
+		TCPKeepAlive:           Duration(c.TCPKeepAlive),
+		SimInitialDelay:        Duration(c.SimInitialDelay),
+		SimPauseMin:            Duration(c.SimPauseMin),
+		SimPauseMax:            Duration(c.SimPauseMax),
+		SimGap:                 Duration(c.SimGap),
+		ReadTimeout:            Duration(c.ReadTimeout),
+		WriteTimeout:           Duration(c.WriteTimeout),
+		StatsShutdownTimeout:   Duration(c.StatsShutdownTimeout),
+		StatsStreamInterval:    Duration(c.StatsStreamInterval),
+		TUIRefreshInterval:     millisDuration(c.TUIRefreshInterval),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	c.HealthCheckInterval = time.Duration(aux.HealthCheckInterval)
+	c.ConnectTimeout = time.Duration(aux.ConnectTimeout)
+	c.IdleTimeout = time.Duration(aux.IdleTimeout)
+	c.MaxConnectionDuration = time.Duration(aux.MaxConnectionDuration)
+	c.ScaleSustain = time.Duration(aux.ScaleSustain)
+	c.ZeroWeightDrainTimeout = time.Duration(aux.ZeroWeightDrainTimeout)
+	c.BreakerCooldown = time.Duration(aux.BreakerCooldown)
+	c.UDPSessionTimeout = time.Duration(aux.UDPSessionTimeout)
+	c.RetryBackoff = time.Duration(aux.RetryBackoff)
+	c.SlowStart = time.Duration(aux.SlowStart)
+	c.HealthCheckMaxBackoff = time.Duration(aux.HealthCheckMaxBackoff)
+	c.OutlierWindow = time.Duration(aux.OutlierWindow)
+	c.OutlierEjection = time.Duration(aux.OutlierEjection)
+	c.HealthCheckTimeout = time.Duration(aux.HealthCheckTimeout)
+	c.TCPKeepAlive = time.Duration(aux.TCPKeepAlive)
+	c.SimInitialDelay = time.Duration(aux.SimInitialDelay)
+	c.SimPauseMin = time.Duration(aux.SimPauseMin)
+	c.SimPauseMax = time.Duration(aux.SimPauseMax)
+	c.SimGap = time.Duration(aux.SimGap)
+	c.ReadTimeout = time.Duration(aux.ReadTimeout)
+	c.WriteTimeout = time.Duration(aux.WriteTimeout)
+	c.StatsShutdownTimeout = time.Duration(aux.StatsShutdownTimeout)
+	c.StatsStreamInterval = time.Duration(aux.StatsStreamInterval)
+	c.TUIRefreshInterval = time.Duration(aux.TUIRefreshInterval)
+
+	return nil
+}
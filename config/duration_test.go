@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDurationUnmarshal(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{`"1500ms"`, 1500 * time.Millisecond},
+		{`"2m"`, 2 * time.Minute},
+		{`5`, 5 * time.Second},
+		{`0.5`, 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		var d Duration
+		if err := json.Unmarshal([]byte(tt.input), &d); err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if time.Duration(d) != tt.want {
+			t.Errorf("%s = %v, want %v", tt.input, time.Duration(d), tt.want)
+		}
+	}
+}
+
+func TestDurationUnmarshalInvalid(t *testing.T) {
+	for _, input := range []string{`"ten seconds"`, `true`} {
+		var d Duration
+		if err := json.Unmarshal([]byte(input), &d); err == nil {
+			t.Errorf("%s: want an error", input)
+		}
+	}
+}
+
+func TestConfigDurationFields(t *testing.T) {
+	data := `{
+		"health_check_interval_seconds": "1500ms",
+		"connect_timeout_seconds": 5,
+		"idle_timeout_seconds": "2m",
+		"retry_backoff_ms": 250
+	}`
+
+	var c Config
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.HealthCheckInterval != 1500*time.Millisecond {
+		t.Errorf("HealthCheckInterval = %v, want 1.5s", c.HealthCheckInterval)
+	}
+	if c.ConnectTimeout != 5*time.Second {
+		t.Errorf("ConnectTimeout = %v, want 5s from bare seconds", c.ConnectTimeout)
+	}
+	if c.IdleTimeout != 2*time.Minute {
+		t.Errorf("IdleTimeout = %v, want 2m", c.IdleTimeout)
+	}
+	if c.RetryBackoff != 250*time.Millisecond {
+		t.Errorf("RetryBackoff = %v, want 250ms from bare milliseconds", c.RetryBackoff)
+	}
+}
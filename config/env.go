@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables that override file configuration. Each one that is set
+// and non-empty replaces the corresponding setting entirely; the file (or the
+// defaults, for LoadConfigFromEnv) supplies everything else.
+const (
+	// EnvListenAddr replaces listen_addr.
+	EnvListenAddr = "TCP_LB_LISTEN_ADDR"
+	// EnvBackends replaces the backends list with comma-separated host:port or
+	// host:port:weight entries. Backends without a weight get a weight of 1.
+	EnvBackends = "TCP_LB_BACKENDS"
+)
+
+// LoadConfigFromEnv builds configuration from DefaultConfig overridden by the
+// environment variables, for deployments without a config file.
+func LoadConfigFromEnv() (*Config, error) {
+	config := DefaultConfig()
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return config, nil
+}
+
+// applyEnv overrides settings from the environment variables that are set.
+func (c *Config) applyEnv() error {
+	if addr := os.Getenv(EnvListenAddr); addr != "" {
+		c.ListenAddr = addr
+	}
+
+	if list := os.Getenv(EnvBackends); list != "" {
+		backends, err := parseBackendList(list)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvBackends, err)
+		}
+		c.Backends = backends
+	}
+
+	return nil
+}
+
+// parseBackendList parses comma-separated host:port[:weight] entries.
+func parseBackendList(list string) ([]BackendConfig, error) {
+	var backends []BackendConfig
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// A plain host:port, including a bracketed IPv6 host, has weight 1
+		if _, _, err := net.SplitHostPort(entry); err == nil {
+			backends = append(backends, BackendConfig{Address: entry, Weight: 1})
+			continue
+		}
+
+		sep := strings.LastIndex(entry, ":")
+		if sep < 0 {
+			return nil, fmt.Errorf("%q is not host:port or host:port:weight", entry)
+		}
+
+		weight, err := strconv.Atoi(entry[sep+1:])
+		if err != nil {
+			return nil, fmt.Errorf("%q has invalid weight: %w", entry, err)
+		}
+
+		backends = append(backends, BackendConfig{Address: entry[:sep], Weight: weight})
+	}
+
+	return backends, nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeConfig writes a config file with one backend and returns its path.
+func writeConfig(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+		"listen_addr": ":7000",
+		"connect_timeout_seconds": 2,
+		"backends": [{"address": "file-backend:9000", "weight": 3}]
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadConfigFromEnvOnly(t *testing.T) {
+	t.Setenv(EnvListenAddr, ":9090")
+	t.Setenv(EnvBackends, "a:9001:2, b:9002,[::1]:9003")
+
+	c, err := LoadConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.ListenAddr != ":9090" {
+		t.Errorf("ListenAddr = %q, want :9090", c.ListenAddr)
+	}
+	want := []BackendConfig{
+		{Address: "a:9001", Weight: 2},
+		{Address: "b:9002", Weight: 1},
+		{Address: "[::1]:9003", Weight: 1},
+	}
+	if !reflect.DeepEqual(c.Backends, want) {
+		t.Errorf("Backends = %+v, want %+v", c.Backends, want)
+	}
+}
+
+func TestLoadConfigFileOnly(t *testing.T) {
+	t.Setenv(EnvListenAddr, "")
+	t.Setenv(EnvBackends, "")
+
+	c, err := LoadConfig(writeConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.ListenAddr != ":7000" {
+		t.Errorf("ListenAddr = %q, want the file's :7000", c.ListenAddr)
+	}
+	if len(c.Backends) != 1 || c.Backends[0].Address != "file-backend:9000" || c.Backends[0].Weight != 3 {
+		t.Errorf("Backends = %+v, want the file's backend", c.Backends)
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	t.Setenv(EnvListenAddr, "")
+	t.Setenv(EnvBackends, "env-backend:9001:4")
+
+	c, err := LoadConfig(writeConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The backend list is replaced while unset variables leave the file's settings
+	if len(c.Backends) != 1 || c.Backends[0].Address != "env-backend:9001" || c.Backends[0].Weight != 4 {
+		t.Errorf("Backends = %+v, want only the environment's backend", c.Backends)
+	}
+	if c.ListenAddr != ":7000" {
+		t.Errorf("ListenAddr = %q, want the file's :7000", c.ListenAddr)
+	}
+}
+
+func TestEnvInvalidBackendList(t *testing.T) {
+	t.Setenv(EnvBackends, "a:9001:heavy")
+
+	if _, err := LoadConfigFromEnv(); err == nil {
+		t.Error("LoadConfigFromEnv() = nil error, want an invalid weight error")
+	}
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate checks the configuration for mistakes that would otherwise surface as
+// confusing runtime behavior, returning an error listing every problem found.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.ListenAddr == "" && len(c.Listeners) == 0 {
+		errs = append(errs, errors.New("listen_addr is required when no listeners are configured"))
+	}
+	if c.ListenAddr != "" {
+		if err := validateAddr(c.ListenAddr, true); err != nil {
+			errs = append(errs, fmt.Errorf("listen_addr %q: %w", c.ListenAddr, err))
+		}
+	}
+
+	if c.StatsAddr != "" {
+		if err := validateAddr(c.StatsAddr, true); err != nil {
+			errs = append(errs, fmt.Errorf("stats_addr %q: %w", c.StatsAddr, err))
+		}
+	}
+
+	configured := make(map[string]bool, len(c.Backends))
+	for _, b := range c.Backends {
+		configured[b.Address] = true
+	}
+
+	for i, l := range c.Listeners {
+		if err := validateAddr(l.ListenAddr, true); err != nil {
+			errs = append(errs, fmt.Errorf("listeners[%d].listen_addr %q: %w", i, l.ListenAddr, err))
+		}
+		for _, addr := range l.Backends {
+			if !configured[addr] {
+				errs = append(errs, fmt.Errorf("listeners[%d].backends: %q is not a configured backend", i, addr))
+			}
+		}
+	}
+
+	if len(c.Backends) == 0 {
+		errs = append(errs, errors.New("at least one backend is required"))
+	}
+
+	for i, b := range c.Backends {
+		if err := validateAddr(b.Address, false); err != nil {
+			errs = append(errs, fmt.Errorf("backends[%d].address %q: %w", i, b.Address, err))
+		}
+		if b.HealthAddress != "" {
+			if err := validateAddr(b.HealthAddress, false); err != nil {
+				errs = append(errs, fmt.Errorf("backends[%d].health_address %q: %w", i, b.HealthAddress, err))
+			}
+		}
+		if b.Weight < 0 {
+			errs = append(errs, fmt.Errorf("backends[%d].weight must not be negative, got %d", i, b.Weight))
+		}
+		for name := range b.Labels {
+			if err := validateLabelName(name); err != nil {
+				errs = append(errs, fmt.Errorf("backends[%d].labels %q: %w", i, name, err))
+			}
+		}
+		if b.MaxConnections < 0 {
+			errs = append(errs, fmt.Errorf("backends[%d].max_connections must not be negative, got %d", i, b.MaxConnections))
+		}
+	}
+
+	if c.OutlierErrorRatio < 0 || c.OutlierErrorRatio >= 1 {
+		errs = append(errs, fmt.Errorf("outlier_error_ratio must be at least 0 and below 1, got %g", c.OutlierErrorRatio))
+	}
+
+	if c.LoadWeightConnections < 0 || c.LoadWeightLatencyMs < 0 || c.LoadWeightFailures < 0 {
+		errs = append(errs, errors.New("load_weight_connections, load_weight_latency_ms and load_weight_failures must not be negative"))
+	}
+
+	if c.StickyMaxLineBytes < 0 {
+		errs = append(errs, fmt.Errorf("sticky_max_line_bytes must not be negative, got %d", c.StickyMaxLineBytes))
+	}
+
+	if c.MaxIdleConnsPerBackend < 0 {
+		errs = append(errs, fmt.Errorf("max_idle_conns_per_backend must not be negative, got %d", c.MaxIdleConnsPerBackend))
+	}
+	if c.ConnectionReuse && c.SendProxyProtocol {
+		errs = append(errs, errors.New("connection_reuse cannot be combined with send_proxy_protocol"))
+	}
+	if c.ConnectionReuse && c.CloseOnEOF {
+		errs = append(errs, errors.New("connection_reuse cannot be combined with close_on_eof"))
+	}
+	if c.Protocol == ProtocolHTTP && (c.ConnectionReuse || c.SendProxyProtocol || c.StickyByFirstLine) {
+		errs = append(errs, fmt.Errorf("protocol %q cannot be combined with connection_reuse, send_proxy_protocol or sticky_by_first_line", ProtocolHTTP))
+	}
+
+	if c.ListenBacklog < 0 {
+		errs = append(errs, fmt.Errorf("listen_backlog must not be negative, got %d", c.ListenBacklog))
+	}
+
+	if c.SparklineSamples < 0 {
+		errs = append(errs, fmt.Errorf("sparkline_samples must not be negative, got %d", c.SparklineSamples))
+	}
+
+	if c.MinShareEvery < 0 {
+		errs = append(errs, fmt.Errorf("min_share_every must not be negative, got %d", c.MinShareEvery))
+	}
+
+	if c.PerConnectionBPS < 0 {
+		errs = append(errs, fmt.Errorf("per_connection_bps must not be negative, got %d", c.PerConnectionBPS))
+	}
+
+	if c.BufferSize < 0 {
+		errs = append(errs, fmt.Errorf("buffer_size must not be negative, got %d", c.BufferSize))
+	}
+
+	if c.ConnectTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("connect_timeout_seconds must be positive, got %s", c.ConnectTimeout))
+	}
+
+	// Zero disables each of these, so only negative values are mistakes
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"health_check_interval_seconds", c.HealthCheckInterval},
+		{"idle_timeout_seconds", c.IdleTimeout},
+		{"max_connection_duration_seconds", c.MaxConnectionDuration},
+		{"scale_sustain_seconds", c.ScaleSustain},
+		{"zero_weight_drain_seconds", c.ZeroWeightDrainTimeout},
+		{"breaker_cooldown_seconds", c.BreakerCooldown},
+		{"udp_session_timeout_seconds", c.UDPSessionTimeout},
+		{"retry_backoff_ms", c.RetryBackoff},
+		{"slow_start_seconds", c.SlowStart},
+		{"health_check_max_backoff_seconds", c.HealthCheckMaxBackoff},
+		{"outlier_window_seconds", c.OutlierWindow},
+		{"outlier_ejection_seconds", c.OutlierEjection},
+		{"health_check_timeout_seconds", c.HealthCheckTimeout},
+		{"tcp_keepalive_seconds", c.TCPKeepAlive},
+		{"sim_initial_delay_seconds", c.SimInitialDelay},
+		{"sim_pause_min_seconds", c.SimPauseMin},
+		{"sim_pause_max_seconds", c.SimPauseMax},
+		{"sim_gap_seconds", c.SimGap},
+		{"read_timeout_seconds", c.ReadTimeout},
+		{"write_timeout_seconds", c.WriteTimeout},
+		{"stats_shutdown_timeout_seconds", c.StatsShutdownTimeout},
+		{"stats_stream_interval_seconds", c.StatsStreamInterval},
+		{"tui_refresh_interval_ms", c.TUIRefreshInterval},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %s", d.name, d.value))
+		}
+	}
+
+	if c.SimPauseMin > 0 && c.SimPauseMax > 0 && c.SimPauseMax < c.SimPauseMin {
+		errs = append(errs, fmt.Errorf("sim_pause_max_seconds %s must not be below sim_pause_min_seconds %s", c.SimPauseMax, c.SimPauseMin))
+	}
+
+	switch c.HealthCheckType {
+	case "", HealthCheckTCP, HealthCheckHTTP:
+	case HealthCheckExpect:
+		if c.HealthCheckExpect == "" {
+			errs = append(errs, fmt.Errorf("health_check_expect is required when health_check_type is %q", HealthCheckExpect))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("health_check_type must be %q, %q or %q, got %q",
+			HealthCheckTCP, HealthCheckHTTP, HealthCheckExpect, c.HealthCheckType))
+	}
+
+	switch c.Protocol {
+	case "", ProtocolTCP, ProtocolUDP, ProtocolHTTP:
+	default:
+		errs = append(errs, fmt.Errorf("protocol must be %q, %q or %q, got %q", ProtocolTCP, ProtocolUDP, ProtocolHTTP, c.Protocol))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateLabelName checks that a backend label name can be used as a Prometheus
+// label and does not clash with the labels /metrics already sets.
+func validateLabelName(name string) error {
+	if name == "" {
+		return errors.New("label name must not be empty")
+	}
+	for i, r := range name {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || r < '0' || r > '9') {
+			return errors.New("label name must contain only letters, digits and underscores and not start with a digit")
+		}
+	}
+	if strings.HasPrefix(name, "__") {
+		return errors.New("label names starting with __ are reserved")
+	}
+	if name == "address" || name == "quantile" {
+		return errors.New("label name is reserved")
+	}
+
+	return nil
+}
+
+// validateAddr checks that addr is a host:port pair with a valid port. Listen
+// addresses may omit the host to bind all interfaces.
+func validateAddr(addr string, listen bool) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if host == "" && !listen {
+		return errors.New("missing host")
+	}
+
+	portNum, err := strconv.Atoi(port)
+	if err != nil || portNum < 0 || portNum > 65535 || (portNum == 0 && !listen) {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	return nil
+}
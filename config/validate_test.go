@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns a configuration that passes validation.
+func validConfig() *Config {
+	return &Config{
+		ListenAddr:          ":8080",
+		Backends:            []BackendConfig{{Address: "localhost:9001", Weight: 1}},
+		HealthCheckInterval: 10 * time.Second,
+		ConnectTimeout:      2 * time.Second,
+	}
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestValidateFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{"missing listen address", func(c *Config) { c.ListenAddr = "" }, "listen_addr is required"},
+		{"malformed listen address", func(c *Config) { c.ListenAddr = "8080" }, "listen_addr"},
+		{"listen port out of range", func(c *Config) { c.ListenAddr = ":70000" }, "invalid port"},
+		{"no backends", func(c *Config) { c.Backends = nil }, "at least one backend"},
+		{"malformed backend address", func(c *Config) { c.Backends[0].Address = "localhost" }, "backends[0].address"},
+		{"backend without host", func(c *Config) { c.Backends[0].Address = ":9001" }, "missing host"},
+		{"backend port zero", func(c *Config) { c.Backends[0].Address = "localhost:0" }, "invalid port"},
+		{"negative weight", func(c *Config) { c.Backends[0].Weight = -1 }, "weight must not be negative"},
+		{"negative health interval", func(c *Config) { c.HealthCheckInterval = -time.Second }, "health_check_interval_seconds"},
+		{"zero connect timeout", func(c *Config) { c.ConnectTimeout = 0 }, "connect_timeout_seconds must be positive"},
+		{"negative idle timeout", func(c *Config) { c.IdleTimeout = -time.Second }, "idle_timeout_seconds"},
+		{"unknown health check type", func(c *Config) { c.HealthCheckType = "icmp" }, "health_check_type"},
+		{"unknown protocol", func(c *Config) { c.Protocol = "sctp" }, "protocol must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(c)
+
+			err := c.Validate()
+			if err == nil {
+				t.Fatal("Validate() = nil, want an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateListsEveryProblem(t *testing.T) {
+	c := validConfig()
+	c.ListenAddr = "nonsense"
+	c.Backends[0].Weight = -1
+	c.ConnectTimeout = 0
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want an error")
+	}
+	for _, want := range []string{"listen_addr", "weight", "connect_timeout_seconds"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %q, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestLoadConfigValidates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"listen_addr": ":8080", "backends": [], "connect_timeout_seconds": 2}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "at least one backend") {
+		t.Errorf("LoadConfig() error = %v, want a validation error", err)
+	}
+}
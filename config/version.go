@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CurrentVersion is the config schema version this build writes and understands.
+// Bump it, and add an entry to migrations, whenever a field is renamed or its
+// meaning changes in a way that older files would otherwise be misread.
+const CurrentVersion = 1
+
+// ErrUnsupportedVersion is returned for config files from a newer schema version
+// than this build understands.
+var ErrUnsupportedVersion = errors.New("unsupported config version")
+
+// migrations upgrades a config from the version it is keyed by to the next one,
+// e.g. by copying a renamed field or filling the default of a new one.
+var migrations = map[int]func(*Config){}
+
+// migrate upgrades c to CurrentVersion. A missing version is taken to be the
+// current one; versions newer than CurrentVersion are rejected.
+func (c *Config) migrate() error {
+	return c.migrateTo(CurrentVersion, migrations)
+}
+
+// migrateTo upgrades c to version target by applying steps in order.
+func (c *Config) migrateTo(target int, steps map[int]func(*Config)) error {
+	if c.Version == 0 {
+		c.Version = target
+	}
+	if c.Version < 0 || c.Version > target {
+		return fmt.Errorf("%w %d, this build supports up to %d", ErrUnsupportedVersion, c.Version, target)
+	}
+
+	for c.Version < target {
+		if upgrade, ok := steps[c.Version]; ok {
+			upgrade(c)
+		}
+		c.Version++
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMigrateOldVersion(t *testing.T) {
+	// Stand-in steps for a schema two versions ahead of the file
+	var applied []int
+	steps := map[int]func(*Config){
+		1: func(c *Config) {
+			applied = append(applied, 1)
+			if c.BreakerCooldown == 0 {
+				c.BreakerCooldown = 30 * time.Second
+			}
+		},
+		2: func(c *Config) {
+			applied = append(applied, 2)
+			c.HealthCheckPath = "/healthz"
+		},
+	}
+
+	c := &Config{Version: 1}
+	if err := c.migrateTo(3, steps); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Version != 3 {
+		t.Errorf("Version = %d, want 3", c.Version)
+	}
+	if len(applied) != 2 || applied[0] != 1 || applied[1] != 2 {
+		t.Errorf("applied steps %v, want [1 2]", applied)
+	}
+	if c.BreakerCooldown != 30*time.Second || c.HealthCheckPath != "/healthz" {
+		t.Errorf("migrated config = %+v, want defaults filled", c)
+	}
+}
+
+func TestMigrateVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		want    int
+		wantErr bool
+	}{
+		{"missing means current", 0, CurrentVersion, false},
+		{"current", CurrentVersion, CurrentVersion, false},
+		{"too new", CurrentVersion + 1, 0, true},
+		{"negative", -1, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Version: tt.version}
+			err := c.migrate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupportedVersion) {
+					t.Errorf("migrate = %v, want ErrUnsupportedVersion", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Version != tt.want {
+				t.Errorf("Version = %d, want %d", c.Version, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsFutureVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+		"version": 99,
+		"listen_addr": ":7000",
+		"backends": [{"address": "file-backend:9000", "weight": 1}]
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadConfig(path); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("LoadConfig = %v, want ErrUnsupportedVersion", err)
+	}
+}
+
+func TestLoadConfigWithoutVersionIsCurrent(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("Version = %d, want %d", cfg.Version, CurrentVersion)
+	}
+}
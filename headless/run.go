@@ -0,0 +1,103 @@
+// Package headless runs the load balancer without the TUI, for deployments
+// where statistics are read from the stats server instead.
+package headless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"tcp_lb/config"
+	"tcp_lb/loadbalancer"
+	"tcp_lb/stats"
+)
+
+// drainTimeout is how long active connections may take to finish on SIGTERM.
+const drainTimeout = 30 * time.Second
+
+// shutdownTimeout is how long active connections may take to finish on interrupt.
+const shutdownTimeout = 5 * time.Second
+
+// Run loads configuration from configPath, or from the environment alone when
+// configPath is empty, and runs the load balancer, along with the stats server
+// when stats_addr is set, until SIGINT or SIGTERM is received. Unlike the TUI it
+// does not start demo backend servers or simulate failures.
+func Run(configPath string) error {
+	var cfg *config.Config
+	var err error
+	if configPath == "" {
+		cfg, err = config.LoadConfigFromEnv()
+	} else {
+		cfg, err = config.LoadConfig(configPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	lb := loadbalancer.New(cfg)
+	globalStats := stats.NewGlobalStats()
+	lb.SetGlobalStats(globalStats)
+
+	var statsServer *stats.Server
+	if cfg.StatsAddr != "" {
+		statsServer = stats.NewServer(lb.GetPool(), cfg.StatsAddr)
+		statsServer.SetLoadBalancer(lb)
+		statsServer.SetShutdownTimeout(cfg.StatsShutdownTimeout)
+		statsServer.SetStreamInterval(cfg.StatsStreamInterval)
+		statsServer.SetGlobalStats(globalStats)
+		samplerCtx, stopSampler := context.WithCancel(context.Background())
+		defer stopSampler()
+		go globalStats.RunThroughputSampler(samplerCtx)
+		go func() {
+			if err := statsServer.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Stats server error: %v", err)
+			}
+		}()
+		log.Printf("Stats server listening on %s", cfg.StatsAddr)
+	}
+
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- lb.Start()
+	}()
+	log.Printf("Load balancer started with %d backends", lb.GetPool().Size())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	var runErr error
+	select {
+	case sig := <-sigCh:
+		log.Printf("Received %v, shutting down", sig)
+		// Drain active connections on SIGTERM before shutting down
+		if sig == syscall.SIGTERM {
+			lb.Drain(drainTimeout)
+		}
+	case err := <-startErr:
+		if err != nil {
+			runErr = fmt.Errorf("load balancer: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := lb.Stop(ctx); err != nil {
+		log.Printf("Shutdown: %v", err)
+	}
+
+	if statsServer != nil {
+		if err := statsServer.Stop(); err != nil {
+			log.Printf("Stats server shutdown: %v", err)
+		}
+	}
+
+	return runErr
+}
@@ -0,0 +1,100 @@
+package headless
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"tcp_lb/stats"
+)
+
+// freeAddr returns a loopback address with a port that was free a moment ago.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	return addr
+}
+
+func TestRunServesStats(t *testing.T) {
+	backendLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backendLn.Close()
+
+	statsAddr := freeAddr(t)
+	cfg := fmt.Sprintf(`{
+		"listen_addr": %q,
+		"stats_addr": %q,
+		"connect_timeout_seconds": 1,
+		"health_check_interval_seconds": 10,
+		"backends": [{"address": %q, "weight": 1}]
+	}`, freeAddr(t), statsAddr, backendLn.Addr().String())
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Catch SIGINT here too, so the signal that stops Run cannot end the test binary
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT)
+	defer signal.Stop(sigCh)
+
+	done := make(chan error, 1)
+	go func() { done <- Run(path) }()
+
+	var got stats.StatsResponse
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get("http://" + statsAddr + "/stats")
+		if err == nil {
+			err = json.NewDecoder(resp.Body).Decode(&got)
+			resp.Body.Close()
+			if err == nil {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stats server not serving: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if got.TotalBackends != 1 {
+		t.Errorf("/stats reports %d backends, want 1", got.TotalBackends)
+	}
+
+	// Run may not have installed its signal handler yet, so keep signalling
+	// until it returns
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(10 * time.Second)
+	for {
+		syscall.Kill(os.Getpid(), syscall.SIGINT)
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Run = %v", err)
+			}
+			return
+		case <-ticker.C:
+		case <-timeout:
+			t.Fatal("Run did not return after SIGINT")
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package loadbalancer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ConnectionResult describes a proxied connection once it has finished.
+type ConnectionResult struct {
+	ID            string        // Connection ID shared by all of its log lines
+	ClientAddr    string        // Remote address of the client
+	ListenAddr    string        // Listener the client connected to
+	BackendAddr   string        // Backend address as configured, possibly a hostname
+	ResolvedAddr  string        // IP:port the backend connection actually went to
+	BytesSent     int64         // Bytes sent from the client to the backend
+	BytesReceived int64         // Bytes sent from the backend to the client
+	Duration      time.Duration // How long the connection was handled, from accept to close
+	Err           error         // Error that ended the connection, nil on a clean close
+}
+
+// ConnectionCallback is called with the result of every proxied connection.
+type ConnectionCallback func(result ConnectionResult)
+
+// ConnectionEventType is a step in the lifecycle of a client connection.
+type ConnectionEventType string
+
+const (
+	ConnectionAccepted      ConnectionEventType = "accepted"         // Client connection accepted
+	ConnectionBackendChosen ConnectionEventType = "backend_selected" // Backend connection established
+	ConnectionClosed        ConnectionEventType = "closed"           // Connection finished or was given up on
+)
+
+// ConnectionEvent is a logged lifecycle step of a client connection.
+type ConnectionEvent struct {
+	ID      string              // Connection ID
+	Type    ConnectionEventType // Lifecycle step
+	Message string              // The logged message, without the ID prefix
+	Time    time.Time
+}
+
+// ConnectionEventHook is called for every lifecycle event of every connection.
+type ConnectionEventHook func(event ConnectionEvent)
+
+// ConnectionObserver receives the lifecycle of every client connection, for
+// plugging in external metrics. Methods are called from connection goroutines
+// and must be safe for concurrent use.
+type ConnectionObserver interface {
+	// OnAccept is called when a client connection is accepted.
+	OnAccept(clientAddr string)
+	// OnBackendSelected is called once a backend connection is established.
+	OnBackendSelected(backend string)
+	// OnClose is called when the connection ends; backend is empty if none was reached.
+	OnClose(backend string, bytesSent, bytesReceived int64, dur time.Duration)
+}
+
+// nopObserver is the ConnectionObserver used when none is set.
+type nopObserver struct{}
+
+func (nopObserver) OnAccept(string)                             {}
+func (nopObserver) OnBackendSelected(string)                    {}
+func (nopObserver) OnClose(string, int64, int64, time.Duration) {}
+
+// SetObserver sets the observer notified of connection lifecycle steps, nil for
+// none. It must be called before Start.
+func (lb *LoadBalancer) SetObserver(observer ConnectionObserver) {
+	if observer == nil {
+		observer = nopObserver{}
+	}
+	lb.observer = observer
+}
+
+// SetConnectionCallback sets the function called when a proxied connection
+// finishes. It must be called before Start.
+func (lb *LoadBalancer) SetConnectionCallback(callback ConnectionCallback) {
+	lb.connCallback = callback
+}
+
+// SetConnectionEventHook sets the function called for each connection lifecycle
+// event. It must be called before Start.
+func (lb *LoadBalancer) SetConnectionEventHook(hook ConnectionEventHook) {
+	lb.connEventHook = hook
+}
+
+// connLog writes log lines for a single connection, prefixed with its ID.
+type connLog struct {
+	id   string
+	hook ConnectionEventHook
+}
+
+// newConnLog creates a connLog with a fresh random ID.
+func (lb *LoadBalancer) newConnLog() *connLog {
+	return &connLog{id: newConnectionID(), hook: lb.connEventHook}
+}
+
+// newConnectionID returns a short random hex ID.
+func newConnectionID() string {
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// logf logs a message for the connection.
+func (cl *connLog) logf(format string, args ...any) {
+	log.Printf("[conn %s] "+format, append([]any{cl.id}, args...)...)
+}
+
+// event logs a lifecycle message for the connection and reports it to the hook.
+func (cl *connLog) event(eventType ConnectionEventType, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	cl.logf("%s", message)
+
+	if cl.hook != nil {
+		cl.hook(ConnectionEvent{ID: cl.id, Type: eventType, Message: message, Time: time.Now()})
+	}
+}
+
+// logConnection writes the closing summary for a finished connection and
+// reports it to the connection callback.
+func (lb *LoadBalancer) logConnection(cl *connLog, result ConnectionResult) {
+	cl.event(ConnectionClosed, "Closed: client=%s listener=%s backend=%s resolved=%s sent=%d received=%d duration=%s err=%v",
+		result.ClientAddr, result.ListenAddr, result.BackendAddr, result.ResolvedAddr,
+		result.BytesSent, result.BytesReceived, result.Duration.Round(time.Millisecond), result.Err)
+
+	lb.observer.OnClose(result.BackendAddr, result.BytesSent, result.BytesReceived, result.Duration)
+
+	if lb.connCallback != nil {
+		lb.connCallback(result)
+	}
+}
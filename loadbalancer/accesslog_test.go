@@ -0,0 +1,175 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestResolvedAddrRecordedSeparately(t *testing.T) {
+	addr := startEchoBackend(t)
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configured := net.JoinHostPort("localhost", port)
+
+	lb := New(&config.Config{
+		ListenAddr:     "127.0.0.1:0",
+		ConnectTimeout: time.Second,
+		Backends:       []config.BackendConfig{{Address: configured, Weight: 1}},
+	})
+	results := make(chan ConnectionResult, 1)
+	lb.SetConnectionCallback(func(result ConnectionResult) { results <- result })
+	addrs := serveListeners(t, lb)
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "ping")
+	conn.Close()
+
+	select {
+	case result := <-results:
+		if result.BackendAddr != configured {
+			t.Errorf("BackendAddr = %q, want the configured %q", result.BackendAddr, configured)
+		}
+		if result.ResolvedAddr != addr {
+			t.Errorf("ResolvedAddr = %q, want the resolved %q", result.ResolvedAddr, addr)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection callback not called")
+	}
+}
+
+func TestConnectionLifecycleEvents(t *testing.T) {
+	addr := startEchoBackend(t)
+	lb := New(&config.Config{
+		ListenAddr:     "127.0.0.1:0",
+		ConnectTimeout: time.Second,
+		Backends:       []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+
+	var mu sync.Mutex
+	var events []ConnectionEvent
+	closed := make(chan struct{})
+	lb.SetConnectionEventHook(func(event ConnectionEvent) {
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+		if event.Type == ConnectionClosed {
+			close(closed)
+		}
+	})
+	addrs := serveListeners(t, lb)
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "ping")
+	conn.Close()
+
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("close event not emitted")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []ConnectionEventType{ConnectionAccepted, ConnectionBackendChosen, ConnectionClosed}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, event := range events {
+		if event.Type != want[i] {
+			t.Errorf("event %d type = %q, want %q", i, event.Type, want[i])
+		}
+		if event.ID == "" || event.ID != events[0].ID {
+			t.Errorf("event %d ID = %q, want the connection's ID %q", i, event.ID, events[0].ID)
+		}
+	}
+	if !strings.Contains(events[1].Message, addr) {
+		t.Errorf("backend event %q does not name the backend", events[1].Message)
+	}
+	if !strings.Contains(events[2].Message, "sent=4 received=4") {
+		t.Errorf("close event %q does not report the bytes proxied", events[2].Message)
+	}
+}
+
+// recordingObserver records the sequence of observer calls.
+type recordingObserver struct {
+	mu     sync.Mutex
+	calls  []string
+	closed chan struct{}
+}
+
+// OnAccept records the accept.
+func (o *recordingObserver) OnAccept(clientAddr string) {
+	o.record("accept")
+}
+
+// OnBackendSelected records the selected backend.
+func (o *recordingObserver) OnBackendSelected(backend string) {
+	o.record("selected " + backend)
+}
+
+// OnClose records the backend and byte counts, then signals the close.
+func (o *recordingObserver) OnClose(backend string, bytesSent, bytesReceived int64, dur time.Duration) {
+	o.record(fmt.Sprintf("close %s %d %d", backend, bytesSent, bytesReceived))
+	close(o.closed)
+}
+
+// record appends a call.
+func (o *recordingObserver) record(call string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.calls = append(o.calls, call)
+}
+
+func TestObserverCallbackSequence(t *testing.T) {
+	addr := startEchoBackend(t)
+	lb := New(&config.Config{
+		ListenAddr:     "127.0.0.1:0",
+		ConnectTimeout: time.Second,
+		Backends:       []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+	observer := &recordingObserver{closed: make(chan struct{})}
+	lb.SetObserver(observer)
+	addrs := serveListeners(t, lb)
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "hello")
+	conn.Close()
+
+	select {
+	case <-observer.closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnClose not called")
+	}
+
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
+	want := []string{"accept", "selected " + addr, "close " + addr + " 5 5"}
+	if !reflect.DeepEqual(observer.calls, want) {
+		t.Errorf("calls = %q, want %q", observer.calls, want)
+	}
+}
+
+func TestNilObserverIsNoOp(t *testing.T) {
+	lb := New(&config.Config{
+		ListenAddr:     "127.0.0.1:0",
+		ConnectTimeout: time.Second,
+		Backends:       []config.BackendConfig{{Address: startEchoBackend(t), Weight: 1}},
+	})
+	lb.SetObserver(nil)
+	addrs := serveListeners(t, lb)
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "hello")
+}
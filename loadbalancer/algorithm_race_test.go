@@ -0,0 +1,71 @@
+package loadbalancer
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+// TestSetAlgorithmWhileRouting switches algorithms while connections are routed.
+// Run with -race to catch unsynchronised access to the algorithm.
+func TestSetAlgorithmWhileRouting(t *testing.T) {
+	backends := []config.BackendConfig{
+		{Address: startEchoBackend(t), Weight: 1},
+		{Address: startEchoBackend(t), Weight: 2},
+	}
+	lb, addrs := startLoadBalancer(t, &config.Config{Backends: backends})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		names := []string{"round_robin", "least_connections", "weighted_round_robin", "ip_hash"}
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			algo, err := NewAlgorithm(names[i%len(names)])
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			lb.SetAlgorithm(algo)
+		}
+	}()
+
+	deadline := time.Now().Add(300 * time.Millisecond)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, 4)
+			for time.Now().Before(deadline) {
+				conn, err := net.DialTimeout("tcp", addrs[0], time.Second)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				conn.SetDeadline(time.Now().Add(2 * time.Second))
+				conn.Write([]byte("ping"))
+				_, err = io.ReadFull(conn, buf)
+				conn.Close()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+}
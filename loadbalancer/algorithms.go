@@ -1,12 +1,56 @@
 package loadbalancer
 
 import (
+	"fmt"
+	"math/rand"
 	"sync"
 	"tcp_lb/backend"
+	"time"
 )
 
 type Algorithm interface {
 	NextBackend(pool *backend.Pool) *backend.Backend
+	Name() string
+}
+
+// ClientAlgorithm is implemented by algorithms that choose a backend from the
+// client's IP address. NextBackend is still used when the client is unknown.
+type ClientAlgorithm interface {
+	Algorithm
+	NextBackendForClient(pool *backend.Pool, clientIP string) *backend.Backend
+}
+
+// nextBackendFor asks algo for a backend for the client at clientIP, which only
+// algorithms implementing ClientAlgorithm take into account.
+func nextBackendFor(algo Algorithm, pool *backend.Pool, clientIP string) *backend.Backend {
+	if ca, ok := algo.(ClientAlgorithm); ok && clientIP != "" {
+		return ca.NextBackendForClient(pool, clientIP)
+	}
+	return algo.NextBackend(pool)
+}
+
+// NewAlgorithm creates an algorithm from its configuration name.
+func NewAlgorithm(name string) (Algorithm, error) {
+	switch name {
+	case "round_robin":
+		return NewRoundRobin(), nil
+	case "least_connections":
+		return NewLeastConnections(), nil
+	case "weighted_round_robin":
+		return NewWeightedRoundRobin(), nil
+	case "weighted_least_connections":
+		return NewWeightedLeastConnections(), nil
+	case "lowest_cost":
+		return NewLowestCost(), nil
+	case "weighted_random":
+		return NewWeightedRandom(), nil
+	case "least_loaded":
+		return NewLeastLoaded(DefaultLoadWeights), nil
+	case "ip_hash":
+		return NewIPHash(), nil
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", name)
+	}
 }
 
 // =============================================================================
@@ -24,9 +68,14 @@ func NewRoundRobin() *RoundRobin {
 	}
 }
 
+// Name returns the configuration name of the algorithm.
+func (rr *RoundRobin) Name() string {
+	return "round_robin"
+}
+
 // NextBackend returns the next healthy backend in round-robin order.
 func (rr *RoundRobin) NextBackend(pool *backend.Pool) *backend.Backend {
-	healthyBackends := pool.GetHealthyBackends()
+	healthyBackends := pool.GetSelectableBackends()
 	if len(healthyBackends) == 0 {
 		return nil
 	}
@@ -54,12 +103,17 @@ func NewLeastConnections() *LeastConnections {
 	return &LeastConnections{}
 }
 
+// Name returns the configuration name of the algorithm.
+func (lc *LeastConnections) Name() string {
+	return "least_connections"
+}
+
 // NextBackend returns the backend with fewest active connections.
 func (lc *LeastConnections) NextBackend(pool *backend.Pool) *backend.Backend {
 	lc.mu.Lock()
 	defer lc.mu.Unlock()
 
-	healthyBackends := pool.GetHealthyBackends()
+	healthyBackends := pool.GetSelectableBackends()
 	if len(healthyBackends) == 0 {
 		return nil
 	}
@@ -80,24 +134,34 @@ func (lc *LeastConnections) NextBackend(pool *backend.Pool) *backend.Backend {
 // WEIGHTED ROUND ROBIN ALGORITHM 
 // =============================================================================
 
-// WeightedRoundRobin distributes requests based on backend weights.
+// WeightedRoundRobin distributes requests based on backend weights using smooth
+// weighted round robin: each pick adds every backend's weight to its running score,
+// chooses the highest score, and subtracts the total weight from the winner. This
+// interleaves selections, so weights 5/1/1 yield a a b a c a a rather than five a's
+// in a row. Weights are read on every pick, so runtime changes made with
+// Backend.SetWeight apply from the next selection.
 type WeightedRoundRobin struct {
-	current       int        // Current position in the weighted sequence
-	currentWeight int        // Current weight counter
-	mu            sync.Mutex // Protects the state
+	currentWeights map[string]float64 // Running score per backend address
+	mu             sync.Mutex         // Protects the state
 }
 
 // NewWeightedRoundRobin creates a new WeightedRoundRobin algorithm instance.
 func NewWeightedRoundRobin() *WeightedRoundRobin {
 	return &WeightedRoundRobin{
-		current:       0,
-		currentWeight: 0,
+		currentWeights: make(map[string]float64),
 	}
 }
 
-// NextBackend returns the next backend in weighted round-robin order.
+// Name returns the configuration name of the algorithm.
+func (wrr *WeightedRoundRobin) Name() string {
+	return "weighted_round_robin"
+}
+
+// NextBackend returns the next backend in smooth weighted round-robin order.
+// Backends in slow start have their weight scaled down, and backups (weight 0)
+// count as weight 1 when they are all that is left.
 func (wrr *WeightedRoundRobin) NextBackend(pool *backend.Pool) *backend.Backend {
-	healthyBackends := pool.GetHealthyBackends()
+	healthyBackends := pool.GetSelectableBackends()
 	if len(healthyBackends) == 0 {
 		return nil
 	}
@@ -105,13 +169,352 @@ func (wrr *WeightedRoundRobin) NextBackend(pool *backend.Pool) *backend.Backend
 	wrr.mu.Lock()
 	defer wrr.mu.Unlock()
 
-	backend := healthyBackends[wrr.current%int(len(healthyBackends))]
-	wrr.currentWeight++
+	// Scores of backends that are no longer selectable are dropped so they
+	// rejoin on equal terms
+	scores := make(map[string]float64, len(healthyBackends))
 
-	if wrr.currentWeight >= backend.GetWeight() {
-		wrr.currentWeight = 0
-		wrr.current++
+	var best *backend.Backend
+	var total float64
+	for _, b := range healthyBackends {
+		weight := b.GetWeight()
+		if weight <= 0 {
+			weight = 1
+		}
+		effective := float64(weight) * b.SlowStartFactor()
+		total += effective
+
+		score := wrr.currentWeights[b.Address] + effective
+		scores[b.Address] = score
+		if best == nil || score > scores[best.Address] {
+			best = b
+		}
 	}
 
-	return backend
+	scores[best.Address] -= total
+	wrr.currentWeights = scores
+
+	return best
+}
+
+// =============================================================================
+// WEIGHTED LEAST CONNECTIONS ALGORITHM
+// =============================================================================
+
+// WeightedLeastConnections routes traffic to the backend with the fewest active
+// connections relative to its weight.
+type WeightedLeastConnections struct {
+	mu sync.Mutex
+}
+
+// NewWeightedLeastConnections creates a new WeightedLeastConnections algorithm instance.
+func NewWeightedLeastConnections() *WeightedLeastConnections {
+	return &WeightedLeastConnections{}
+}
+
+// Name returns the configuration name of the algorithm.
+func (wlc *WeightedLeastConnections) Name() string {
+	return "weighted_least_connections"
+}
+
+// NextBackend returns the backend minimizing active connections divided by weight.
+// A weight of zero or less is treated as 1. Backends in slow start have their weight
+// scaled down and count the new connection, so an idle recovered backend is not flooded.
+func (wlc *WeightedLeastConnections) NextBackend(pool *backend.Pool) *backend.Backend {
+	wlc.mu.Lock()
+	defer wlc.mu.Unlock()
+
+	var best *backend.Backend
+	var bestLoad float64
+	for _, b := range pool.GetSelectableBackends() {
+		conns := b.GetActiveConnections()
+		weight := b.GetWeight()
+		if weight <= 0 {
+			weight = 1
+		}
+
+		load := float64(conns) / float64(weight)
+		if factor := b.SlowStartFactor(); factor < 1 {
+			load = float64(conns+1) / (float64(weight) * factor)
+		}
+
+		if best == nil || load < bestLoad {
+			best = b
+			bestLoad = load
+		}
+	}
+
+	return best
+}
+
+// =============================================================================
+// LOWEST COST ALGORITHM
+// =============================================================================
+
+// LowestCost routes traffic to the lowest-cost backend, overflowing to
+// higher-cost backends only when cheaper ones are at their connection cap.
+type LowestCost struct {
+	mu sync.Mutex
+}
+
+// NewLowestCost creates a new LowestCost algorithm instance.
+func NewLowestCost() *LowestCost {
+	return &LowestCost{}
+}
+
+// Name returns the configuration name of the algorithm.
+func (lc *LowestCost) Name() string {
+	return "lowest_cost"
+}
+
+// NextBackend returns the lowest-cost backend with spare capacity, preferring
+// fewer active connections among backends of equal cost.
+func (lc *LowestCost) NextBackend(pool *backend.Pool) *backend.Backend {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	var best *backend.Backend
+	for _, b := range pool.GetSelectableBackends() {
+		if b.AtCapacity() {
+			continue
+		}
+
+		if best == nil ||
+			b.GetCost() < best.GetCost() ||
+			(b.GetCost() == best.GetCost() && b.GetActiveConnections() < best.GetActiveConnections()) {
+			best = b
+		}
+	}
+
+	return best
+}
+
+// =============================================================================
+// WEIGHTED RANDOM ALGORITHM
+// =============================================================================
+
+// WeightedRandom picks a healthy backend at random with probability proportional
+// to its weight. It keeps no state between selections.
+type WeightedRandom struct{}
+
+// NewWeightedRandom creates a new WeightedRandom algorithm instance.
+func NewWeightedRandom() *WeightedRandom {
+	return &WeightedRandom{}
+}
+
+// Name returns the configuration name of the algorithm.
+func (wr *WeightedRandom) Name() string {
+	return "weighted_random"
+}
+
+// NextBackend draws a backend over the cumulative weight of the healthy backends.
+// Backends in slow start have their weight scaled down. If every weight is zero,
+// a backend is picked uniformly.
+func (wr *WeightedRandom) NextBackend(pool *backend.Pool) *backend.Backend {
+	healthyBackends := pool.GetSelectableBackends()
+	if len(healthyBackends) == 0 {
+		return nil
+	}
+
+	weights := make([]float64, len(healthyBackends))
+	var total float64
+	for i, b := range healthyBackends {
+		if weight := b.GetWeight(); weight > 0 {
+			weights[i] = float64(weight) * b.SlowStartFactor()
+			total += weights[i]
+		}
+	}
+
+	if total == 0 {
+		return healthyBackends[rand.Intn(len(healthyBackends))]
+	}
+
+	draw := rand.Float64() * total
+	for i, weight := range weights {
+		draw -= weight
+		if draw < 0 {
+			return healthyBackends[i]
+		}
+	}
+
+	// Floating point rounding can leave the draw just past the last backend
+	return healthyBackends[len(healthyBackends)-1]
+}
+
+// =============================================================================
+// LEAST LOADED ALGORITHM
+// =============================================================================
+
+// LoadWeights sets how much each signal contributes to a backend's load score.
+type LoadWeights struct {
+	Connections float64 // Per active connection
+	LatencyMs   float64 // Per millisecond of the last health check's response time
+	Failures    float64 // Per consecutive dial failure
+}
+
+// DefaultLoadWeights counts 10ms of health check latency as much as one active
+// connection, and a recent dial failure as much as five.
+var DefaultLoadWeights = LoadWeights{Connections: 1, LatencyMs: 0.1, Failures: 5}
+
+// LeastLoaded routes each connection to the backend with the lowest load score,
+// a weighted sum of its active connections, response time and recent failures.
+type LeastLoaded struct {
+	weights LoadWeights
+	mu      sync.Mutex // Protects weights
+}
+
+// NewLeastLoaded creates a new LeastLoaded algorithm instance with the given weights.
+func NewLeastLoaded(weights LoadWeights) *LeastLoaded {
+	return &LeastLoaded{weights: weights}
+}
+
+// Name returns the configuration name of the algorithm.
+func (ll *LeastLoaded) Name() string {
+	return "least_loaded"
+}
+
+// SetWeights changes the weights used to score backends.
+func (ll *LeastLoaded) SetWeights(weights LoadWeights) {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+
+	ll.weights = weights
+}
+
+// Score returns the backend's load score under the algorithm's weights.
+func (ll *LeastLoaded) Score(b *backend.Backend) float64 {
+	ll.mu.Lock()
+	weights := ll.weights
+	ll.mu.Unlock()
+
+	latencyMs := float64(b.GetLastResponseTime()) / float64(time.Millisecond)
+
+	return weights.Connections*float64(b.GetActiveConnections()) +
+		weights.LatencyMs*latencyMs +
+		weights.Failures*float64(b.GetConsecutiveFailures())
+}
+
+// NextBackend returns the backend with the lowest load score, the first one in
+// pool order on ties.
+func (ll *LeastLoaded) NextBackend(pool *backend.Pool) *backend.Backend {
+	var best *backend.Backend
+	var bestScore float64
+	for _, b := range pool.GetSelectableBackends() {
+		if score := ll.Score(b); best == nil || score < bestScore {
+			best = b
+			bestScore = score
+		}
+	}
+
+	return best
+}
+
+// =============================================================================
+// IP HASH ALGORITHM
+// =============================================================================
+
+// IPHash sends every connection from a client IP to the same backend while it
+// stays selectable. Backends are ranked per IP by rendezvous hashing, so when one
+// goes down only its clients move and they spread over the remaining backends.
+type IPHash struct {
+	fallback *RoundRobin // Used when the client is unknown
+}
+
+// NewIPHash creates a new IPHash algorithm instance.
+func NewIPHash() *IPHash {
+	return &IPHash{fallback: NewRoundRobin()}
+}
+
+// Name returns the configuration name of the algorithm.
+func (ih *IPHash) Name() string {
+	return "ip_hash"
+}
+
+// NextBackend falls back to round robin, as there is no client to hash.
+func (ih *IPHash) NextBackend(pool *backend.Pool) *backend.Backend {
+	return ih.fallback.NextBackend(pool)
+}
+
+// NextBackendForClient returns the highest ranked selectable backend for clientIP.
+func (ih *IPHash) NextBackendForClient(pool *backend.Pool, clientIP string) *backend.Backend {
+	return backendForToken(pool, clientIP)
+}
+
+// =============================================================================
+// MINIMUM SHARE WRAPPER
+// =============================================================================
+
+// MinShare wraps another algorithm so that every selectable backend is picked at
+// least once in every `every` selections, however low its weight. When a backend
+// has gone every-1 selections without being picked it is chosen next, the one
+// waiting longest first; otherwise the wrapped algorithm decides.
+type MinShare struct {
+	algorithm Algorithm
+	every     int
+	waiting   map[string]int // Selections since each selectable backend was last picked
+	mu        sync.Mutex
+}
+
+// NewMinShare wraps algorithm with a minimum share of one in every `every` selections.
+func NewMinShare(algorithm Algorithm, every int) *MinShare {
+	return &MinShare{
+		algorithm: algorithm,
+		every:     every,
+		waiting:   make(map[string]int),
+	}
+}
+
+// Name returns the configuration name of the wrapped algorithm.
+func (ms *MinShare) Name() string {
+	return ms.algorithm.Name()
+}
+
+// NextBackend picks the backend that is owed its minimum share, if any, and
+// otherwise defers to the wrapped algorithm.
+func (ms *MinShare) NextBackend(pool *backend.Pool) *backend.Backend {
+	return ms.next(pool, "")
+}
+
+// NextBackendForClient is like NextBackend, passing the client on to the wrapped
+// algorithm.
+func (ms *MinShare) NextBackendForClient(pool *backend.Pool, clientIP string) *backend.Backend {
+	return ms.next(pool, clientIP)
+}
+
+// next picks the backend owed its minimum share, or the wrapped algorithm's
+// choice for clientIP.
+func (ms *MinShare) next(pool *backend.Pool, clientIP string) *backend.Backend {
+	healthyBackends := pool.GetSelectableBackends()
+	if len(healthyBackends) == 0 {
+		return nil
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var chosen *backend.Backend
+	longest := ms.every - 2
+	for _, b := range healthyBackends {
+		if waited := ms.waiting[b.Address]; waited > longest {
+			chosen, longest = b, waited
+		}
+	}
+
+	if chosen == nil {
+		chosen = nextBackendFor(ms.algorithm, pool, clientIP)
+		if chosen == nil {
+			return nil
+		}
+	}
+
+	// Only backends that could have been picked are owed a share; one coming back
+	// from being down starts over
+	waiting := make(map[string]int, len(healthyBackends))
+	for _, b := range healthyBackends {
+		if b != chosen {
+			waiting[b.Address] = ms.waiting[b.Address] + 1
+		}
+	}
+	ms.waiting = waiting
+
+	return chosen
 }
@@ -0,0 +1,345 @@
+package loadbalancer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"tcp_lb/backend"
+	"tcp_lb/config"
+)
+
+// newTestPool creates a pool of backends, returned in the order given.
+func newTestPool(backends ...*backend.Backend) *backend.Pool {
+	pool := backend.NewPool()
+	for _, b := range backends {
+		pool.AddBackend(b)
+	}
+	return pool
+}
+
+// connect records a new active connection on b and returns it.
+func connect(b *backend.Backend) net.Conn {
+	conn, _ := net.Pipe()
+	b.AddConnection(conn)
+	return conn
+}
+
+func TestLowestCostSpillsOverAtCapacity(t *testing.T) {
+	cheap := backend.NewBackendWithWeight("cheap:1", 1)
+	cheap.Cost = 1
+	cheap.MaxConnections = 2
+	expensive := backend.NewBackendWithWeight("expensive:1", 1)
+	expensive.Cost = 5
+	pool := newTestPool(expensive, cheap)
+
+	algo := NewLowestCost()
+
+	var conns []net.Conn
+	for i, want := range []*backend.Backend{cheap, cheap, expensive, expensive} {
+		got := algo.NextBackend(pool)
+		if got != want {
+			t.Fatalf("pick %d = %s, want %s", i+1, got.Address, want.Address)
+		}
+		conns = append(conns, connect(got))
+	}
+
+	// Once the cheap backend has room again it is preferred
+	cheap.RemoveConnection(conns[0])
+	if got := algo.NextBackend(pool); got != cheap {
+		t.Errorf("after freeing capacity got %s, want cheap", got.Address)
+	}
+}
+
+func TestWeightedLeastConnectionsFollowsWeights(t *testing.T) {
+	light := backend.NewBackendWithWeight("light:1", 1)
+	heavy := backend.NewBackendWithWeight("heavy:1", 3)
+	pool := newTestPool(light, heavy)
+
+	algo := NewWeightedLeastConnections()
+	for range 40 {
+		connect(algo.NextBackend(pool))
+	}
+
+	if l, h := light.GetActiveConnections(), heavy.GetActiveConnections(); l != 10 || h != 30 {
+		t.Errorf("connections = %d light, %d heavy, want 10 and 30", l, h)
+	}
+}
+
+func TestWeightedLeastConnectionsZeroWeight(t *testing.T) {
+	zero := backend.NewBackendWithWeight("zero:1", 0)
+	pool := newTestPool(zero)
+
+	if got := NewWeightedLeastConnections().NextBackend(pool); got != zero {
+		t.Errorf("NextBackend = %v, want the zero-weight backend", got)
+	}
+}
+
+func TestIPHashKeepsClientsOnOneBackend(t *testing.T) {
+	var backends []*backend.Backend
+	for _, addr := range []string{"a:1", "b:1", "c:1"} {
+		backends = append(backends, backend.NewBackendWithWeight(addr, 1))
+	}
+	pool := newTestPool(backends...)
+
+	algo := NewIPHash()
+	clients := []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4", "2001:db8::1", "2001:db8::2"}
+
+	chosen := make(map[string]*backend.Backend)
+	for _, ip := range clients {
+		chosen[ip] = nextBackendFor(algo, pool, ip)
+		for range 5 {
+			if got := nextBackendFor(algo, pool, ip); got != chosen[ip] {
+				t.Fatalf("%s moved from %s to %s", ip, chosen[ip].Address, got.Address)
+			}
+		}
+	}
+
+	// Only the clients of a backend that goes down move
+	down := chosen[clients[0]]
+	down.SetAlive(false)
+	for _, ip := range clients {
+		got := nextBackendFor(algo, pool, ip)
+		if got == down {
+			t.Errorf("%s still sent to the down backend", ip)
+		}
+		if chosen[ip] != down && got != chosen[ip] {
+			t.Errorf("%s moved from %s to %s though its backend is up", ip, chosen[ip].Address, got.Address)
+		}
+	}
+
+	// Without a client it falls back to round robin
+	if algo.NextBackend(pool) == nil {
+		t.Error("NextBackend returned nil with backends up")
+	}
+}
+
+func TestSlowStartReducesTrafficToRecoveredBackend(t *testing.T) {
+	steady := backend.NewBackendWithWeight("steady:1", 1)
+	recovered := backend.NewBackendWithWeight("recovered:1", 1)
+	recovered.SetSlowStart(time.Minute)
+	recovered.SetAlive(false)
+	recovered.SetAlive(true)
+	pool := newTestPool(steady, recovered)
+
+	for _, algo := range []Algorithm{NewWeightedRoundRobin(), NewWeightedRandom()} {
+		counts := make(map[*backend.Backend]int)
+		for range 1000 {
+			counts[algo.NextBackend(pool)]++
+		}
+
+		// Early in the window the recovered backend gets a small fraction of its
+		// equal share
+		if counts[recovered] == 0 || counts[recovered] > 100 {
+			t.Errorf("%s: recovered backend got %d of 1000 picks, want a few", algo.Name(), counts[recovered])
+		}
+	}
+}
+
+func TestSlowStartFactorRamps(t *testing.T) {
+	b := backend.NewBackendWithWeight("b:1", 1)
+	b.SetSlowStart(200 * time.Millisecond)
+
+	if f := b.SlowStartFactor(); f != 1 {
+		t.Errorf("factor before any recovery = %v, want 1", f)
+	}
+
+	b.SetAlive(false)
+	b.SetAlive(true)
+	if f := b.SlowStartFactor(); f >= 0.25 {
+		t.Errorf("factor just after recovery = %v, want near zero", f)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if f := b.SlowStartFactor(); f < 0.4 || f > 0.9 {
+		t.Errorf("factor halfway through the window = %v, want about 0.5", f)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if f := b.SlowStartFactor(); f != 1 {
+		t.Errorf("factor after the window = %v, want 1", f)
+	}
+}
+
+func TestWeightedRandomFollowsWeights(t *testing.T) {
+	weights := map[string]int{"a:1": 1, "b:1": 2, "c:1": 7}
+	var backends []*backend.Backend
+	for _, addr := range []string{"a:1", "b:1", "c:1"} {
+		backends = append(backends, backend.NewBackendWithWeight(addr, weights[addr]))
+	}
+	pool := newTestPool(backends...)
+
+	algo := NewWeightedRandom()
+
+	const draws = 20000
+	counts := make(map[string]int)
+	for range draws {
+		counts[algo.NextBackend(pool).Address]++
+	}
+
+	// Each share is within two percentage points of weight/10
+	for addr, weight := range weights {
+		share := float64(counts[addr]) / draws
+		want := float64(weight) / 10
+		if share < want-0.02 || share > want+0.02 {
+			t.Errorf("%s got %.3f of draws, want %.2f", addr, share, want)
+		}
+	}
+
+	if algo.NextBackend(newTestPool()) != nil {
+		t.Error("NextBackend on an empty pool should return nil")
+	}
+}
+
+func TestWeightedRoundRobinSpreadsSelections(t *testing.T) {
+	tests := []struct {
+		weights []int
+		maxRun  int // Longest allowed run of one backend within a cycle
+	}{
+		{[]int{5, 1, 1}, 2},
+		{[]int{3, 3}, 1},
+		{[]int{4, 2, 1}, 2},
+		{[]int{9, 1}, 5},
+	}
+
+	for _, tt := range tests {
+		var backends []*backend.Backend
+		total := 0
+		for i, w := range tt.weights {
+			backends = append(backends, backend.NewBackendWithWeight(string(rune('a'+i))+":1", w))
+			total += w
+		}
+		pool := newTestPool(backends...)
+		algo := NewWeightedRoundRobin()
+
+		counts := make(map[*backend.Backend]int)
+		var last *backend.Backend
+		run, longest := 0, 0
+		// One cycle selects each backend exactly its weight times
+		for range total {
+			b := algo.NextBackend(pool)
+			counts[b]++
+			if b == last {
+				run++
+			} else {
+				run = 1
+			}
+			last = b
+			longest = max(longest, run)
+		}
+
+		if longest > tt.maxRun {
+			t.Errorf("weights %v: run of %d selections, want at most %d", tt.weights, longest, tt.maxRun)
+		}
+		for i, b := range backends {
+			if counts[b] != tt.weights[i] {
+				t.Errorf("weights %v: %s selected %d times, want %d", tt.weights, b.Address, counts[b], tt.weights[i])
+			}
+		}
+	}
+}
+
+func TestLeastLoadedPrefersLowLatency(t *testing.T) {
+	slow := backend.NewBackendWithWeight(startEchoBackend(t), 1)
+	fast := backend.NewBackendWithWeight(startEchoBackend(t), 1)
+	slow.SetDialer(slowAcceptDialer{delay: 200 * time.Millisecond})
+	fast.SetDialer(slowAcceptDialer{delay: 10 * time.Millisecond})
+	for _, b := range []*backend.Backend{slow, fast} {
+		if !b.CheckHealth(time.Second) {
+			t.Fatalf("%s: health check failed", b.Address)
+		}
+	}
+
+	// Few connections but high latency, against moderate connections and low latency
+	connect(slow)
+	for range 5 {
+		connect(fast)
+	}
+
+	algo := NewLeastLoaded(DefaultLoadWeights)
+	if got := algo.NextBackend(newTestPool(slow, fast)); got != fast {
+		t.Errorf("picked the slow backend (score %.1f) over the fast one (score %.1f)",
+			algo.Score(slow), algo.Score(fast))
+	}
+
+	// Without the latency signal, connections alone decide
+	algo.SetWeights(LoadWeights{Connections: 1})
+	if got := algo.NextBackend(newTestPool(slow, fast)); got != slow {
+		t.Error("with only connections weighted, want the backend with fewer connections")
+	}
+}
+
+func TestWeightedRoundRobinFollowsWeightChanges(t *testing.T) {
+	a := backend.NewBackendWithWeight("a:1", 1)
+	b := backend.NewBackendWithWeight("b:1", 1)
+	pool := newTestPool(a, b)
+	algo := NewWeightedRoundRobin()
+
+	// countPicks selects n backends and returns how often a was picked.
+	countPicks := func(n int) int {
+		picks := 0
+		for range n {
+			if algo.NextBackend(pool) == a {
+				picks++
+			}
+		}
+		return picks
+	}
+
+	if got := countPicks(10); got != 5 {
+		t.Fatalf("equal weights: a picked %d of 10 times, want 5", got)
+	}
+
+	// The new weight applies from the next selection, without rebuilding the algorithm
+	a.SetWeight(4)
+	if got := countPicks(50); got != 40 {
+		t.Errorf("weights 4/1: a picked %d of 50 times, want 40", got)
+	}
+
+	a.SetWeight(1)
+	b.SetWeight(3)
+	if got := countPicks(40); got != 10 {
+		t.Errorf("weights 1/3: a picked %d of 40 times, want 10", got)
+	}
+}
+
+func TestMinShareGuaranteesLowWeightSelections(t *testing.T) {
+	heavy1 := backend.NewBackendWithWeight("heavy1:1", 100)
+	heavy2 := backend.NewBackendWithWeight("heavy2:1", 100)
+	light := backend.NewBackendWithWeight("light:1", 1)
+	pool := newTestPool(heavy1, heavy2, light)
+
+	const every = 10
+	algo := NewMinShare(NewWeightedRoundRobin(), every)
+
+	picks, gap, longestGap := 0, 0, 0
+	for range 200 {
+		if algo.NextBackend(pool) == light {
+			picks++
+			gap = 0
+			continue
+		}
+		gap++
+		longestGap = max(longestGap, gap)
+	}
+
+	// Left to weighted round robin alone it would get about 1 in 201
+	if picks < 200/every {
+		t.Errorf("weight-1 backend picked %d of 200 times, want at least %d", picks, 200/every)
+	}
+	if longestGap >= every {
+		t.Errorf("weight-1 backend went %d selections unpicked, want fewer than %d", longestGap, every)
+	}
+}
+
+func TestMinShareConfiguredWrapsAlgorithm(t *testing.T) {
+	lb := New(&config.Config{MinShareEvery: 5})
+	lb.SetAlgorithm(NewWeightedRoundRobin())
+
+	if _, ok := lb.currentAlgorithm().(*MinShare); !ok {
+		t.Errorf("algorithm is %T, want it wrapped in *MinShare", lb.currentAlgorithm())
+	}
+	if name := lb.currentAlgorithm().Name(); name != NewWeightedRoundRobin().Name() {
+		t.Errorf("Name = %q, want the wrapped algorithm's name", name)
+	}
+}
@@ -0,0 +1,159 @@
+package loadbalancer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// scalingSampleInterval is how often utilization is sampled for scaling recommendations.
+const scalingSampleInterval = time.Second
+
+// ScalingRecommendation is a signal for external autoscalers.
+type ScalingRecommendation string
+
+const (
+	ScaleNone ScalingRecommendation = "none"
+	ScaleUp   ScalingRecommendation = "scale_up"
+	ScaleDown ScalingRecommendation = "scale_down"
+)
+
+// ScalingCallback is called when the scaling recommendation changes.
+type ScalingCallback func(recommendation ScalingRecommendation, utilization float64)
+
+// ScalingAdvisor turns utilization samples into scaling recommendations. A
+// recommendation is only made once utilization has stayed beyond a watermark
+// for the sustain duration, which avoids flapping on short spikes.
+type ScalingAdvisor struct {
+	highWatermark float64       // Utilization at or above which scaling up is recommended
+	lowWatermark  float64       // Utilization at or below which scaling down is recommended
+	sustain       time.Duration // How long a watermark must be crossed before recommending
+	aboveSince    time.Time     // When utilization first reached the high watermark
+	belowSince    time.Time     // When utilization first reached the low watermark
+	current       ScalingRecommendation
+	utilization   float64 // Most recent utilization sample
+	callback      ScalingCallback
+	mu            sync.Mutex
+}
+
+// NewScalingAdvisor creates a ScalingAdvisor with the given watermarks and sustain duration.
+func NewScalingAdvisor(highWatermark float64, lowWatermark float64, sustain time.Duration) *ScalingAdvisor {
+	return &ScalingAdvisor{
+		highWatermark: highWatermark,
+		lowWatermark:  lowWatermark,
+		sustain:       sustain,
+		current:       ScaleNone,
+	}
+}
+
+// SetCallback sets the function called when the recommendation changes.
+func (sa *ScalingAdvisor) SetCallback(callback ScalingCallback) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	sa.callback = callback
+}
+
+// Observe records a utilization sample taken at now and returns the resulting recommendation.
+func (sa *ScalingAdvisor) Observe(utilization float64, now time.Time) ScalingRecommendation {
+	sa.mu.Lock()
+
+	sa.utilization = utilization
+	recommendation := ScaleNone
+
+	switch {
+	case utilization >= sa.highWatermark:
+		sa.belowSince = time.Time{}
+		if sa.aboveSince.IsZero() {
+			sa.aboveSince = now
+		}
+		if now.Sub(sa.aboveSince) >= sa.sustain {
+			recommendation = ScaleUp
+		}
+	case utilization <= sa.lowWatermark:
+		sa.aboveSince = time.Time{}
+		if sa.belowSince.IsZero() {
+			sa.belowSince = now
+		}
+		if now.Sub(sa.belowSince) >= sa.sustain {
+			recommendation = ScaleDown
+		}
+	default:
+		sa.aboveSince = time.Time{}
+		sa.belowSince = time.Time{}
+	}
+
+	changed := recommendation != sa.current
+	sa.current = recommendation
+	callback := sa.callback
+	sa.mu.Unlock()
+
+	if changed && callback != nil {
+		callback(recommendation, utilization)
+	}
+
+	return recommendation
+}
+
+// Recommendation returns the current recommendation and the utilization it was based on.
+func (sa *ScalingAdvisor) Recommendation() (ScalingRecommendation, float64) {
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+
+	return sa.current, sa.utilization
+}
+
+// Utilization returns active connections as a fraction of the total connection
+// capacity of healthy backends. Backends without a connection cap are ignored.
+func (lb *LoadBalancer) Utilization() float64 {
+	active, capacity := 0, 0
+	for _, b := range lb.pool.GetHealthyBackends() {
+		maxConnections := b.GetMaxConnections()
+		if maxConnections <= 0 {
+			continue
+		}
+		active += b.GetActiveConnections()
+		capacity += maxConnections
+	}
+
+	if capacity == 0 {
+		return 0
+	}
+
+	return float64(active) / float64(capacity)
+}
+
+// ScalingRecommendation returns the current scaling recommendation and utilization.
+func (lb *LoadBalancer) ScalingRecommendation() (ScalingRecommendation, float64) {
+	if lb.scaling == nil {
+		return ScaleNone, lb.Utilization()
+	}
+
+	return lb.scaling.Recommendation()
+}
+
+// SetScalingCallback sets the function called when the scaling recommendation changes.
+func (lb *LoadBalancer) SetScalingCallback(callback ScalingCallback) {
+	if lb.scaling != nil {
+		lb.scaling.SetCallback(callback)
+	}
+}
+
+// startScalingMonitor periodically samples utilization until the load balancer stops.
+func (lb *LoadBalancer) startScalingMonitor(ctx context.Context) {
+	if lb.scaling == nil {
+		return
+	}
+
+	ticker := time.NewTicker(scalingSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			lb.scaling.Observe(lb.Utilization(), now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package loadbalancer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScalingAdvisorWatermarks(t *testing.T) {
+	sa := NewScalingAdvisor(0.8, 0.2, 10*time.Second)
+
+	var emitted []ScalingRecommendation
+	sa.SetCallback(func(recommendation ScalingRecommendation, utilization float64) {
+		emitted = append(emitted, recommendation)
+	})
+
+	start := time.Now()
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	steps := []struct {
+		seconds     int
+		utilization float64
+		want        ScalingRecommendation
+	}{
+		{0, 0.9, ScaleNone},  // Above the high watermark, not yet sustained
+		{5, 0.95, ScaleNone}, // Still within the sustain duration
+		{10, 0.85, ScaleUp},  // Sustained for the full duration
+		{11, 0.5, ScaleNone}, // Back between the watermarks
+		{12, 0.9, ScaleNone}, // A short spike restarts the sustain timer
+		{15, 0.1, ScaleNone}, // Below the low watermark, not yet sustained
+		{25, 0.15, ScaleDown},
+	}
+
+	for _, step := range steps {
+		if got := sa.Observe(step.utilization, at(step.seconds)); got != step.want {
+			t.Errorf("t=%ds utilization %.2f: got %s, want %s", step.seconds, step.utilization, got, step.want)
+		}
+	}
+
+	want := []ScalingRecommendation{ScaleUp, ScaleNone, ScaleDown}
+	if len(emitted) != len(want) {
+		t.Fatalf("callback got %v, want %v", emitted, want)
+	}
+	for i := range want {
+		if emitted[i] != want[i] {
+			t.Errorf("callback got %v, want %v", emitted, want)
+			break
+		}
+	}
+
+	if rec, utilization := sa.Recommendation(); rec != ScaleDown || utilization != 0.15 {
+		t.Errorf("Recommendation = %s, %.2f, want scale_down, 0.15", rec, utilization)
+	}
+}
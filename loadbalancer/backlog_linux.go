@@ -0,0 +1,34 @@
+//go:build linux
+
+package loadbalancer
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// setListenBacklog changes a listening socket's pending connection backlog by
+// calling listen again, which Linux allows on a socket that is already listening.
+// The kernel still caps the backlog at net.core.somaxconn.
+func setListenBacklog(ln net.Listener, backlog int) error {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("listener %T does not expose its socket", ln)
+	}
+
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var listenErr error
+	err = rc.Control(func(fd uintptr) {
+		listenErr = syscall.Listen(int(fd), backlog)
+	})
+	if err != nil {
+		return err
+	}
+
+	return listenErr
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package loadbalancer
+
+import (
+	"errors"
+	"net"
+)
+
+// setListenBacklog fails because changing the listen backlog is only supported on Linux.
+func setListenBacklog(ln net.Listener, backlog int) error {
+	return errors.New("listen_backlog is only supported on Linux")
+}
@@ -0,0 +1,150 @@
+package loadbalancer
+
+import (
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestProxiedBytesCounted(t *testing.T) {
+	request := "0123456789"
+	reply := strings.Repeat("r", 25)
+
+	addr := startBackend(t, func(conn net.Conn) {
+		io.ReadFull(conn, make([]byte, len(request)))
+		conn.Write([]byte(reply))
+	})
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+
+	conn := dial(t, addrs[0])
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != reply {
+		t.Fatalf("reply = %q, want %q", got, reply)
+	}
+	conn.Close()
+
+	b := lb.pool.GetBackendByAddress(addr)
+	waitFor(t, 2*time.Second, func() bool {
+		sent, received := b.GetBytes()
+		return sent == int64(len(request)) && received == int64(len(reply))
+	})
+}
+
+// recordingStats is a GlobalStatsRecorder keeping its totals for inspection.
+type recordingStats struct {
+	mu       sync.Mutex
+	total    int64
+	active   int64
+	sent     int64
+	received int64
+}
+
+func (rs *recordingStats) IncrementConnections() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.total++
+	rs.active++
+}
+
+func (rs *recordingStats) DecrementActiveConnections() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.active--
+}
+
+func (rs *recordingStats) AddBytesSent(bytes int64) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.sent += bytes
+}
+
+func (rs *recordingStats) AddBytesReceived(bytes int64) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.received += bytes
+}
+
+// snapshot returns the recorded totals.
+func (rs *recordingStats) snapshot() (total, active, sent, received int64) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.total, rs.active, rs.sent, rs.received
+}
+
+func TestGlobalStatsTotals(t *testing.T) {
+	lb := New(&config.Config{
+		ListenAddr:     "127.0.0.1:0",
+		ConnectTimeout: time.Second,
+		Backends: []config.BackendConfig{
+			{Address: startEchoBackend(t), Weight: 1},
+			{Address: startEchoBackend(t), Weight: 1},
+		},
+	})
+	recorder := &recordingStats{}
+	lb.SetGlobalStats(recorder)
+	addrs := serveListeners(t, lb)
+
+	// Each connection sends 5 bytes and gets them echoed back
+	for range 3 {
+		conn := dial(t, addrs[0])
+		roundTrip(t, conn, "hello")
+
+		if _, active, _, _ := recorder.snapshot(); active != 1 {
+			t.Errorf("active connections = %d while one is open, want 1", active)
+		}
+		conn.Close()
+		waitFor(t, 2*time.Second, func() bool {
+			_, active, _, _ := recorder.snapshot()
+			return active == 0
+		})
+	}
+
+	total, _, sent, received := recorder.snapshot()
+	if total != 3 || sent != 15 || received != 15 {
+		t.Errorf("totals = %d connections, %d sent, %d received, want 3, 15, 15", total, sent, received)
+	}
+}
+
+func TestGlobalBytesCountedWhileOpen(t *testing.T) {
+	lb := New(&config.Config{
+		ListenAddr:     "127.0.0.1:0",
+		ConnectTimeout: time.Second,
+		Backends:       []config.BackendConfig{{Address: startEchoBackend(t), Weight: 1}},
+	})
+	recorder := &recordingStats{}
+	lb.SetGlobalStats(recorder)
+	addrs := serveListeners(t, lb)
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "hello")
+
+	// The bytes show up while the connection is still open
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, sent, received := recorder.snapshot()
+		return sent == 5 && received == 5
+	})
+
+	// Closing it does not count them again
+	conn.Close()
+	waitFor(t, 2*time.Second, func() bool {
+		_, active, _, _ := recorder.snapshot()
+		return active == 0
+	})
+	if _, _, sent, received := recorder.snapshot(); sent != 5 || received != 5 {
+		t.Errorf("after close: %d sent, %d received, want 5 and 5", sent, received)
+	}
+}
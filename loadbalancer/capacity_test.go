@@ -0,0 +1,57 @@
+package loadbalancer
+
+import (
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestMaxConnectionsSkipsBackendAtCap(t *testing.T) {
+	_, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{
+			{Address: startNamedBackend(t, "a"), Weight: 1, MaxConnections: 1},
+			{Address: startNamedBackend(t, "b"), Weight: 1},
+		},
+	})
+
+	// Round robin picks a, then b, then a again; with a holding its one allowed
+	// connection the third goes to b instead
+	want := []string{"a", "b", "b"}
+	for i, w := range want {
+		conn := dial(t, addrs[0])
+		if got := readConnName(t, conn); got != w {
+			t.Errorf("connection %d routed to %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+func TestConcurrentConnectionLimitRefusesExcess(t *testing.T) {
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		MaxConcurrentConnections: 2,
+		Backends:                 []config.BackendConfig{{Address: startEchoBackend(t), Weight: 1}},
+	})
+
+	first := dial(t, addrs[0])
+	roundTrip(t, first, "one")
+	second := dial(t, addrs[0])
+	roundTrip(t, second, "two")
+
+	// The limit is saturated, so the next connection is refused
+	refused := dial(t, addrs[0])
+	refused.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := refused.Read(make([]byte, 1)); err == nil {
+		t.Error("connection over the limit was not refused")
+	}
+
+	if stats := lb.ConnectionLimitStats(); stats.InFlight != 2 || stats.Shed != 1 {
+		t.Errorf("stats = %+v, want 2 in flight and 1 shed", stats)
+	}
+
+	// Closing a connection frees its slot
+	first.Close()
+	waitFor(t, 2*time.Second, func() bool { return lb.ConnectionLimitStats().InFlight == 1 })
+
+	third := dial(t, addrs[0])
+	roundTrip(t, third, "three")
+}
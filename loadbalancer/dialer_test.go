@@ -0,0 +1,80 @@
+package loadbalancer
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+// recordingDialer dials with a net.Dialer and records every address it dialed.
+type recordingDialer struct {
+	mu    sync.Mutex
+	dials []string
+}
+
+// DialContext records address and dials it.
+func (d *recordingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.mu.Lock()
+	d.dials = append(d.dials, address)
+	d.mu.Unlock()
+
+	var nd net.Dialer
+	return nd.DialContext(ctx, network, address)
+}
+
+// count returns how many times address was dialed.
+func (d *recordingDialer) count(address string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := 0
+	for _, a := range d.dials {
+		if a == address {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCustomDialerUsedForProxyingAndHealthChecks(t *testing.T) {
+	configured := startEchoBackend(t)
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: configured, Weight: 1}},
+	})
+
+	d := &recordingDialer{}
+	lb.SetDialer(d)
+
+	// A backend added after SetDialer uses the dialer too
+	added := startEchoBackend(t)
+	if err := lb.AddBackend(lb.NewBackend(config.BackendConfig{Address: added, Weight: 1})); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, addr := range []string{configured, added} {
+		b := lb.pool.GetBackendByAddress(addr)
+		if !b.CheckHealth(time.Second) {
+			t.Fatalf("%s: health check failed", addr)
+		}
+		if n := d.count(addr); n != 1 {
+			t.Errorf("%s: %d dials after a health check, want 1", addr, n)
+		}
+	}
+
+	// Round robin sends one connection to each backend
+	for i := 0; i < 2; i++ {
+		conn := dial(t, addrs[0])
+		roundTrip(t, conn, "ping")
+		conn.Close()
+	}
+
+	for _, addr := range []string{configured, added} {
+		if n := d.count(addr); n != 2 {
+			t.Errorf("%s: %d dials after proxying, want 2", addr, n)
+		}
+	}
+}
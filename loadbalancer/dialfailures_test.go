@@ -0,0 +1,34 @@
+package loadbalancer
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestDialFailuresCountedOnClosedPort(t *testing.T) {
+	addr := closedAddr(t)
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+	b := lb.pool.GetBackendByAddress(addr)
+
+	// The client connection's dial fails and is counted
+	conn := dial(t, addrs[0])
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	io.Copy(io.Discard, conn)
+
+	if n := b.GetDialFailures(); n != 1 {
+		t.Fatalf("dial failures after a client connection = %d, want 1", n)
+	}
+
+	// Failed health checks are not dials, so they leave the count alone
+	lb.CheckAllBackends()
+	lb.CheckAllBackends()
+
+	if n := b.GetDialFailures(); n != 1 {
+		t.Errorf("dial failures after two health checks = %d, want 1", n)
+	}
+}
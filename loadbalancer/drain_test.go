@@ -0,0 +1,124 @@
+package loadbalancer
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestDrainForciblyClosesRemainingConnections(t *testing.T) {
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: startEchoBackend(t), Weight: 1}},
+	})
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "hello")
+
+	if forced := lb.Drain(200 * time.Millisecond); forced != 1 {
+		t.Errorf("Drain forced %d connections, want 1", forced)
+	}
+
+	// The forcibly closed connection reads EOF instead of hanging
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("connection still open after drain")
+	}
+}
+
+func TestDrainWithoutConnectionsReturnsAtOnce(t *testing.T) {
+	lb, _ := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: startEchoBackend(t), Weight: 1}},
+	})
+
+	start := time.Now()
+	if forced := lb.Drain(5 * time.Second); forced != 0 {
+		t.Errorf("Drain forced %d connections, want 0", forced)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Drain took %v with no connections", elapsed)
+	}
+}
+
+func TestDrainBackendWaitsForConnections(t *testing.T) {
+	drained := startNamedBackend(t, "a")
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{
+			{Address: drained, Weight: 1},
+			{Address: startNamedBackend(t, "b"), Weight: 1},
+		},
+	})
+
+	conn := dial(t, addrs[0])
+	if name := readConnName(t, conn); name != "a" {
+		t.Fatalf("first connection went to %s, want a", name)
+	}
+
+	type drainResult struct {
+		forced int
+		err    error
+	}
+	done := make(chan drainResult, 1)
+	go func() {
+		forced, err := lb.DrainBackend(drained, 5*time.Second)
+		done <- drainResult{forced, err}
+	}()
+
+	// New connections avoid the draining backend while it waits
+	waitFor(t, time.Second, lb.pool.GetBackendByAddress(drained).IsDraining)
+	for range 2 {
+		if name := readName(t, addrs[0], 1); name != "b" {
+			t.Errorf("new connection went to %s while a is draining", name)
+		}
+	}
+
+	select {
+	case <-done:
+		t.Fatal("drain finished while a connection was still open")
+	case <-time.After(200 * time.Millisecond):
+	}
+	if lb.pool.GetBackendByAddress(drained) == nil {
+		t.Fatal("backend removed before its connection closed")
+	}
+
+	conn.Close()
+
+	select {
+	case res := <-done:
+		if res.err != nil || res.forced != 0 {
+			t.Errorf("DrainBackend = %d, %v, want 0 forced and no error", res.forced, res.err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("drain did not finish after the connection closed")
+	}
+	if lb.pool.GetBackendByAddress(drained) != nil {
+		t.Error("backend still in the pool after draining")
+	}
+}
+
+func TestBeginDrainRefusesNewConnectionsOnly(t *testing.T) {
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: startEchoBackend(t), Weight: 1}},
+	})
+
+	existing := dial(t, addrs[0])
+	roundTrip(t, existing, "before")
+
+	if status := lb.BeginDrain(); !status.Draining || status.InFlight != 1 || status.Drained() {
+		t.Fatalf("BeginDrain = %+v, want draining with 1 in flight", status)
+	}
+
+	// New connections are accepted and closed cleanly
+	refused := dial(t, addrs[0])
+	refused.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := refused.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("new connection read err = %v, want EOF", err)
+	}
+
+	// The existing connection keeps working
+	roundTrip(t, existing, "during")
+
+	existing.Close()
+	waitFor(t, 2*time.Second, func() bool { return lb.GetDrainStatus().Drained() })
+}
@@ -0,0 +1,48 @@
+package loadbalancer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tcp_lb/config"
+)
+
+func TestEffectiveConfigReflectsRuntimeWeight(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{
+		"version": 1,
+		"listen_addr": "127.0.0.1:0",
+		"backends": [{"address": "10.0.0.1:80", "weight": 1}],
+		"health_check_interval_seconds": 10,
+		"connect_timeout_seconds": 5
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb := New(cfg)
+
+	if err := lb.SetBackendWeight("10.0.0.1:80", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := lb.EffectiveConfig().Config.Backends[0].Weight; w != 5 {
+		t.Errorf("effective weight = %d, want 5", w)
+	}
+	if w := cfg.Backends[0].Weight; w != 1 {
+		t.Errorf("loaded config weight = %d, want 1", w)
+	}
+
+	onDisk, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := onDisk.Backends[0].Weight; w != 1 {
+		t.Errorf("weight on disk = %d, want 1", w)
+	}
+}
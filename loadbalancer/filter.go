@@ -0,0 +1,152 @@
+package loadbalancer
+
+import (
+	"slices"
+	"sync"
+
+	"tcp_lb/backend"
+)
+
+// BackendFilter narrows the candidate backends before the algorithm picks one,
+// returning the backends that remain. Filters are composed into an ordered chain
+// with SetBackendFilters, so behaviours such as skipping full backends can be
+// combined with any algorithm.
+type BackendFilter interface {
+	Filter(backends []*backend.Backend) []*backend.Backend
+}
+
+// SetBackendFilters sets the chain of filters applied, in order, to the healthy
+// backends of every listener before its algorithm picks. Calling it without
+// filters removes the chain. The zone preference configured with local_zone is
+// kept and always applied after the chain. It is safe to call while connections
+// are being routed.
+func (lb *LoadBalancer) SetBackendFilters(filters ...BackendFilter) {
+	lb.filtersMu.Lock()
+	lb.filters = filters
+	lb.filtersMu.Unlock()
+
+	lb.applyFilters()
+}
+
+// applyFilters installs the filter chain followed by the zone filter, if any, as
+// the selection filter of every pool.
+func (lb *LoadBalancer) applyFilters() {
+	lb.filtersMu.Lock()
+	filters := slices.Clone(lb.filters)
+	if lb.zoneFilter != nil {
+		filters = append(filters, lb.zoneFilter)
+	}
+	lb.filtersMu.Unlock()
+
+	var chain backend.SelectionFilter
+	if len(filters) > 0 {
+		chain = func(backends []*backend.Backend) []*backend.Backend {
+			for _, f := range filters {
+				backends = f.Filter(backends)
+			}
+			return backends
+		}
+	}
+
+	lb.pool.SetSelectionFilter(chain)
+	for _, l := range lb.listeners {
+		l.pool.SetSelectionFilter(chain)
+	}
+}
+
+// MaxConnectionsFilter drops backends that have reached their connection cap, so
+// the algorithm picks among backends with room instead of retrying full ones.
+type MaxConnectionsFilter struct{}
+
+// Filter returns the backends below their connection cap.
+func (MaxConnectionsFilter) Filter(backends []*backend.Backend) []*backend.Backend {
+	var remaining []*backend.Backend
+	for _, b := range backends {
+		if !b.AtCapacity() {
+			remaining = append(remaining, b)
+		}
+	}
+
+	return remaining
+}
+
+// MaintenanceFilter drops backends placed in maintenance. Unlike pausing, a backend
+// in maintenance keeps its existing connections and its health checks; it just
+// receives no new connections.
+type MaintenanceFilter struct {
+	addresses map[string]bool // Addresses of backends in maintenance
+	mu        sync.RWMutex
+}
+
+// NewMaintenanceFilter creates a MaintenanceFilter with the given backends in maintenance.
+func NewMaintenanceFilter(addresses ...string) *MaintenanceFilter {
+	mf := &MaintenanceFilter{addresses: make(map[string]bool)}
+	for _, address := range addresses {
+		mf.addresses[address] = true
+	}
+
+	return mf
+}
+
+// SetMaintenance puts the backend at address into maintenance, or takes it out.
+func (mf *MaintenanceFilter) SetMaintenance(address string, maintenance bool) {
+	mf.mu.Lock()
+	defer mf.mu.Unlock()
+
+	if maintenance {
+		mf.addresses[address] = true
+	} else {
+		delete(mf.addresses, address)
+	}
+}
+
+// Filter returns the backends not in maintenance.
+func (mf *MaintenanceFilter) Filter(backends []*backend.Backend) []*backend.Backend {
+	mf.mu.RLock()
+	defer mf.mu.RUnlock()
+
+	var remaining []*backend.Backend
+	for _, b := range backends {
+		if !mf.addresses[b.Address] {
+			remaining = append(remaining, b)
+		}
+	}
+
+	return remaining
+}
+
+// ZoneFilter prefers backends in the local zone, keeping only those that are below
+// their connection cap. When no local backend is available traffic spills over to
+// the backends in other zones, or, if there are none, the backends are returned
+// unchanged.
+type ZoneFilter struct {
+	zone string
+}
+
+// NewZoneFilter creates a ZoneFilter preferring backends in zone.
+func NewZoneFilter(zone string) *ZoneFilter {
+	return &ZoneFilter{zone: zone}
+}
+
+// Filter returns the available local backends, or the other zones' backends if
+// there are none.
+func (zf *ZoneFilter) Filter(backends []*backend.Backend) []*backend.Backend {
+	var local, remote []*backend.Backend
+	for _, b := range backends {
+		switch {
+		case b.GetZone() != zf.zone:
+			remote = append(remote, b)
+		case !b.AtCapacity():
+			local = append(local, b)
+		}
+	}
+
+	switch {
+	case len(local) > 0:
+		return local
+	case len(remote) > 0:
+		return remote
+	default:
+		return backends
+	}
+}
@@ -0,0 +1,127 @@
+package loadbalancer
+
+import (
+	"slices"
+	"testing"
+
+	"tcp_lb/backend"
+	"tcp_lb/config"
+)
+
+// addresses returns the addresses of backends, in order.
+func addresses(backends []*backend.Backend) []string {
+	var addrs []string
+	for _, b := range backends {
+		addrs = append(addrs, b.Address)
+	}
+	return addrs
+}
+
+func TestBackendFiltersCompose(t *testing.T) {
+	lb := New(&config.Config{
+		Backends: []config.BackendConfig{
+			{Address: "full:1", Weight: 1, MaxConnections: 1},
+			{Address: "maintenance:1", Weight: 1},
+			{Address: "open1:1", Weight: 1},
+			{Address: "open2:1", Weight: 1},
+		},
+	})
+	connect(lb.pool.GetBackendByAddress("full:1"))
+
+	maintenance := NewMaintenanceFilter("maintenance:1")
+	lb.SetBackendFilters(MaxConnectionsFilter{}, maintenance)
+
+	want := []string{"open1:1", "open2:1"}
+	if got := addresses(lb.pool.GetSelectableBackends()); !slices.Equal(got, want) {
+		t.Errorf("selectable = %v, want %v", got, want)
+	}
+
+	// Each filter keeps working on its own within the chain
+	maintenance.SetMaintenance("maintenance:1", false)
+	maintenance.SetMaintenance("open1:1", true)
+	want = []string{"maintenance:1", "open2:1"}
+	if got := addresses(lb.pool.GetSelectableBackends()); !slices.Equal(got, want) {
+		t.Errorf("after maintenance change selectable = %v, want %v", got, want)
+	}
+
+	// Clearing the chain offers every healthy backend again
+	lb.SetBackendFilters()
+	if got := lb.pool.GetSelectableBackends(); len(got) != 4 {
+		t.Errorf("without filters selectable = %v, want all 4", addresses(got))
+	}
+}
+
+func TestZonePreferenceAndFailover(t *testing.T) {
+	lb := New(&config.Config{
+		LocalZone: "zone-a",
+		Backends: []config.BackendConfig{
+			{Address: "remote:1", Weight: 1, Zone: "zone-b"},
+			{Address: "local:1", Weight: 1, Zone: "zone-a", MaxConnections: 1},
+		},
+	})
+	local := lb.pool.GetBackendByAddress("local:1")
+
+	tests := []struct {
+		name  string
+		setup func()
+		want  []string
+	}{
+		{"local preferred", func() {}, []string{"local:1"}},
+		{"local over capacity", func() { connect(local) }, []string{"remote:1"}},
+		{"local unhealthy", func() { local.SetAlive(false) }, []string{"remote:1"}},
+		{"local recovered", func() {
+			local.CloseConnections()
+			local.SetAlive(true)
+		}, []string{"local:1"}},
+	}
+
+	for _, tt := range tests {
+		tt.setup()
+		if got := addresses(lb.pool.GetSelectableBackends()); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: selectable = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetBackendFiltersKeepsZonePreference(t *testing.T) {
+	lb := New(&config.Config{
+		LocalZone: "zone-a",
+		Backends: []config.BackendConfig{
+			{Address: "remote1:1", Weight: 1, Zone: "zone-b"},
+			{Address: "remote2:1", Weight: 1, Zone: "zone-b"},
+			{Address: "local:1", Weight: 1, Zone: "zone-a"},
+		},
+	})
+
+	maintenance := NewMaintenanceFilter("remote1:1")
+	lb.SetBackendFilters(maintenance)
+	if got := addresses(lb.pool.GetSelectableBackends()); !slices.Equal(got, []string{"local:1"}) {
+		t.Errorf("selectable = %v, want the local backend still preferred", got)
+	}
+
+	// The zone filter runs after the chain, spilling over among what it left
+	maintenance.SetMaintenance("local:1", true)
+	if got := addresses(lb.pool.GetSelectableBackends()); !slices.Equal(got, []string{"remote2:1"}) {
+		t.Errorf("selectable = %v, want [remote2:1]", got)
+	}
+}
+
+func TestZonePreferenceKeepsBackupsInReserve(t *testing.T) {
+	lb := New(&config.Config{
+		LocalZone: "zone-a",
+		Backends: []config.BackendConfig{
+			{Address: "remote:1", Weight: 1, Zone: "zone-b"},
+			{Address: "local-backup:1", Weight: 0, Zone: "zone-a"},
+		},
+	})
+
+	// A local backup does not take traffic from a healthy remote primary
+	if got := addresses(lb.pool.GetSelectableBackends()); !slices.Equal(got, []string{"remote:1"}) {
+		t.Errorf("selectable = %v, want the remote primary", got)
+	}
+
+	lb.pool.GetBackendByAddress("remote:1").SetAlive(false)
+	if got := addresses(lb.pool.GetSelectableBackends()); !slices.Equal(got, []string{"local-backup:1"}) {
+		t.Errorf("with no primary selectable = %v, want the backup", got)
+	}
+}
@@ -1,52 +1,119 @@
 package loadbalancer
 
 import (
+	"context"
+	"log"
 	"sync"
 	"tcp_lb/backend"
+	"tcp_lb/config"
 	"time"
 )
 
 // startHealthChecker runs periodic health checks on all backends.
-func (lb *LoadBalancer) startHealthChecker() {
+// A non-positive HealthCheckInterval disables active health checking.
+func (lb *LoadBalancer) startHealthChecker(ctx context.Context) {
+	if lb.config.HealthCheckInterval <= 0 {
+		log.Println("Health checks disabled (health check interval is not positive)")
+		return
+	}
+
+	// UDP backends have no connection to probe, so only HTTP checks apply
+	if lb.config.Protocol == config.ProtocolUDP && lb.config.HealthCheckType != config.HealthCheckHTTP {
+		log.Println("Health checks disabled (TCP checks do not apply to UDP backends)")
+		return
+	}
+
 	ticker := time.NewTicker(lb.config.HealthCheckInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			lb.checkAllBackends()
-		case <-lb.healthStop:
+		case tick := <-ticker.C:
+			lb.checkDueBackends(tick)
+		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-// checkAllBackends performs a health check on every backend in the pool.
-func (lb *LoadBalancer) checkAllBackends() {
-	backends := lb.pool.GetBackends()
+// defaultHealthCheckBackoffFactor caps health check backoff at this many intervals
+// when HealthCheckMaxBackoff is not configured.
+const defaultHealthCheckBackoffFactor = 8
+
+// checkDueBackends health checks every backend whose next check is due at tick,
+// then schedules its next check, backing off for backends that keep failing.
+func (lb *LoadBalancer) checkDueBackends(tick time.Time) {
+	interval := lb.config.HealthCheckInterval
+
+	var due []*backend.Backend
+	for _, b := range lb.pool.GetBackends() {
+		// Ticks can arrive slightly early relative to the schedule, so allow half an interval
+		if b.HealthCheckDue(tick.Add(interval / 2)) {
+			due = append(due, b)
+		}
+	}
+
+	lb.checkBackends(due, tick)
+}
+
+// CheckAllBackends health checks every backend immediately, regardless of its
+// schedule, and returns the resulting health status.
+func (lb *LoadBalancer) CheckAllBackends() HealthStatus {
+	lb.checkBackends(lb.pool.GetBackends(), time.Now())
+	return lb.GetHealthStatus()
+}
+
+// checkBackends health checks the given backends concurrently and schedules each
+// one's next check from the given time. Only one round of checks runs at a time,
+// so manual and scheduled checks don't interleave.
+func (lb *LoadBalancer) checkBackends(backends []*backend.Backend, from time.Time) {
+	lb.healthMu.Lock()
+	defer lb.healthMu.Unlock()
+
+	interval := lb.config.HealthCheckInterval
+	maxInterval := lb.config.HealthCheckMaxBackoff
+	if maxInterval <= 0 {
+		maxInterval = defaultHealthCheckBackoffFactor * interval
+	}
+
+	timeout := lb.config.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = lb.config.ConnectTimeout
+	}
 
 	var wg sync.WaitGroup
 	for _, b := range backends {
 		wg.Add(1)
 		go func(backend *backend.Backend) {
 			defer wg.Done()
-			backend.CheckHealth(lb.config.ConnectTimeout)
+			switch lb.config.HealthCheckType {
+			case config.HealthCheckHTTP:
+				backend.CheckHealthHTTP(lb.config.HealthCheckPath, timeout)
+			case config.HealthCheckExpect:
+				backend.CheckHealthExpect(lb.config.HealthCheckSend, lb.config.HealthCheckExpect, timeout)
+			default:
+				backend.CheckHealth(timeout)
+			}
+			if interval > 0 {
+				backend.ScheduleHealthCheck(from, interval, maxInterval)
+			}
 		}(b)
 	}
 	wg.Wait()
 }
 
 type HealthStatus struct {
-	TotalBackends   int             
-	HealthyBackends int             
-	Backends        []BackendHealth 
+	TotalBackends   int
+	HealthyBackends int
+	Backends        []BackendHealth
 }
 
 type BackendHealth struct {
-	Address      string        
-	Alive        bool          
-	LastCheck    time.Time     
-	ResponseTime time.Duration 
+	Address      string
+	Alive        bool
+	LastCheck    time.Time
+	ResponseTime time.Duration
+	Reason       backend.DownReason
 }
 
 // GetHealthStatus returns the current health status of all backends.
@@ -68,7 +135,8 @@ func (lb *LoadBalancer) GetHealthStatus() HealthStatus {
 			Address:      address,
 			Alive:        isAlive,
 			LastCheck:    lastCheck,
-			ResponseTime: 0,
+			ResponseTime: b.GetLastResponseTime(),
+			Reason:       b.GetDownReason(),
 		})
 	}
 
@@ -0,0 +1,178 @@
+package loadbalancer
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestZeroHealthCheckIntervalDisablesChecks(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		lb := New(&config.Config{
+			HealthCheckInterval: interval,
+			Backends:            []config.BackendConfig{{Address: "127.0.0.1:1", Weight: 1}},
+		})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			lb.startHealthChecker(context.Background())
+		}()
+
+		// A disabled checker returns at once instead of panicking in time.NewTicker
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("interval %v: health checker did not return", interval)
+		}
+
+		if b := lb.pool.GetBackendByAddress("127.0.0.1:1"); !b.IsAlive() {
+			t.Errorf("interval %v: backend was health checked", interval)
+		}
+	}
+}
+
+func TestHTTPHealthCheckType(t *testing.T) {
+	newHTTPBackend := func(status int) string {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(ts.Close)
+		return strings.TrimPrefix(ts.URL, "http://")
+	}
+	up := newHTTPBackend(http.StatusOK)
+	failing := newHTTPBackend(http.StatusServiceUnavailable)
+
+	lb := New(&config.Config{
+		HealthCheckInterval: time.Minute,
+		HealthCheckType:     config.HealthCheckHTTP,
+		HealthCheckPath:     "/health",
+		ConnectTimeout:      time.Second,
+		Backends: []config.BackendConfig{
+			{Address: up, Weight: 1},
+			{Address: failing, Weight: 1},
+		},
+	})
+
+	// A TCP check would pass both, since both accept connections
+	status := lb.CheckAllBackends()
+	if status.HealthyBackends != 1 {
+		t.Fatalf("healthy backends = %d, want 1", status.HealthyBackends)
+	}
+	if lb.pool.GetBackendByAddress(failing).IsAlive() {
+		t.Error("backend answering 503 is alive")
+	}
+}
+
+func TestHealthCheckBackoffGrowsForDownBackend(t *testing.T) {
+	up := startEchoBackend(t)
+	down := closedAddr(t)
+
+	const interval = time.Second
+	lb := New(&config.Config{
+		HealthCheckInterval:   interval,
+		HealthCheckMaxBackoff: 8 * time.Second,
+		ConnectTimeout:        time.Second,
+		Backends: []config.BackendConfig{
+			{Address: up, Weight: 1},
+			{Address: down, Weight: 1},
+		},
+	})
+
+	// Drive the scheduler with simulated ticks, recording when each backend is checked
+	start := time.Now()
+	checked := map[string][]int{}
+	for tick := range 40 {
+		at := start.Add(time.Duration(tick) * interval)
+		for _, addr := range []string{up, down} {
+			if lb.pool.GetBackendByAddress(addr).HealthCheckDue(at.Add(interval / 2)) {
+				checked[addr] = append(checked[addr], tick)
+			}
+		}
+		lb.checkDueBackends(at)
+	}
+
+	if n := len(checked[up]); n != 40 {
+		t.Errorf("healthy backend checked on %d of 40 ticks, want every tick", n)
+	}
+
+	// The down backend's gaps double from one interval up to the cap
+	want := []int{1, 2, 4, 8, 8, 8}
+	got := checked[down]
+	if len(got) < len(want)+1 {
+		t.Fatalf("down backend checked at ticks %v, too few checks", got)
+	}
+	for i, gap := range want {
+		if g := got[i+1] - got[i]; g != gap {
+			t.Errorf("gap %d = %d intervals, want %d (checks at ticks %v)", i+1, g, gap, got)
+		}
+	}
+}
+
+// slowAcceptDialer takes delay to connect, like a backend slow to accept, and
+// gives up if the dial's deadline comes first.
+type slowAcceptDialer struct {
+	delay time.Duration
+}
+
+// DialContext waits for the delay and then dials normally.
+func (d slowAcceptDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	select {
+	case <-time.After(d.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return (&net.Dialer{}).DialContext(ctx, network, address)
+}
+
+func TestHealthCheckTimeoutShorterThanConnectTimeout(t *testing.T) {
+	addr := startEchoBackend(t)
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		ConnectTimeout:     time.Second,
+		HealthCheckTimeout: 50 * time.Millisecond,
+		Backends:           []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+	lb.SetDialer(slowAcceptDialer{delay: 200 * time.Millisecond})
+
+	// A client dial waits out the slow accept within the connect timeout
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "ping")
+
+	// The health probe gives up first
+	if status := lb.CheckAllBackends(); status.HealthyBackends != 0 {
+		t.Errorf("%d healthy backends, want the slow backend marked unhealthy", status.HealthyBackends)
+	}
+}
+
+func TestExpectHealthCheckDispatched(t *testing.T) {
+	// A backend that accepts connections but answers with the wrong greeting
+	wrong := startBackend(t, func(conn net.Conn) { conn.Write([]byte("503 busy\n")) })
+	right := startBackend(t, func(conn net.Conn) { conn.Write([]byte("220 ready\n")) })
+
+	lb := New(&config.Config{
+		HealthCheckType:    config.HealthCheckExpect,
+		HealthCheckExpect:  "220",
+		HealthCheckTimeout: 300 * time.Millisecond,
+		Backends: []config.BackendConfig{
+			{Address: wrong, Weight: 1},
+			{Address: right, Weight: 1},
+		},
+	})
+
+	status := lb.CheckAllBackends()
+	if status.HealthyBackends != 1 {
+		t.Errorf("%d healthy backends, want 1", status.HealthyBackends)
+	}
+	if lb.pool.GetBackendByAddress(wrong).IsAlive() {
+		t.Error("backend with a non-matching reply is alive; a plain TCP check was used")
+	}
+	if !lb.pool.GetBackendByAddress(right).IsAlive() {
+		t.Error("backend with a matching reply is down")
+	}
+}
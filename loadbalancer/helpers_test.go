@@ -0,0 +1,165 @@
+package loadbalancer
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+// startBackend starts a TCP server running handle for each accepted connection and
+// returns its address. The server stops when the test ends.
+func startBackend(t *testing.T, handle func(conn net.Conn)) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// startEchoBackend starts a backend that echoes bytes back unchanged.
+func startEchoBackend(t *testing.T) string {
+	t.Helper()
+
+	return startBackend(t, func(conn net.Conn) { io.Copy(conn, conn) })
+}
+
+// closedAddr returns a loopback address nothing is listening on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	return addr
+}
+
+// startLoadBalancer creates a load balancer from cfg and serves its listeners on
+// loopback ports, returning their addresses in listener order. Unlike Start it
+// runs no health checks, so tests control backend health directly.
+func startLoadBalancer(t *testing.T, cfg *config.Config) (*LoadBalancer, []string) {
+	t.Helper()
+
+	if cfg.ListenAddr == "" && len(cfg.Listeners) == 0 {
+		cfg.ListenAddr = "127.0.0.1:0"
+	}
+	if cfg.ConnectTimeout == 0 {
+		cfg.ConnectTimeout = time.Second
+	}
+
+	lb := New(cfg)
+	return lb, serveListeners(t, lb)
+}
+
+// serveListeners serves lb's listeners on loopback ports, returning their
+// addresses in listener order. They are closed when the test ends.
+func serveListeners(t *testing.T, lb *LoadBalancer) []string {
+	t.Helper()
+
+	var addrs []string
+	for _, l := range lb.listeners {
+		if lb.config.Protocol == config.ProtocolUDP {
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.udpConn = conn
+			addrs = append(addrs, conn.LocalAddr().String())
+
+			go newUDPLoadBalancer(lb, l, conn).serve()
+			continue
+		}
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.netListener = ln
+		addrs = append(addrs, ln.Addr().String())
+
+		if lb.config.Protocol == config.ProtocolHTTP {
+			l.httpLB = newHTTPLoadBalancer(lb, l)
+			go l.httpLB.serve()
+		} else {
+			go lb.acceptLoop(l)
+		}
+	}
+
+	t.Cleanup(func() {
+		lb.cancel()
+		lb.closeListeners()
+	})
+
+	return addrs
+}
+
+// dial connects to addr, failing the test on error. The connection is closed
+// when the test ends.
+func dial(t *testing.T, addr string) net.Conn {
+	t.Helper()
+
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// roundTrip writes msg to conn and reads back as many bytes, failing the test if
+// they differ.
+func roundTrip(t *testing.T, conn net.Conn, msg string) {
+	t.Helper()
+
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != msg {
+		t.Fatalf("echo = %q, want %q", buf, msg)
+	}
+}
+
+// waitFor polls cond until it returns true, failing the test after timeout.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
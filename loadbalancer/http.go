@@ -0,0 +1,336 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tcp_lb/backend"
+)
+
+// httpLoadBalancer balances each HTTP request on a listener across the backends,
+// so requests sent over one keep-alive client connection can reach different
+// backends. Backend connections are pooled by the transport.
+type httpLoadBalancer struct {
+	lb     *LoadBalancer
+	l      *listener
+	proxy  *httputil.ReverseProxy
+	server *http.Server
+}
+
+// clientConnKey is the request context key holding the client's connection.
+type clientConnKey struct{}
+
+// requestKey is the request context key holding the request's routing state.
+type requestKey struct{}
+
+// httpRequestState is the routing state of one proxied request.
+type httpRequestState struct {
+	backend  *backend.Backend       // Backend chosen for the request
+	err      error                  // Error that made the proxy fail the request, if any
+	resolved atomic.Pointer[string] // Address of the backend connection the request used
+}
+
+// newHTTPLoadBalancer creates an HTTP request balancer for a listener.
+func newHTTPLoadBalancer(lb *LoadBalancer, l *listener) *httpLoadBalancer {
+	h := &httpLoadBalancer{lb: lb, l: l}
+
+	maxIdle := lb.config.MaxIdleConnsPerBackend
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+
+	h.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			state := pr.In.Context().Value(requestKey{}).(*httpRequestState)
+			pr.SetURL(&url.URL{Scheme: "http", Host: state.backend.Address})
+			if !lb.config.HTTPForwardedDisabled {
+				setForwardedHeaders(pr)
+			}
+		},
+		Transport: &http.Transport{
+			DialContext:         h.dialBackend,
+			MaxIdleConnsPerHost: maxIdle,
+			IdleConnTimeout:     lb.config.IdleTimeout,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			r.Context().Value(requestKey{}).(*httpRequestState).err = err
+			http.Error(w, "Bad gateway", http.StatusBadGateway)
+		},
+	}
+
+	h.server = &http.Server{
+		Handler: h,
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			return context.WithValue(ctx, clientConnKey{}, conn)
+		},
+	}
+
+	return h
+}
+
+// setForwardedHeaders tells the backend about the client: the client IP is appended
+// to any X-Forwarded-For chain the request arrived with, and X-Forwarded-Proto and
+// X-Forwarded-Host carry the scheme and host the client used.
+func setForwardedHeaders(pr *httputil.ProxyRequest) {
+	// Rewrite strips the inbound header, restore it so the client IP is appended
+	if prior, ok := pr.In.Header["X-Forwarded-For"]; ok {
+		pr.Out.Header["X-Forwarded-For"] = prior
+	}
+	pr.SetXForwarded()
+}
+
+// serve handles HTTP requests on the listener until it is closed.
+func (h *httpLoadBalancer) serve() {
+	err := h.server.Serve(&admitListener{Listener: h.l.netListener, lb: h.lb, l: h.l})
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("HTTP listener %s: %v", h.l.addr, err)
+	}
+}
+
+// closeIdle stops reusing client connections, closing those that are idle, so
+// draining is not held up by keep-alive clients.
+func (h *httpLoadBalancer) closeIdle() {
+	h.server.SetKeepAlivesEnabled(false)
+}
+
+// dialBackend connects to the backend a request was routed to, applying the
+// passive health check of the TCP path. The circuit breaker is told the outcome
+// of each request by ServeHTTP, as pooled connections are not dialed.
+func (h *httpLoadBalancer) dialBackend(ctx context.Context, network, address string) (net.Conn, error) {
+	b := h.l.pool.GetBackendByAddress(address)
+	if b == nil {
+		return nil, ErrBackendNotFound
+	}
+
+	conn, err := b.DialNetworkContext(ctx, network, h.lb.config.ConnectTimeout)
+	if err != nil {
+		b.MarkUnreachable()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// ServeHTTP picks a backend for the request with the listener's algorithm and
+// proxies the request to it. Each request goes through the same connection
+// events and observer calls as a TCP connection.
+func (h *httpLoadBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	cl := h.lb.newConnLog()
+	cl.event(ConnectionAccepted, "Accepted %s %s from %s on %s", r.Method, r.URL.Path, r.RemoteAddr, h.l.addr)
+	h.lb.observer.OnAccept(r.RemoteAddr)
+
+	clientIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		clientIP = host
+	}
+
+	b := h.pickBackend(clientIP)
+	if b == nil {
+		cl.event(ConnectionClosed, "No backend available for %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		h.lb.observer.OnClose("", 0, 0, time.Since(start))
+		http.Error(w, "No backend available", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Count the request as a connection to the backend while it is in flight,
+	// keyed by the client connection so a forced drain closes it
+	clientConn, _ := r.Context().Value(clientConnKey{}).(net.Conn)
+	if clientConn != nil {
+		b.AddConnection(clientConn)
+		defer b.RemoveConnection(clientConn)
+	}
+	if h.lb.globalStats != nil {
+		h.lb.globalStats.IncrementConnections()
+		defer h.lb.globalStats.DecrementActiveConnections()
+	}
+	cl.event(ConnectionBackendChosen, "Selected backend %s", b.Address)
+	h.lb.observer.OnBackendSelected(b.Address)
+
+	body := &countingReader{r: r.Body}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = body
+	}
+	rw := &countingResponseWriter{ResponseWriter: w}
+
+	// Resolve the breaker's half-open trial, if this request is it, even when the
+	// proxy aborts the handler with a panic
+	completed := false
+	defer func() {
+		if !completed {
+			recordOutcome(b, r, http.ErrAbortHandler)
+		}
+	}()
+
+	state := &httpRequestState{backend: b}
+	ctx := context.WithValue(r.Context(), requestKey{}, state)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			addr := info.Conn.RemoteAddr().String()
+			state.resolved.Store(&addr)
+		},
+	})
+	h.proxy.ServeHTTP(rw, r.WithContext(ctx))
+	completed = true
+	proxyErr := state.err
+	recordOutcome(b, r, proxyErr)
+
+	sent := body.count.Load()
+	b.AddBytes(sent, rw.count)
+	if h.lb.globalStats != nil {
+		h.lb.globalStats.AddBytesSent(sent)
+		h.lb.globalStats.AddBytesReceived(rw.count)
+	}
+	// As with the breaker, a request the client gave up on says nothing about the backend
+	if r.Context().Err() == nil && b.RecordResult(proxyErr == nil) {
+		cl.logf("Backend %s ejected as an outlier for its error rate", b.Address)
+	}
+
+	resolved := b.Address
+	if addr := state.resolved.Load(); addr != nil {
+		resolved = *addr
+	}
+
+	h.lb.logConnection(cl, ConnectionResult{
+		ID:            cl.id,
+		ClientAddr:    r.RemoteAddr,
+		ListenAddr:    h.l.addr,
+		BackendAddr:   b.Address,
+		ResolvedAddr:  resolved,
+		BytesSent:     sent,
+		BytesReceived: rw.count,
+		Duration:      time.Since(start),
+		Err:           proxyErr,
+	})
+}
+
+// recordOutcome reports a proxied request to the backend's circuit breaker: err
+// counts as a failure and a nil err as a success. Requests the client gave up on
+// say nothing about the backend, so they only release any half-open trial.
+func recordOutcome(b *backend.Backend, r *http.Request, err error) {
+	switch {
+	case r.Context().Err() != nil:
+		b.AbandonTrial()
+	case err != nil:
+		b.RecordDialFailure()
+	default:
+		b.RecordDialSuccess()
+	}
+}
+
+// pickBackend asks the listener's algorithm for a backend for the client at
+// clientIP, skipping those at their connection cap or held out by their circuit
+// breaker. It returns nil when no backend is available.
+func (h *httpLoadBalancer) pickBackend(clientIP string) *backend.Backend {
+	algorithm := h.l.algorithm
+	if algorithm == nil {
+		algorithm = h.lb.currentAlgorithm()
+	}
+
+	for attempt := 0; attempt < max(h.l.pool.Size(), 1); attempt++ {
+		var b *backend.Backend
+		if attempt == 0 {
+			b = nextBackendFor(algorithm, h.l.pool, clientIP)
+		} else {
+			b = algorithm.NextBackend(h.l.pool)
+		}
+		if b == nil {
+			return nil
+		}
+		if !b.AtCapacity() && b.AllowConnection() {
+			return b
+		}
+	}
+
+	return nil
+}
+
+// admitListener applies the load balancer's admission rules to accepted HTTP
+// client connections: draining, the per-IP rate limit and the concurrent
+// connection cap. Refused connections are closed without being served.
+type admitListener struct {
+	net.Listener
+	lb *LoadBalancer
+	l  *listener
+}
+
+// Accept returns the next admitted connection, releasing its connection slot
+// when it is closed.
+func (al *admitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := al.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case al.lb.draining.Load():
+			log.Printf("Draining, refusing connection from %s", conn.RemoteAddr())
+		case !al.lb.ipLimiter.Allow(clientIP(conn)):
+			log.Printf("Rate limit exceeded for %s, refusing connection", clientIP(conn))
+		case !al.lb.admit(al.lb.classify(conn, al.l)):
+			log.Printf("Connection cap reached, shedding connection from %s", conn.RemoteAddr())
+		default:
+			al.lb.setKeepAlive(conn)
+			return &admittedConn{Conn: conn, lb: al.lb}, nil
+		}
+		conn.Close()
+	}
+}
+
+// admittedConn releases its connection slot the first time it is closed.
+type admittedConn struct {
+	net.Conn
+	lb      *LoadBalancer
+	release sync.Once
+}
+
+// Close closes the connection and releases its slot.
+func (c *admittedConn) Close() error {
+	c.release.Do(c.lb.release)
+	return c.Conn.Close()
+}
+
+// countingReader counts the bytes read through it. The transport may still be
+// reading the body when the response is returned, so the count is atomic.
+type countingReader struct {
+	r     io.ReadCloser
+	count atomic.Int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.count.Add(int64(n))
+	return n, err
+}
+
+func (cr *countingReader) Close() error {
+	return cr.r.Close()
+}
+
+// countingResponseWriter counts the response body bytes written through it.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	count int64
+}
+
+func (cw *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := cw.ResponseWriter.Write(p)
+	cw.count += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush.
+func (cw *countingResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,247 @@
+package loadbalancer
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"tcp_lb/backend"
+	"tcp_lb/config"
+)
+
+// startHTTPBackend starts an HTTP backend that answers every request with its
+// name. It returns the backend's address.
+func startHTTPBackend(t *testing.T, name string) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, name)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.Listener.Addr().String()
+}
+
+// get sends a GET request for url with client and returns the response body. It
+// reports whether the request went over a reused client connection.
+func get(t *testing.T, client *http.Client, url string) (string, bool) {
+	t.Helper()
+
+	var reused bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused },
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d: %s", url, resp.StatusCode, body)
+	}
+
+	return string(body), reused
+}
+
+// newHTTPClient returns a client with its own keep-alive connection pool, closed
+// when the test ends.
+func newHTTPClient(t *testing.T) *http.Client {
+	t.Helper()
+
+	transport := &http.Transport{}
+	t.Cleanup(transport.CloseIdleConnections)
+
+	return &http.Client{Transport: transport, Timeout: 2 * time.Second}
+}
+
+func TestHTTPBalancesKeepAliveRequests(t *testing.T) {
+	_, addrs := startLoadBalancer(t, &config.Config{
+		Protocol: config.ProtocolHTTP,
+		Backends: []config.BackendConfig{
+			{Address: startHTTPBackend(t, "a"), Weight: 1},
+			{Address: startHTTPBackend(t, "b"), Weight: 1},
+		},
+	})
+	client := newHTTPClient(t)
+
+	first, _ := get(t, client, "http://"+addrs[0]+"/")
+	second, reused := get(t, client, "http://"+addrs[0]+"/")
+
+	if !reused {
+		t.Fatal("second request did not reuse the keep-alive connection")
+	}
+	if first == second {
+		t.Errorf("both keep-alive requests went to backend %q", first)
+	}
+}
+
+func TestHTTPResolvesBreakerTrial(t *testing.T) {
+	addr := startHTTPBackend(t, "a")
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Protocol:         config.ProtocolHTTP,
+		FailureThreshold: 1,
+		BreakerCooldown:  50 * time.Millisecond,
+		Backends:         []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+	b := lb.pool.GetBackendByAddress(addr)
+	client := newHTTPClient(t)
+
+	// Leave a pooled backend connection, so the trial request is not dialed
+	get(t, client, "http://"+addrs[0]+"/")
+
+	b.RecordDialFailure()
+	if state := b.GetBreakerState(); state != backend.BreakerOpen {
+		t.Fatalf("breaker %s after a failure, want open", state)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	get(t, client, "http://"+addrs[0]+"/")
+	if state := b.GetBreakerState(); state != backend.BreakerClosed {
+		t.Errorf("breaker %s after a successful trial, want closed", state)
+	}
+	get(t, client, "http://"+addrs[0]+"/")
+}
+
+func TestHTTPResolvedAddrFromConnection(t *testing.T) {
+	addr := startHTTPBackend(t, "a")
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configured := net.JoinHostPort("localhost", port)
+
+	lb := New(&config.Config{
+		ListenAddr:     "127.0.0.1:0",
+		Protocol:       config.ProtocolHTTP,
+		ConnectTimeout: time.Second,
+		Backends:       []config.BackendConfig{{Address: configured, Weight: 1}},
+	})
+	results := make(chan ConnectionResult, 1)
+	lb.SetConnectionCallback(func(result ConnectionResult) { results <- result })
+	addrs := serveListeners(t, lb)
+
+	get(t, newHTTPClient(t), "http://"+addrs[0]+"/")
+
+	select {
+	case result := <-results:
+		if result.BackendAddr != configured {
+			t.Errorf("BackendAddr = %q, want the configured %q", result.BackendAddr, configured)
+		}
+		if result.ResolvedAddr != addr {
+			t.Errorf("ResolvedAddr = %q, want the connected %q", result.ResolvedAddr, addr)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection callback not called")
+	}
+}
+
+func TestHTTPObserverCallbackSequence(t *testing.T) {
+	addr := startHTTPBackend(t, "a")
+	lb := New(&config.Config{
+		ListenAddr:     "127.0.0.1:0",
+		Protocol:       config.ProtocolHTTP,
+		ConnectTimeout: time.Second,
+		Backends:       []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+	observer := &recordingObserver{closed: make(chan struct{})}
+	lb.SetObserver(observer)
+
+	var mu sync.Mutex
+	var events []ConnectionEventType
+	lb.SetConnectionEventHook(func(event ConnectionEvent) {
+		mu.Lock()
+		events = append(events, event.Type)
+		mu.Unlock()
+	})
+	addrs := serveListeners(t, lb)
+
+	get(t, newHTTPClient(t), "http://"+addrs[0]+"/")
+
+	select {
+	case <-observer.closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnClose not called")
+	}
+
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []string{"accept", "selected " + addr, "close " + addr + " 0 1"}
+	if !reflect.DeepEqual(observer.calls, want) {
+		t.Errorf("calls = %q, want %q", observer.calls, want)
+	}
+	wantEvents := []ConnectionEventType{ConnectionAccepted, ConnectionBackendChosen, ConnectionClosed}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("events = %q, want %q", events, wantEvents)
+	}
+}
+
+func TestHTTPForwardedHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		disabled  bool
+		prior     string
+		wantFor   string
+		wantProto string
+	}{
+		{"client ip", false, "", "127.0.0.1", "http"},
+		{"appended to chain", false, "203.0.113.7", "203.0.113.7, 127.0.0.1", "http"},
+		{"disabled", true, "203.0.113.7", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := make(chan http.Header, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				headers <- r.Header.Clone()
+			}))
+			t.Cleanup(srv.Close)
+
+			_, addrs := startLoadBalancer(t, &config.Config{
+				Protocol:              config.ProtocolHTTP,
+				HTTPForwardedDisabled: tt.disabled,
+				Backends:              []config.BackendConfig{{Address: srv.Listener.Addr().String(), Weight: 1}},
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "http://"+addrs[0]+"/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.prior != "" {
+				req.Header.Set("X-Forwarded-For", tt.prior)
+			}
+			resp, err := newHTTPClient(t).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			h := <-headers
+			if got := h.Get("X-Forwarded-For"); got != tt.wantFor {
+				t.Errorf("X-Forwarded-For = %q, want %q", got, tt.wantFor)
+			}
+			if got := h.Get("X-Forwarded-Proto"); got != tt.wantProto {
+				t.Errorf("X-Forwarded-Proto = %q, want %q", got, tt.wantProto)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+//go:build linux
+
+package loadbalancer
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+// keepAliveOptions returns the SO_KEEPALIVE flag and TCP_KEEPIDLE seconds set on conn.
+func keepAliveOptions(t *testing.T, conn *net.TCPConn) (enabled, idle int) {
+	t.Helper()
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		enabled, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+		if sockErr == nil {
+			idle, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+
+	return enabled, idle
+}
+
+func TestKeepAliveAppliedToTCPConn(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Disable Go's default keepalive so only setKeepAlive turns it on
+	dialer := net.Dialer{KeepAlive: -1}
+	conn, err := dialer.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	tcpConn := conn.(*net.TCPConn)
+
+	disabled := &LoadBalancer{config: &config.Config{}}
+	disabled.setKeepAlive(tcpConn)
+	if enabled, _ := keepAliveOptions(t, tcpConn); enabled != 0 {
+		t.Error("keepalive enabled without tcp_keepalive_seconds")
+	}
+
+	lb := &LoadBalancer{config: &config.Config{TCPKeepAlive: 42 * time.Second}}
+	lb.setKeepAlive(tcpConn)
+	if enabled, idle := keepAliveOptions(t, tcpConn); enabled == 0 || idle != 42 {
+		t.Errorf("keepalive = %d with idle %ds, want enabled with 42s", enabled, idle)
+	}
+
+	// Connections without keepalive support are left alone
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	lb.setKeepAlive(client)
+}
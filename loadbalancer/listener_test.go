@@ -0,0 +1,202 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+// startNamedBackend starts a backend that writes name to each connection.
+func startNamedBackend(t *testing.T, name string) string {
+	t.Helper()
+
+	return startBackend(t, func(conn net.Conn) {
+		conn.Write([]byte(name))
+		io.Copy(io.Discard, conn)
+	})
+}
+
+// readName connects to addr and returns the name the chosen backend writes.
+func readName(t *testing.T, addr string, size int) string {
+	t.Helper()
+
+	conn := dial(t, addr)
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return string(buf)
+}
+
+// readConnName returns the one-byte name written by the backend behind conn,
+// leaving the connection open.
+func readConnName(t *testing.T, conn net.Conn) string {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return string(buf)
+}
+
+func TestListenersRouteToTaggedBackends(t *testing.T) {
+	_, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{
+			{Address: startNamedBackend(t, "a1"), Weight: 1, Tags: []string{"tier-a"}},
+			{Address: startNamedBackend(t, "b1"), Weight: 1, Tags: []string{"tier-b"}},
+			{Address: startNamedBackend(t, "a2"), Weight: 1, Tags: []string{"tier-a"}},
+		},
+		Listeners: []config.ListenerConfig{
+			{ListenAddr: "127.0.0.1:0", Tags: []string{"tier-a"}},
+			{ListenAddr: "127.0.0.1:0", Tags: []string{"tier-b"}, Algorithm: "least_connections"},
+		},
+	})
+
+	seenA := map[string]bool{}
+	for range 4 {
+		name := readName(t, addrs[0], 2)
+		if name != "a1" && name != "a2" {
+			t.Errorf("tier-a listener routed to %s", name)
+		}
+		seenA[name] = true
+	}
+	if len(seenA) != 2 {
+		t.Errorf("tier-a listener used %v, want both tier-a backends", seenA)
+	}
+
+	for range 4 {
+		if name := readName(t, addrs[1], 2); name != "b1" {
+			t.Errorf("tier-b listener routed to %s", name)
+		}
+	}
+}
+
+func TestStartServesEveryFrontend(t *testing.T) {
+	shared := startNamedBackend(t, "s")
+	dedicated := startNamedBackend(t, "d")
+	frontends := []string{closedAddr(t), closedAddr(t)}
+
+	lb := New(&config.Config{
+		ListenAddr:     frontends[0],
+		ConnectTimeout: time.Second,
+		Backends: []config.BackendConfig{
+			{Address: shared, Weight: 1},
+			{Address: dedicated, Weight: 1},
+		},
+		Listeners: []config.ListenerConfig{
+			{ListenAddr: frontends[1], Backends: []string{dedicated}},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- lb.StartContext(ctx) }()
+
+	// Wait for both frontends to be bound
+	for _, addr := range frontends {
+		waitFor(t, 2*time.Second, func() bool {
+			conn, err := net.Dial("tcp", addr)
+			if err == nil {
+				conn.Close()
+			}
+			return err == nil
+		})
+	}
+
+	// The main frontend shares the whole pool
+	seen := map[string]bool{}
+	for range 4 {
+		seen[readName(t, frontends[0], 1)] = true
+	}
+	if !seen["s"] || !seen["d"] {
+		t.Errorf("main frontend used %v, want both backends", seen)
+	}
+
+	// The second frontend only routes to its own backend
+	for range 4 {
+		if name := readName(t, frontends[1], 1); name != "d" {
+			t.Errorf("second frontend routed to %s", name)
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("StartContext = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("StartContext did not return after cancel")
+	}
+
+	for _, addr := range frontends {
+		if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+			conn.Close()
+			t.Errorf("%s still accepting after shutdown", addr)
+		}
+	}
+}
+
+// tempError is an accept error that reports itself as temporary, like running out
+// of file descriptors.
+type tempError struct{}
+
+func (tempError) Error() string   { return "too many open files" }
+func (tempError) Timeout() bool   { return false }
+func (tempError) Temporary() bool { return true }
+
+// failingListener is a listener whose Accept returns each of errs in turn, then
+// net.ErrClosed.
+type failingListener struct {
+	net.Listener
+	errs []error
+}
+
+// Accept returns the next queued error.
+func (l *failingListener) Accept() (net.Conn, error) {
+	if len(l.errs) == 0 {
+		return nil, net.ErrClosed
+	}
+	err := l.errs[0]
+	l.errs = l.errs[1:]
+	return nil, err
+}
+
+func TestAcceptErrorsCounted(t *testing.T) {
+	lb := New(&config.Config{ListenAddr: "127.0.0.1:0"})
+	l := lb.listeners[0]
+	l.netListener = &failingListener{errs: []error{
+		tempError{},
+		&net.OpError{Op: "accept", Net: "tcp", Err: tempError{}},
+		errors.New("listener broken"),
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lb.acceptLoop(l)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("accept loop did not return once the listener was closed")
+	}
+
+	if got := lb.AcceptStats(); got != (AcceptStats{Temporary: 2, Permanent: 1}) {
+		t.Errorf("AcceptStats = %+v, want 2 temporary and 1 permanent", got)
+	}
+}
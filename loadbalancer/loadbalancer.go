@@ -1,125 +1,942 @@
 package loadbalancer
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"net"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"tcp_lb/backend"
 	"tcp_lb/config"
 	"tcp_lb/proxy"
 	"time"
 )
 
+// ErrForcedShutdown is returned by Stop when connections had to be forcibly closed.
+var ErrForcedShutdown = errors.New("connections forcibly closed during shutdown")
+
+// ErrBackendNotFound is returned when no backend has the given address.
+var ErrBackendNotFound = errors.New("backend not found")
+
+// ErrBackendExists is returned when adding a backend whose address is already configured.
+var ErrBackendExists = errors.New("backend already exists")
+
+// errBackendAtCapacity records that a candidate backend was skipped for being at capacity.
+var errBackendAtCapacity = errors.New("backend at connection capacity")
+
+// errorBannerTimeout bounds how long writing the error banner to a client may block.
+const errorBannerTimeout = 5 * time.Second
+
+// errNoBackendAvailable records that the algorithm had no backend to offer.
+var errNoBackendAvailable = errors.New("no backend available")
+
+// defaultMaxIdleConns is how many idle connections each backend keeps for reuse
+// when connection_reuse is enabled without max_idle_conns_per_backend.
+const defaultMaxIdleConns = 4
+
+// errBreakerOpen records that a candidate backend was skipped by its circuit breaker.
+var errBreakerOpen = errors.New("backend circuit breaker open")
+
 // LoadBalancer is the main struct that coordinates all load balancing operations.
 type LoadBalancer struct {
-	config     *config.Config
-	pool       *backend.Pool
-	algorithm  Algorithm
-	listener   net.Listener
-	healthStop chan struct{}
+	config        *config.Config
+	pool          *backend.Pool
+	algorithm     Algorithm
+	algoMu        sync.RWMutex // Protects algorithm, which can be changed while connections are routed
+	listeners     []*listener
+	ctx           context.Context     // Cancelled on shutdown, ending background loops and in-flight dials
+	cancel        context.CancelFunc  // Cancels ctx
+	healthMu      sync.Mutex          // Serializes rounds of health checks
+	draining      atomic.Bool         // Set once Drain or BeginDrain has been called
+	scaling       *ScalingAdvisor     // Nil when scaling recommendations are disabled
+	retries       *retryLimiter       // Global limit on backend retries per second
+	globalStats   GlobalStatsRecorder // Optional recorder for totals across all backends
+	qosRules      []qosRule           // Rules assigning client connections a priority
+	inFlight      atomic.Int64        // Client connections currently being handled
+	shed          atomic.Int64        // Connections refused by the concurrent connection cap
+	acceptTemp    atomic.Int64        // Temporary errors returned by Accept
+	acceptPerm    atomic.Int64        // Other errors returned by Accept
+	ipLimiter     *ipRateLimiter      // Per-client-IP limit on new connections
+	connCallback  ConnectionCallback  // Optional callback for finished connections
+	connEventHook ConnectionEventHook // Optional hook for connection lifecycle events
+	observer      ConnectionObserver  // Notified of connection lifecycle steps, never nil
+	dialerMu      sync.Mutex          // Protects dialer
+	dialer        backend.Dialer      // Dialer for all backends, nil for the default net.Dialer
+	filtersMu     sync.Mutex          // Protects filters and zoneFilter
+	filters       []BackendFilter     // Filters set with SetBackendFilters
+	zoneFilter    BackendFilter       // Applied after filters when local_zone is set, nil otherwise
+}
+
+// GlobalStatsRecorder records connection and byte totals across all backends.
+type GlobalStatsRecorder interface {
+	IncrementConnections()
+	DecrementActiveConnections()
+	AddBytesSent(bytes int64)
+	AddBytesReceived(bytes int64)
+}
+
+// EffectiveConfig is the running configuration after runtime changes.
+type EffectiveConfig struct {
+	Config    config.Config // Configuration with backends reflecting the current pool
+	Algorithm string        // Name of the active algorithm
+	Draining  bool          // Whether the load balancer is draining
+}
+
+// listener is a frontend address with its own routing settings.
+type listener struct {
+	addr        string
+	pool        *backend.Pool // Backends this listener routes to
+	tags        []string      // Tags a backend needs to be routed to by this listener
+	backends    []string      // Addresses this listener is limited to, empty for any
+	algorithm   Algorithm     // Algorithm override, nil to use the load balancer's algorithm
+	netListener net.Listener
+	udpConn     *net.UDPConn      // Set instead of netListener when balancing UDP
+	httpLB      *httpLoadBalancer // Serves netListener when balancing HTTP requests
 }
 
 // New creates a LoadBalancer from configuration.
 func New(cfg *config.Config) *LoadBalancer {
 	backendPool := backend.NewPool()
 
-	for _, b := range cfg.Backends {
-		backendPool.AddBackend(backend.NewBackendWithWeight(b.Address, b.Weight))
-	}
+	ctx, cancel := context.WithCancel(context.Background())
 
 	loadbalancer := &LoadBalancer{
-		config:     cfg,
-		pool:       backendPool,
-		algorithm:  NewRoundRobin(),
-		healthStop: make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+		config:    cfg,
+		pool:      backendPool,
+		retries:   newRetryLimiter(cfg.MaxRetriesPerSecond),
+		qosRules:  parseQoSRules(cfg.QoSRules),
+		ipLimiter: newIPRateLimiter(cfg.MaxConnectionsPerSecondPerIP),
+		observer:  nopObserver{},
+	}
+	loadbalancer.algorithm = loadbalancer.withMinShare(NewRoundRobin())
+
+	for _, bc := range cfg.Backends {
+		if err := loadbalancer.AddBackend(loadbalancer.NewBackend(bc)); err != nil {
+			log.Printf("Backend %s: %v, ignoring duplicate", bc.Address, err)
+		}
+	}
+
+	if cfg.ScaleUpUtilization > 0 {
+		loadbalancer.scaling = NewScalingAdvisor(cfg.ScaleUpUtilization, cfg.ScaleDownUtilization, cfg.ScaleSustain)
+	}
+
+	if cfg.ListenAddr != "" {
+		loadbalancer.listeners = append(loadbalancer.listeners, &listener{
+			addr: cfg.ListenAddr,
+			pool: backendPool,
+		})
+	}
+
+	for _, lc := range cfg.Listeners {
+		l := newListener(lc, backendPool)
+		loadbalancer.applyLoadWeights(l.algorithm)
+		if l.algorithm != nil {
+			l.algorithm = loadbalancer.withMinShare(l.algorithm)
+		}
+		loadbalancer.listeners = append(loadbalancer.listeners, l)
+	}
+
+	if cfg.LocalZone != "" {
+		loadbalancer.zoneFilter = NewZoneFilter(cfg.LocalZone)
+		loadbalancer.applyFilters()
 	}
 
+	loadbalancer.loadState()
+
 	return loadbalancer
 }
 
-// SetAlgorithm changes the load balancing algorithm.
+// newListener creates a listener routing to the backends matching its tag filter.
+func newListener(lc config.ListenerConfig, backendPool *backend.Pool) *listener {
+	l := &listener{
+		addr:     lc.ListenAddr,
+		pool:     backendPool,
+		tags:     lc.Tags,
+		backends: lc.Backends,
+	}
+
+	if lc.Algorithm != "" {
+		algo, err := NewAlgorithm(lc.Algorithm)
+		if err != nil {
+			log.Printf("Listener %s: %v, using default algorithm", lc.ListenAddr, err)
+		} else {
+			l.algorithm = algo
+		}
+	}
+
+	// Tagged listeners and listeners with a backend list get their own pool
+	// sharing the matching backends
+	if len(lc.Tags) > 0 || len(lc.Backends) > 0 {
+		l.pool = backend.NewPool()
+		for _, b := range backendPool.GetBackends() {
+			if l.matches(b) {
+				l.pool.AddBackend(b)
+			}
+		}
+	}
+
+	return l
+}
+
+// matches reports whether b passes the listener's tag filter and backend list.
+func (l *listener) matches(b *backend.Backend) bool {
+	return b.HasTags(l.tags) && (len(l.backends) == 0 || slices.Contains(l.backends, b.Address))
+}
+
+// NewBackend creates a backend from bc with the load balancer's per-backend
+// settings applied: circuit breaker, slow start, outlier detection and, with
+// connection reuse, its idle connection limit. The backend is not added to any
+// pool; see AddBackend.
+func (lb *LoadBalancer) NewBackend(bc config.BackendConfig) *backend.Backend {
+	cfg := lb.config
+
+	b := backend.NewBackendWithWeight(bc.Address, bc.Weight)
+	b.HealthAddress = bc.HealthAddress
+	b.Tags = bc.Tags
+	b.Zone = bc.Zone
+	b.Labels = bc.Labels
+	b.MaxConnections = bc.MaxConnections
+	b.Cost = bc.Cost
+	b.SetCircuitBreaker(cfg.FailureThreshold, cfg.BreakerCooldown)
+	b.SetSlowStart(cfg.SlowStart)
+	b.SetOutlierDetection(cfg.OutlierErrorRatio, cfg.OutlierWindow, cfg.OutlierEjection)
+
+	lb.dialerMu.Lock()
+	b.SetDialer(lb.dialer)
+	lb.dialerMu.Unlock()
+
+	if cfg.ConnectionReuse {
+		maxIdle := cfg.MaxIdleConnsPerBackend
+		if maxIdle == 0 {
+			maxIdle = defaultMaxIdleConns
+		}
+		b.SetMaxIdleConns(maxIdle)
+	}
+
+	return b
+}
+
+// AddBackend adds b to the load balancer's pool and to the pool of every listener
+// whose tag filter and backend list it matches. It returns ErrBackendExists if a
+// backend with the same address is already configured.
+func (lb *LoadBalancer) AddBackend(b *backend.Backend) error {
+	if !lb.pool.AddBackendIfAbsent(b) {
+		return ErrBackendExists
+	}
+
+	for _, l := range lb.listeners {
+		if l.pool != lb.pool && l.matches(b) {
+			l.pool.AddBackend(b)
+		}
+	}
+
+	return nil
+}
+
+// DrainBackend stops new connections to a backend, waits up to timeout for its
+// active connections to finish, then removes it from the load balancer's pool and
+// every listener pool. It returns how many connections had to be closed at the
+// timeout, or ErrBackendNotFound.
+func (lb *LoadBalancer) DrainBackend(address string, timeout time.Duration) (int, error) {
+	forced, found := lb.pool.DrainBackend(address, timeout)
+	if !found {
+		return 0, ErrBackendNotFound
+	}
+
+	for _, l := range lb.listeners {
+		if l.pool != lb.pool {
+			l.pool.RemoveBackend(address)
+		}
+	}
+
+	return forced, nil
+}
+
+// SetGlobalStats sets the recorder for connection and byte totals. It must be
+// called before Start.
+func (lb *LoadBalancer) SetGlobalStats(recorder GlobalStatsRecorder) {
+	lb.globalStats = recorder
+}
+
+// SetDialer sets the dialer backends use for proxied connections and health
+// checks, including backends added later. A nil dialer restores the default
+// net.Dialer.
+func (lb *LoadBalancer) SetDialer(d backend.Dialer) {
+	lb.dialerMu.Lock()
+	defer lb.dialerMu.Unlock()
+
+	lb.dialer = d
+	for _, b := range lb.pool.GetBackends() {
+		b.SetDialer(d)
+	}
+}
+
+// SetAlgorithm changes the load balancing algorithm. It is safe to call while
+// connections are being routed. A LeastLoaded algorithm takes the configured
+// load weights, if any, and is wrapped to give every backend its configured
+// minimum share.
 func (lb *LoadBalancer) SetAlgorithm(algo Algorithm) {
+	lb.applyLoadWeights(algo)
+	algo = lb.withMinShare(algo)
+
+	lb.algoMu.Lock()
+	defer lb.algoMu.Unlock()
+
 	lb.algorithm = algo
 }
 
-// Start begins accepting TCP connections on the configured address.
+// applyLoadWeights sets the configured load weights on a LeastLoaded algorithm.
+// Without any configured weights the algorithm keeps its own.
+func (lb *LoadBalancer) applyLoadWeights(algo Algorithm) {
+	ll, ok := algo.(*LeastLoaded)
+	if !ok {
+		return
+	}
+
+	weights := LoadWeights{
+		Connections: lb.config.LoadWeightConnections,
+		LatencyMs:   lb.config.LoadWeightLatencyMs,
+		Failures:    lb.config.LoadWeightFailures,
+	}
+	if weights != (LoadWeights{}) {
+		ll.SetWeights(weights)
+	}
+}
+
+// withMinShare wraps algo in a MinShare when min_share_every is configured.
+func (lb *LoadBalancer) withMinShare(algo Algorithm) Algorithm {
+	if lb.config.MinShareEvery <= 0 {
+		return algo
+	}
+	return NewMinShare(algo, lb.config.MinShareEvery)
+}
+
+// currentAlgorithm returns the load balancer's active algorithm.
+func (lb *LoadBalancer) currentAlgorithm() Algorithm {
+	lb.algoMu.RLock()
+	defer lb.algoMu.RUnlock()
+
+	return lb.algorithm
+}
+
+// AlgorithmName returns the name of the active algorithm, such as "round_robin".
+func (lb *LoadBalancer) AlgorithmName() string {
+	return lb.currentAlgorithm().Name()
+}
+
+// EffectiveConfig returns the current running configuration, reflecting runtime
+// changes to backends, weights, the algorithm and draining state. The originally
+// loaded configuration is left unchanged.
+func (lb *LoadBalancer) EffectiveConfig() EffectiveConfig {
+	cfg := *lb.config
+	cfg.Backends = nil
+
+	for _, b := range lb.pool.GetBackends() {
+		cfg.Backends = append(cfg.Backends, config.BackendConfig{
+			Address:        b.Address,
+			HealthAddress:  b.GetHealthAddress(),
+			Weight:         b.GetWeight(),
+			Tags:           b.GetTags(),
+			Zone:           b.GetZone(),
+			Labels:         b.GetLabels(),
+			MaxConnections: b.GetMaxConnections(),
+			Cost:           b.GetCost(),
+		})
+	}
+
+	return EffectiveConfig{
+		Config:    cfg,
+		Algorithm: lb.currentAlgorithm().Name(),
+		Draining:  lb.draining.Load(),
+	}
+}
+
+// SetBackendWeight changes a backend's weight at runtime. When DrainOnZeroWeight is
+// enabled, setting the weight to zero drains the backend's existing connections in
+// the background, and a later positive weight returns it to rotation. Otherwise a
+// zero weight turns the backend into a backup.
+func (lb *LoadBalancer) SetBackendWeight(address string, weight int) error {
+	b := lb.pool.GetBackendByAddress(address)
+	if b == nil {
+		return ErrBackendNotFound
+	}
+
+	b.SetWeight(weight)
+
+	if !lb.config.DrainOnZeroWeight {
+		return nil
+	}
+
+	if weight == 0 {
+		go func() {
+			if forced := b.Drain(lb.config.ZeroWeightDrainTimeout); forced > 0 {
+				log.Printf("Backend %s drained after weight set to zero, forcibly closed %d connections",
+					address, forced)
+			}
+		}()
+	} else {
+		b.SetDraining(false)
+	}
+
+	return nil
+}
+
+// Start begins accepting TCP connections, UDP datagrams when the protocol is UDP,
+// or HTTP requests when it is HTTP, on all configured listeners. It blocks until every listener has been closed.
 func (lb *LoadBalancer) Start() error {
-	addr := lb.config.ListenAddr
-	listener, err := net.Listen("tcp", addr)
+	return lb.StartContext(context.Background())
+}
+
+// StartContext is like Start, but also shuts the load balancer down when ctx is
+// done: listeners are closed, background loops stop and in-flight backend dials
+// are abandoned. Connections already being proxied are left to finish; use Stop
+// to wait for them.
+func (lb *LoadBalancer) StartContext(ctx context.Context) error {
+	udp := lb.config.Protocol == config.ProtocolUDP
+	httpMode := lb.config.Protocol == config.ProtocolHTTP
 
+	listenConfig := lb.listenConfig()
+	for _, l := range lb.listeners {
+		var err error
+		if udp {
+			err = l.listenUDP(listenConfig)
+		} else {
+			l.netListener, err = listenConfig.Listen(ctx, "tcp", l.addr)
+			if err == nil && lb.config.ListenBacklog > 0 {
+				err = setListenBacklog(l.netListener, lb.config.ListenBacklog)
+			}
+			if err == nil && httpMode {
+				l.httpLB = newHTTPLoadBalancer(lb, l)
+			}
+		}
+		if err != nil {
+			lb.closeListeners()
+			return err
+		}
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		lb.cancel()
+		lb.closeListeners()
+	})
+	defer stop()
+
+	go lb.startHealthChecker(lb.ctx)
+	go lb.startScalingMonitor(lb.ctx)
+
+	var wg sync.WaitGroup
+	for _, l := range lb.listeners {
+		wg.Add(1)
+		go func(l *listener) {
+			defer wg.Done()
+			switch {
+			case udp:
+				newUDPLoadBalancer(lb, l, l.udpConn).serve()
+			case httpMode:
+				l.httpLB.serve()
+			default:
+				lb.acceptLoop(l)
+			}
+		}(l)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// listenConfig returns the configuration for binding listeners, setting
+// SO_REUSEPORT when reuse_port is enabled.
+func (lb *LoadBalancer) listenConfig() net.ListenConfig {
+	var lc net.ListenConfig
+	if lb.config.ReusePort {
+		lc.Control = reusePortControl
+	}
+	return lc
+}
+
+// listenUDP binds the listener's address for UDP.
+func (l *listener) listenUDP(lc net.ListenConfig) error {
+	conn, err := lc.ListenPacket(context.Background(), "udp", l.addr)
 	if err != nil {
 		return err
 	}
 
-	lb.listener = listener
-
-	go lb.startHealthChecker()
+	l.udpConn = conn.(*net.UDPConn)
+	return nil
+}
 
+// acceptLoop accepts connections on a listener until it is closed.
+func (lb *LoadBalancer) acceptLoop(l *listener) {
 	for {
-		conn, err := lb.listener.Accept()
+		conn, err := l.netListener.Accept()
 		if err != nil {
 
 			if errors.Is(err, net.ErrClosed) {
-				return nil
+				return
 			}
 
+			if isTemporary(err) {
+				lb.acceptTemp.Add(1)
+			} else {
+				lb.acceptPerm.Add(1)
+			}
 			log.Printf("Accept error: %v\n", err)
 			time.Sleep(50 * time.Millisecond)
 			continue
 		}
-		go lb.handleConnection(conn)
+
+		// Shed connections beyond the cap, low priority ones first
+		if !lb.admit(lb.classify(conn, l)) {
+			log.Printf("Connection cap reached, shedding connection from %s", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+
+		go func() {
+			defer lb.release()
+			lb.handleConnection(conn, l)
+		}()
+	}
+}
+
+// temporaryError is implemented by errors that report whether they are temporary,
+// such as the net.Error returned when Accept runs out of file descriptors.
+type temporaryError interface {
+	Temporary() bool
+}
+
+// isTemporary reports whether err says it is temporary.
+func isTemporary(err error) bool {
+	var te temporaryError
+	return errors.As(err, &te) && te.Temporary()
+}
+
+// AcceptStats counts errors returned by Accept across all listeners.
+type AcceptStats struct {
+	Temporary int64 // Errors reported as temporary, such as running out of file descriptors
+	Permanent int64 // All other accept errors
+}
+
+// AcceptStats returns a snapshot of accept errors across all listeners.
+func (lb *LoadBalancer) AcceptStats() AcceptStats {
+	return AcceptStats{
+		Temporary: lb.acceptTemp.Load(),
+		Permanent: lb.acceptPerm.Load(),
 	}
 }
 
-// Stop gracefully shuts down the load balancer.
-func (lb *LoadBalancer) Stop() error {
-	close(lb.healthStop)
+// Stop gracefully shuts down the load balancer, blocking until active connections
+// finish or ctx is done. It returns nil after a clean drain, or an error wrapping
+// ErrForcedShutdown if remaining connections had to be forcibly closed.
+func (lb *LoadBalancer) Stop(ctx context.Context) error {
+	lb.cancel()
 
-	if lb.listener != nil {
-		return lb.listener.Close()
+	if forced := lb.drain(ctx); forced > 0 {
+		return fmt.Errorf("%w: %d connections", ErrForcedShutdown, forced)
 	}
 
 	return nil
 }
 
-// handleConnection routes a client connection to a backend using the configured algorithm.
-func (lb *LoadBalancer) handleConnection(clientConn net.Conn) {
+// closeListeners closes all listeners, returning the first error encountered.
+func (lb *LoadBalancer) closeListeners() error {
+	var firstErr error
+	for _, l := range lb.listeners {
+		if l.netListener != nil {
+			if err := l.netListener.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		if l.udpConn != nil {
+			if err := l.udpConn.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		if l.httpLB != nil {
+			l.httpLB.closeIdle()
+		}
+	}
+
+	return firstErr
+}
+
+// drainPollInterval is how often draining checks for remaining active connections.
+const drainPollInterval = 100 * time.Millisecond
+
+// Drain stops accepting new connections and waits for active connections to finish.
+// Connections still open when the timeout elapses are closed, and their count is returned.
+func (lb *LoadBalancer) Drain(timeout time.Duration) int {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return lb.drain(ctx)
+}
+
+// DrainStatus is a snapshot of the load balancer's draining progress.
+type DrainStatus struct {
+	Draining bool  // Whether new connections are being refused
+	InFlight int64 // Client connections still being handled
+}
+
+// Drained reports whether draining has finished, with no connections left.
+func (s DrainStatus) Drained() bool {
+	return s.Draining && s.InFlight == 0
+}
+
+// BeginDrain makes the load balancer refuse new connections, closing them as soon
+// as they are accepted, while existing connections carry on. Unlike Drain it does
+// not wait or close the listeners, so progress is polled with GetDrainStatus.
+func (lb *LoadBalancer) BeginDrain() DrainStatus {
+	lb.draining.Store(true)
+
+	return lb.GetDrainStatus()
+}
+
+// GetDrainStatus returns whether the load balancer is draining and how many client
+// connections it is still handling.
+func (lb *LoadBalancer) GetDrainStatus() DrainStatus {
+	return DrainStatus{
+		Draining: lb.draining.Load(),
+		InFlight: lb.inFlight.Load(),
+	}
+}
+
+// drain closes the listeners and waits for active connections to finish until ctx is done,
+// then forcibly closes any that remain and returns their count.
+func (lb *LoadBalancer) drain(ctx context.Context) int {
+	lb.draining.Store(true)
+	lb.closeListeners()
+	defer lb.closeIdleConns()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for lb.activeConnections() > 0 {
+		select {
+		case <-ctx.Done():
+			forced := 0
+			for _, b := range lb.pool.GetBackends() {
+				forced += b.CloseConnections()
+			}
+			log.Printf("Drain timed out, forcibly closed %d connections", forced)
+			return forced
+		case <-ticker.C:
+		}
+	}
+
+	return 0
+}
+
+// closeIdleConns closes the idle pooled connections of all backends.
+func (lb *LoadBalancer) closeIdleConns() {
+	for _, b := range lb.pool.GetBackends() {
+		b.CloseIdleConns()
+	}
+}
+
+// activeConnections returns the number of active connections across all backends.
+func (lb *LoadBalancer) activeConnections() int {
+	total := 0
+	for _, b := range lb.pool.GetBackends() {
+		total += b.GetActiveConnections()
+	}
+	return total
+}
+
+// handleConnection routes a client connection to a backend using the listener's algorithm.
+func (lb *LoadBalancer) handleConnection(clientConn net.Conn, l *listener) {
 	defer clientConn.Close()
 
-	// Try up to pool size times to find a working backend
-	maxRetries := lb.pool.Size()
+	start := time.Now()
+	cl := lb.newConnLog()
+
+	// A panic while handling one connection, e.g. from a buggy algorithm, closes
+	// that connection instead of taking down the whole load balancer
+	defer func() {
+		if r := recover(); r != nil {
+			cl.event(ConnectionClosed, "Recovered from panic handling %s: %v", clientConn.RemoteAddr(), r)
+			cl.logf("%s", debug.Stack())
+		}
+	}()
+
+	cl.event(ConnectionAccepted, "Accepted from %s on %s", clientConn.RemoteAddr(), l.addr)
+	lb.observer.OnAccept(clientConn.RemoteAddr().String())
+
+	// Refuse new clients while draining by closing the connection straight away
+	if lb.draining.Load() {
+		cl.event(ConnectionClosed, "Draining, refusing connection from %s", clientConn.RemoteAddr())
+		lb.observer.OnClose("", 0, 0, time.Since(start))
+		return
+	}
+
+	// Refuse clients opening connections faster than their rate limit
+	if ip := clientIP(clientConn); !lb.ipLimiter.Allow(ip) {
+		cl.event(ConnectionClosed, "Rate limit exceeded for %s, refusing connection", ip)
+		lb.observer.OnClose("", 0, 0, time.Since(start))
+		return
+	}
+
+	lb.setKeepAlive(clientConn)
+
+	// Route by the client's first line, replaying it to the chosen backend
+	var stickyToken string
+	if lb.config.StickyByFirstLine {
+		var err error
+		stickyToken, clientConn, err = peekFirstLine(clientConn, lb.config.StickyMaxLineBytes)
+		if err != nil {
+			cl.event(ConnectionClosed, "Refusing connection from %s: first line: %v", clientConn.RemoteAddr(), err)
+			lb.observer.OnClose("", 0, 0, time.Since(start))
+			return
+		}
+	}
+
+	algorithm := l.algorithm
+	if algorithm == nil {
+		algorithm = lb.currentAlgorithm()
+	}
+
+	// Try up to max_retries times, or pool size times when unset, to find a working
+	// backend. An empty pool still gets one attempt so the failure is reported.
+	maxRetries := lb.config.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = max(l.pool.Size(), 1)
+	}
 	var lastErr error
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		nextBackend := lb.algorithm.NextBackend(lb.pool)
+		if attempt > 0 {
+			// Fail fast when the global retry budget is exhausted
+			if !lb.retries.Allow() {
+				cl.logf("Retry limit reached, giving up after %d attempts", attempt)
+				break
+			}
+
+			if lb.config.RetryBackoff > 0 && !sleepContext(lb.ctx, lb.config.RetryBackoff) {
+				cl.logf("Shutting down, giving up after %d attempts", attempt)
+				lastErr = lb.ctx.Err()
+				break
+			}
+		}
+
+		// The first attempt honours the session token or the client's IP for
+		// algorithms that hash it, retries use the algorithm alone
+		var nextBackend *backend.Backend
+		switch {
+		case attempt == 0 && stickyToken != "":
+			nextBackend = backendForToken(l.pool, stickyToken)
+		case attempt == 0:
+			nextBackend = nextBackendFor(algorithm, l.pool, clientIP(clientConn))
+		default:
+			nextBackend = algorithm.NextBackend(l.pool)
+		}
 		if nextBackend == nil {
-			log.Println("No backend available for connection")
-			return
+			cl.logf("No backend available for connection")
+			lastErr = errNoBackendAvailable
+			break
 		}
 
-		backendConn, err := nextBackend.Dial(lb.config.ConnectTimeout)
+		// Skip backends that have reached their connection cap
+		if nextBackend.AtCapacity() {
+			cl.logf("Backend %s is at capacity, skipping (attempt %d/%d)",
+				nextBackend.Address, attempt+1, maxRetries)
+			lastErr = errBackendAtCapacity
+			continue
+		}
+
+		// Skip backends whose circuit breaker is holding them out
+		if !nextBackend.AllowConnection() {
+			cl.logf("Backend %s circuit breaker is open, skipping (attempt %d/%d)",
+				nextBackend.Address, attempt+1, maxRetries)
+			lastErr = errBreakerOpen
+			continue
+		}
+
+		var backendConn net.Conn
+		var reused bool
+		var err error
+		if lb.config.ConnectionReuse {
+			backendConn, reused, err = nextBackend.DialReusable(lb.ctx, lb.config.ConnectTimeout)
+		} else {
+			backendConn, err = nextBackend.DialNetworkContext(lb.ctx, "tcp", lb.config.ConnectTimeout)
+		}
 		if err != nil {
+			nextBackend.RecordDialFailure()
+
 			// Mark backend as unhealthy (passive health check)
 			nextBackend.SetAlive(false)
-			log.Printf("Backend %s is down, marking unhealthy (attempt %d/%d)",
+			cl.logf("Backend %s is down, marking unhealthy (attempt %d/%d)",
 				nextBackend.Address, attempt+1, maxRetries)
 			lastErr = err
 			continue // Try another backend
 		}
 
+		// Tell the backend the original client address before any client data; a
+		// reused connection already carried the header for its first client
+		if lb.config.SendProxyProtocol && !reused {
+			if err := proxy.WriteProxyProtocolHeader(backendConn, clientConn); err != nil {
+				cl.logf("Backend %s: failed to write PROXY header: %v (attempt %d/%d)",
+					nextBackend.Address, err, attempt+1, maxRetries)
+				nextBackend.RecordDialFailure()
+				backendConn.Close()
+				lastErr = err
+				continue
+			}
+		}
+		nextBackend.RecordDialSuccess()
+		if reused {
+			cl.event(ConnectionBackendChosen, "Selected backend %s (%s), reusing an idle connection", nextBackend.Address, backendConn.RemoteAddr())
+		} else {
+			cl.event(ConnectionBackendChosen, "Selected backend %s (%s)", nextBackend.Address, backendConn.RemoteAddr())
+		}
+		lb.observer.OnBackendSelected(nextBackend.Address)
+
 		// Success - track and proxy the connection
+		lb.setKeepAlive(backendConn)
 		nextBackend.AddConnection(backendConn)
 		defer nextBackend.RemoveConnection(backendConn)
-		defer backendConn.Close()
 
-		proxy.Proxy(clientConn, backendConn)
+		// A backend connection left in a clean state goes back to the idle pool
+		reusable := false
+		defer func() {
+			if !reusable || !nextBackend.PutIdleConn(backendConn) {
+				backendConn.Close()
+			}
+		}()
+
+		if lb.globalStats != nil {
+			lb.globalStats.IncrementConnections()
+			defer lb.globalStats.DecrementActiveConnections()
+		}
+
+		opts := proxy.Options{
+			IdleTimeout:    lb.config.IdleTimeout,
+			Lifetime:       lb.config.MaxConnectionDuration,
+			ReadTimeout:    lb.config.ReadTimeout,
+			WriteTimeout:   lb.config.WriteTimeout,
+			CloseOnEOF:     lb.config.CloseOnEOF,
+			BufferSize:     lb.config.BufferSize,
+			BytesPerSecond: lb.config.PerConnectionBPS,
+		}
+		if lb.globalStats != nil {
+			// Count traffic as it flows, so throughput is accurate while connections are open
+			opts.OnTransfer = func(sent, received int64) {
+				if sent > 0 {
+					lb.globalStats.AddBytesSent(sent)
+				}
+				if received > 0 {
+					lb.globalStats.AddBytesReceived(received)
+				}
+			}
+		}
+		var bytesSent, bytesReceived int64
+		if lb.config.ConnectionReuse {
+			bytesSent, bytesReceived, reusable, err = proxy.ProxyReusable(clientConn, backendConn, opts)
+		} else {
+			bytesSent, bytesReceived, err = proxy.ProxyWithOptions(clientConn, backendConn, opts)
+		}
+		nextBackend.AddBytes(bytesSent, bytesReceived)
+
+		switch {
+		case errors.Is(err, proxy.ErrIdleTimeout), errors.Is(err, proxy.ErrReadTimeout), errors.Is(err, proxy.ErrWriteTimeout):
+			nextBackend.RecordIdleTimeout()
+		case errors.Is(err, proxy.ErrLifetimeExceeded):
+			nextBackend.RecordLifetimeTimeout()
+		}
+
+		// Only failures on the backend's side count against it. A client that goes
+		// away or stalls says nothing about the backend, and neither does a
+		// connection the load balancer closed itself, e.g. while draining.
+		var ejected bool
+		switch {
+		case err == nil:
+			ejected = nextBackend.RecordResult(true)
+		case errors.Is(err, proxy.ErrBackendFailed) && !errors.Is(err, net.ErrClosed):
+			ejected = nextBackend.RecordResult(false)
+		}
+		if ejected {
+			cl.logf("Backend %s ejected as an outlier for its error rate", nextBackend.Address)
+		}
+
+		lb.logConnection(cl, ConnectionResult{
+			ID:            cl.id,
+			ClientAddr:    clientConn.RemoteAddr().String(),
+			ListenAddr:    l.addr,
+			BackendAddr:   nextBackend.Address,
+			ResolvedAddr:  backendConn.RemoteAddr().String(),
+			BytesSent:     bytesSent,
+			BytesReceived: bytesReceived,
+			Duration:      time.Since(start),
+			Err:           err,
+		})
 		return
 	}
 
-	log.Printf("All backends failed, last error: %v", lastErr)
+	cl.event(ConnectionClosed, "All backends failed after %s, last error: %v",
+		time.Since(start).Round(time.Millisecond), lastErr)
+	lb.observer.OnClose("", 0, 0, time.Since(start))
+
+	// Tell the client why the connection is being closed
+	banner := lb.config.ErrorBanner
+	if errors.Is(lastErr, errNoBackendAvailable) && lb.config.NoBackendMessage != "" {
+		banner = lb.config.NoBackendMessage
+	}
+	if banner != "" {
+		clientConn.SetWriteDeadline(time.Now().Add(errorBannerTimeout))
+		clientConn.Write([]byte(banner))
+	}
+}
+
+// sleepContext waits for d, returning false if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// keepAliveConn is implemented by connections that support TCP keepalive, such as *net.TCPConn.
+type keepAliveConn interface {
+	SetKeepAlive(keepalive bool) error
+	SetKeepAlivePeriod(period time.Duration) error
+}
+
+// setKeepAlive enables TCP keepalive on conn when configured and supported by the
+// connection type; other connections, such as TLS ones, are left unchanged.
+func (lb *LoadBalancer) setKeepAlive(conn net.Conn) {
+	if lb.config.TCPKeepAlive <= 0 {
+		return
+	}
+
+	if kc, ok := conn.(keepAliveConn); ok {
+		kc.SetKeepAlive(true)
+		kc.SetKeepAlivePeriod(lb.config.TCPKeepAlive)
+	}
 }
 
 // GetPool returns the backend pool.
 func (lb *LoadBalancer) GetPool() *backend.Pool {
 	return lb.pool
 }
+
+// clientIP returns the IP address of the connection's remote end without its port.
+// IPv6 addresses come back without brackets, keeping any zone such as "fe80::1%eth0".
+// Addresses that are not host:port pairs are returned unchanged.
+func clientIP(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
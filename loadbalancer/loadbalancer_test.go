@@ -0,0 +1,79 @@
+package loadbalancer
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"tcp_lb/config"
+)
+
+func TestAddBackendJoinsMatchingListeners(t *testing.T) {
+	lb := New(&config.Config{
+		ListenAddr: "127.0.0.1:0",
+		Listeners: []config.ListenerConfig{
+			{ListenAddr: "127.0.0.1:0", Tags: []string{"web"}},
+			{ListenAddr: "127.0.0.1:0", Tags: []string{"db"}},
+		},
+	})
+
+	if err := lb.AddBackend(lb.NewBackend(config.BackendConfig{Address: "10.0.0.1:80", Weight: 1, Tags: []string{"web"}})); err != nil {
+		t.Fatal(err)
+	}
+
+	if lb.pool.GetBackendByAddress("10.0.0.1:80") == nil {
+		t.Error("backend missing from the main pool")
+	}
+	if lb.listeners[1].pool.GetBackendByAddress("10.0.0.1:80") == nil {
+		t.Error("backend missing from the web listener's pool")
+	}
+	if lb.listeners[2].pool.GetBackendByAddress("10.0.0.1:80") != nil {
+		t.Error("backend added to the db listener's pool")
+	}
+
+	err := lb.AddBackend(lb.NewBackend(config.BackendConfig{Address: "10.0.0.1:80", Weight: 1}))
+	if !errors.Is(err, ErrBackendExists) {
+		t.Errorf("duplicate add: err = %v, want ErrBackendExists", err)
+	}
+
+	if _, err := lb.DrainBackend("10.0.0.1:80", 0); err != nil {
+		t.Fatal(err)
+	}
+	if lb.listeners[1].pool.GetBackendByAddress("10.0.0.1:80") != nil {
+		t.Error("drained backend still in the web listener's pool")
+	}
+}
+
+// remoteAddrConn is a connection that only reports a remote address.
+type remoteAddrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+// RemoteAddr returns the configured remote address.
+func (c remoteAddrConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name string
+		addr net.Addr
+		want string
+	}{
+		{"ipv4", &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 5000}, "192.0.2.10"},
+		{"ipv6", &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 5000}, "2001:db8::1"},
+		{"ipv6 loopback", &net.TCPAddr{IP: net.IPv6loopback, Port: 80}, "::1"},
+		{"ipv6 with zone", &net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 5000, Zone: "eth0"}, "fe80::1%eth0"},
+		{"ipv4-mapped ipv6", &net.TCPAddr{IP: net.ParseIP("::ffff:192.0.2.10"), Port: 5000}, "192.0.2.10"},
+		{"not host:port", &net.UnixAddr{Name: "/tmp/lb.sock", Net: "unix"}, "/tmp/lb.sock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clientIP(remoteAddrConn{remote: tt.addr}); got != tt.want {
+				t.Errorf("clientIP(%s) = %q, want %q", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
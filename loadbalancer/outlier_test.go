@@ -0,0 +1,75 @@
+package loadbalancer
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+// resetConn closes conn with a TCP reset instead of an orderly shutdown.
+func resetConn(conn net.Conn) {
+	conn.(*net.TCPConn).SetLinger(0)
+	conn.Close()
+}
+
+func TestOnlyBackendFailuresCountAsOutliers(t *testing.T) {
+	tests := []struct {
+		name        string
+		resetClient bool
+		wantEjected bool
+	}{
+		{"client resets", true, false},
+		{"backend resets", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startBackend(t, func(conn net.Conn) {
+				if tt.resetClient {
+					io.Copy(conn, conn)
+					return
+				}
+				conn.Read(make([]byte, 4))
+				resetConn(conn)
+			})
+			lb := New(&config.Config{
+				ListenAddr:        "127.0.0.1:0",
+				ConnectTimeout:    time.Second,
+				OutlierErrorRatio: 0.5,
+				OutlierWindow:     time.Minute,
+				OutlierEjection:   time.Minute,
+				Backends:          []config.BackendConfig{{Address: addr, Weight: 1}},
+			})
+			results := make(chan ConnectionResult, 10)
+			lb.SetConnectionCallback(func(result ConnectionResult) { results <- result })
+			addrs := serveListeners(t, lb)
+
+			// Enough connections for the window's error ratio to be trusted
+			for range 5 {
+				conn := dial(t, addrs[0])
+				if tt.resetClient {
+					roundTrip(t, conn, "ping")
+					resetConn(conn)
+				} else {
+					conn.Write([]byte("ping"))
+					conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+					io.Copy(io.Discard, conn)
+					conn.Close()
+				}
+
+				select {
+				case <-results:
+				case <-time.After(2 * time.Second):
+					t.Fatal("connection callback not called")
+				}
+			}
+
+			if got := lb.pool.GetBackendByAddress(addr).IsEjected(); got != tt.wantEjected {
+				t.Errorf("ejected = %v, want %v", got, tt.wantEjected)
+			}
+		})
+	}
+}
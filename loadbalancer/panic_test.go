@@ -0,0 +1,52 @@
+package loadbalancer
+
+import (
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tcp_lb/backend"
+	"tcp_lb/config"
+)
+
+// panickingAlgorithm panics on its first selection, then falls back to round robin.
+type panickingAlgorithm struct {
+	calls    atomic.Int64
+	fallback *RoundRobin
+}
+
+// NextBackend panics the first time it is called.
+func (a *panickingAlgorithm) NextBackend(pool *backend.Pool) *backend.Backend {
+	if a.calls.Add(1) == 1 {
+		var b *backend.Backend
+		_ = b.Address // nil dereference, like a buggy algorithm
+	}
+	return a.fallback.NextBackend(pool)
+}
+
+// Name returns the algorithm name.
+func (a *panickingAlgorithm) Name() string {
+	return "Panicking"
+}
+
+func TestPanickingAlgorithmKeepsLoadBalancerAlive(t *testing.T) {
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: startEchoBackend(t), Weight: 1}},
+	})
+	lb.SetAlgorithm(&panickingAlgorithm{fallback: NewRoundRobin()})
+
+	// The connection whose handler panicked is closed
+	first := dial(t, addrs[0])
+	first.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := first.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("panicked connection read err = %v, want EOF", err)
+	}
+	// Its slot is released despite the panic
+	waitFor(t, 2*time.Second, func() bool { return lb.GetDrainStatus().InFlight == 0 })
+
+	// Later connections are still accepted and proxied
+	for range 3 {
+		roundTrip(t, dial(t, addrs[0]), "still alive")
+	}
+}
@@ -0,0 +1,39 @@
+package loadbalancer
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestProxyProtocolHeaderSentToBackend(t *testing.T) {
+	headers := make(chan string, 1)
+	addr := startBackend(t, func(conn net.Conn) {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		headers <- line
+	})
+
+	_, addrs := startLoadBalancer(t, &config.Config{
+		SendProxyProtocol: true,
+		Backends:          []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+
+	conn := dial(t, addrs[0])
+	client := conn.LocalAddr().(*net.TCPAddr)
+	lbAddr := conn.RemoteAddr().(*net.TCPAddr)
+
+	want := fmt.Sprintf("PROXY TCP4 %s %s %d %d\r\n", client.IP, lbAddr.IP, client.Port, lbAddr.Port)
+	select {
+	case got := <-headers:
+		if got != want {
+			t.Errorf("header = %q, want %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("backend received no header")
+	}
+}
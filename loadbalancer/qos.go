@@ -0,0 +1,122 @@
+package loadbalancer
+
+import (
+	"log"
+	"net"
+	"tcp_lb/config"
+)
+
+// Priority is the QoS class of a client connection.
+type Priority int
+
+const (
+	PriorityLow  Priority = iota // Shed first when the connection cap is under pressure
+	PriorityHigh                 // May use the connections reserved for high priority
+)
+
+// qosRule assigns a priority to connections matching a client network and/or listener.
+type qosRule struct {
+	network    *net.IPNet // Client network to match, nil matches any client
+	listenAddr string     // Listener address to match, empty matches any listener
+	priority   Priority
+}
+
+// parseQoSRules converts configured QoS rules, skipping invalid ones.
+func parseQoSRules(rules []config.QoSRule) []qosRule {
+	parsed := make([]qosRule, 0, len(rules))
+
+	for _, r := range rules {
+		rule := qosRule{listenAddr: r.ListenAddr}
+
+		if r.CIDR != "" {
+			_, network, err := net.ParseCIDR(r.CIDR)
+			if err != nil {
+				log.Printf("Ignoring QoS rule with invalid CIDR %q: %v", r.CIDR, err)
+				continue
+			}
+			rule.network = network
+		}
+
+		switch r.Priority {
+		case config.PriorityHigh:
+			rule.priority = PriorityHigh
+		case config.PriorityLow:
+			rule.priority = PriorityLow
+		default:
+			log.Printf("Ignoring QoS rule with unknown priority %q", r.Priority)
+			continue
+		}
+
+		parsed = append(parsed, rule)
+	}
+
+	return parsed
+}
+
+// classify returns the priority of the first QoS rule matching the connection,
+// or PriorityLow if none match.
+func (lb *LoadBalancer) classify(conn net.Conn, l *listener) Priority {
+	var clientIP net.IP
+	if tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		clientIP = tcpAddr.IP
+	}
+
+	for _, rule := range lb.qosRules {
+		if rule.listenAddr != "" && rule.listenAddr != l.addr {
+			continue
+		}
+		if rule.network != nil && (clientIP == nil || !rule.network.Contains(clientIP)) {
+			continue
+		}
+		return rule.priority
+	}
+
+	return PriorityLow
+}
+
+// admit reserves a slot under the concurrent connection cap, returning false if
+// the connection should be shed. Low priority connections cannot use the slots
+// reserved for high priority ones.
+func (lb *LoadBalancer) admit(priority Priority) bool {
+	limit := int64(lb.config.MaxConcurrentConnections)
+	if limit <= 0 {
+		lb.inFlight.Add(1)
+		return true
+	}
+
+	if priority == PriorityLow {
+		limit -= int64(lb.config.HighPriorityReserve)
+	}
+
+	for {
+		current := lb.inFlight.Load()
+		if current >= limit {
+			lb.shed.Add(1)
+			return false
+		}
+		if lb.inFlight.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
+// release frees a slot reserved by admit.
+func (lb *LoadBalancer) release() {
+	lb.inFlight.Add(-1)
+}
+
+// ConnectionLimitStats is a snapshot of the concurrent connection cap.
+type ConnectionLimitStats struct {
+	InFlight int64 // Client connections currently being handled
+	Limit    int   // Maximum concurrent connections, 0 means unlimited
+	Shed     int64 // Connections refused because the cap was reached
+}
+
+// ConnectionLimitStats returns the current in-flight count and cap activity.
+func (lb *LoadBalancer) ConnectionLimitStats() ConnectionLimitStats {
+	return ConnectionLimitStats{
+		InFlight: lb.inFlight.Load(),
+		Limit:    lb.config.MaxConcurrentConnections,
+		Shed:     lb.shed.Load(),
+	}
+}
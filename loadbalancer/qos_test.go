@@ -0,0 +1,43 @@
+package loadbalancer
+
+import (
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestHighPriorityAdmittedAtCap(t *testing.T) {
+	const highListener = "127.0.0.1:9443"
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		MaxConcurrentConnections: 2,
+		HighPriorityReserve:      1,
+		QoSRules:                 []config.QoSRule{{ListenAddr: highListener, Priority: config.PriorityHigh}},
+		Backends:                 []config.BackendConfig{{Address: startEchoBackend(t), Weight: 1}},
+		Listeners: []config.ListenerConfig{
+			{ListenAddr: "127.0.0.1:9080"},
+			{ListenAddr: highListener},
+		},
+	})
+	lowAddr, highAddr := addrs[0], addrs[1]
+
+	// The one slot open to low priority is taken
+	low := dial(t, lowAddr)
+	roundTrip(t, low, "low")
+
+	// A second low priority connection is shed
+	shed := dial(t, lowAddr)
+	shed.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := shed.Read(make([]byte, 1)); err == nil {
+		t.Error("low priority connection over the cap was not shed")
+	}
+
+	// The reserved slot still admits a high priority connection
+	high := dial(t, highAddr)
+	roundTrip(t, high, "high")
+
+	stats := lb.ConnectionLimitStats()
+	if stats.Shed != 1 || stats.InFlight != 2 {
+		t.Errorf("stats = %+v, want 1 shed and 2 in flight", stats)
+	}
+}
@@ -0,0 +1,81 @@
+package loadbalancer
+
+import (
+	"sync"
+	"time"
+)
+
+// ipBucketIdleTimeout is how long a client's bucket may go unused before it is removed.
+const ipBucketIdleTimeout = time.Minute
+
+// ipBucket is a token bucket for one client IP.
+type ipBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// ipRateLimiter limits new connections per client IP with a token bucket per
+// address, refilling at rate tokens per second up to a burst of rate.
+type ipRateLimiter struct {
+	rate        int // Connections per second per IP, 0 means unlimited
+	buckets     map[string]*ipBucket
+	lastCleanup time.Time
+	mu          sync.Mutex
+}
+
+// newIPRateLimiter creates an ipRateLimiter allowing rate connections per second per IP.
+func newIPRateLimiter(rate int) *ipRateLimiter {
+	return &ipRateLimiter{
+		rate:        rate,
+		buckets:     make(map[string]*ipBucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Allow reports whether a new connection from the client ip may proceed, taking a
+// token if so.
+func (rl *ipRateLimiter) Allow(ip string) bool {
+	if rl.rate <= 0 {
+		return true
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	rl.cleanup(now)
+
+	bucket, ok := rl.buckets[ip]
+	if !ok {
+		bucket = &ipBucket{tokens: float64(rl.rate)}
+		rl.buckets[ip] = bucket
+	} else {
+		bucket.tokens += now.Sub(bucket.lastSeen).Seconds() * float64(rl.rate)
+		if bucket.tokens > float64(rl.rate) {
+			bucket.tokens = float64(rl.rate)
+		}
+	}
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+	return true
+}
+
+// cleanup removes buckets idle for longer than ipBucketIdleTimeout, at most once
+// per timeout. Caller must hold mu.
+func (rl *ipRateLimiter) cleanup(now time.Time) {
+	if now.Sub(rl.lastCleanup) < ipBucketIdleTimeout {
+		return
+	}
+	rl.lastCleanup = now
+
+	for ip, bucket := range rl.buckets {
+		if now.Sub(bucket.lastSeen) >= ipBucketIdleTimeout {
+			delete(rl.buckets, ip)
+		}
+	}
+}
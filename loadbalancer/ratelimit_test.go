@@ -0,0 +1,57 @@
+package loadbalancer
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestPerIPRateLimitRefusesExcess(t *testing.T) {
+	addr := startEchoBackend(t)
+	_, addrs := startLoadBalancer(t, &config.Config{
+		Backends:                     []config.BackendConfig{{Address: addr, Weight: 1}},
+		MaxConnectionsPerSecondPerIP: 3,
+	})
+
+	// Open connections well within a second, faster than the bucket refills
+	served, refused := 0, 0
+	for i := 0; i < 6; i++ {
+		conn := dial(t, addrs[0])
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+		conn.Write([]byte("x"))
+
+		buf := make([]byte, 1)
+		if _, err := io.ReadFull(conn, buf); err == nil {
+			served++
+		} else {
+			refused++
+		}
+	}
+
+	if served != 3 || refused != 3 {
+		t.Errorf("served %d and refused %d connections, want 3 and 3", served, refused)
+	}
+}
+
+func TestIPRateLimiterRefills(t *testing.T) {
+	rl := newIPRateLimiter(10)
+
+	for i := 0; i < 10; i++ {
+		if !rl.Allow("192.0.2.1") {
+			t.Fatalf("connection %d refused within the burst", i)
+		}
+	}
+	if rl.Allow("192.0.2.1") {
+		t.Error("connection beyond the burst allowed")
+	}
+	if !rl.Allow("192.0.2.2") {
+		t.Error("another IP shares the exhausted bucket")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if !rl.Allow("192.0.2.1") {
+		t.Error("bucket did not refill")
+	}
+}
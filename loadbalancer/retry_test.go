@@ -0,0 +1,150 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+// failingDialer fails its first failures dials and passes the rest to a
+// recordingDialer.
+type failingDialer struct {
+	recordingDialer
+	mu       sync.Mutex
+	failures int
+	attempts int
+}
+
+// DialContext fails until failures dials have been attempted.
+func (d *failingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.mu.Lock()
+	d.attempts++
+	fail := d.attempts <= d.failures
+	d.mu.Unlock()
+
+	if fail {
+		return nil, errors.New("dial refused by test")
+	}
+	return d.recordingDialer.DialContext(ctx, network, address)
+}
+
+// attempted returns how many dials were attempted.
+func (d *failingDialer) attempted() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.attempts
+}
+
+// startRetryLoadBalancer serves a load balancer over three echo backends whose
+// first failures dials fail.
+func startRetryLoadBalancer(t *testing.T, cfg *config.Config, failures int) (*failingDialer, string) {
+	t.Helper()
+
+	for range 3 {
+		cfg.Backends = append(cfg.Backends, config.BackendConfig{Address: startEchoBackend(t), Weight: 1})
+	}
+	cfg.ListenAddr = "127.0.0.1:0"
+	cfg.ConnectTimeout = time.Second
+
+	lb := New(cfg)
+	d := &failingDialer{failures: failures}
+	lb.SetDialer(d)
+
+	return d, serveListeners(t, lb)[0]
+}
+
+func TestRetryCountHonored(t *testing.T) {
+	d, addr := startRetryLoadBalancer(t, &config.Config{
+		MaxRetries:   2,
+		RetryBackoff: 50 * time.Millisecond,
+		ErrorBanner:  "unavailable\n",
+	}, 3)
+
+	start := time.Now()
+	conn := dial(t, addr)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	got, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "unavailable\n" {
+		t.Errorf("client got %q, want the error banner", got)
+	}
+	if n := d.attempted(); n != 2 {
+		t.Errorf("%d dial attempts, want max_retries = 2", n)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("gave up after %v, want at least one 50ms backoff", elapsed)
+	}
+}
+
+func TestRetrySucceedsOnThirdAttempt(t *testing.T) {
+	d, addr := startRetryLoadBalancer(t, &config.Config{MaxRetries: 3}, 2)
+
+	conn := dial(t, addr)
+	roundTrip(t, conn, "ping")
+
+	if n := d.attempted(); n != 3 {
+		t.Errorf("%d dial attempts, want 3", n)
+	}
+}
+
+func TestAllBackendsDownWritesMessageAndClosesOnce(t *testing.T) {
+	lb := New(&config.Config{
+		ListenAddr:       "127.0.0.1:0",
+		ConnectTimeout:   time.Second,
+		MaxRetries:       5,
+		ErrorBanner:      "unavailable\n",
+		NoBackendMessage: "no backend\n",
+		Backends: []config.BackendConfig{
+			{Address: startEchoBackend(t), Weight: 1},
+			{Address: startEchoBackend(t), Weight: 1},
+		},
+	})
+	for _, b := range lb.pool.GetBackends() {
+		b.SetAlive(false)
+	}
+
+	d := &failingDialer{}
+	lb.SetDialer(d)
+
+	var mu sync.Mutex
+	var closes int
+	lb.SetConnectionEventHook(func(event ConnectionEvent) {
+		if event.Type == ConnectionClosed {
+			mu.Lock()
+			closes++
+			mu.Unlock()
+		}
+	})
+	addrs := serveListeners(t, lb)
+
+	conn := dial(t, addrs[0])
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	got, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != "no backend\n" {
+		t.Errorf("client got %q, want the no backend message", got)
+	}
+	// Nothing to dial, so the retry loop stops at once
+	if n := d.attempted(); n != 0 {
+		t.Errorf("%d dial attempts with every backend down, want 0", n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if closes != 1 {
+		t.Errorf("%d close events, want 1", closes)
+	}
+}
@@ -0,0 +1,76 @@
+package loadbalancer
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryStats is a snapshot of connection retry activity.
+type RetryStats struct {
+	RetriesPerSecond int   // Retries in the current one-second window
+	TotalRetries     int64 // Retries performed since start
+	Throttled        int64 // Retries refused because the global limit was reached
+}
+
+// retryLimiter caps the number of backend retries performed per second across
+// all connections, so a broad outage doesn't multiply load on surviving backends.
+type retryLimiter struct {
+	limit       int       // Maximum retries per second, 0 means unlimited
+	windowStart time.Time // Start of the current one-second window
+	count       int       // Retries in the current window
+	total       int64
+	throttled   int64
+	mu          sync.Mutex
+}
+
+// newRetryLimiter creates a retryLimiter allowing limit retries per second.
+func newRetryLimiter(limit int) *retryLimiter {
+	return &retryLimiter{
+		limit:       limit,
+		windowStart: time.Now(),
+	}
+}
+
+// Allow reports whether another retry may be performed, counting it if so.
+func (rl *retryLimiter) Allow() bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.advanceWindow()
+
+	if rl.limit > 0 && rl.count >= rl.limit {
+		rl.throttled++
+		return false
+	}
+
+	rl.count++
+	rl.total++
+	return true
+}
+
+// Stats returns a snapshot of retry activity.
+func (rl *retryLimiter) Stats() RetryStats {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.advanceWindow()
+
+	return RetryStats{
+		RetriesPerSecond: rl.count,
+		TotalRetries:     rl.total,
+		Throttled:        rl.throttled,
+	}
+}
+
+// advanceWindow starts a new window once the current one is over. Caller must hold mu.
+func (rl *retryLimiter) advanceWindow() {
+	if time.Since(rl.windowStart) >= time.Second {
+		rl.windowStart = time.Now()
+		rl.count = 0
+	}
+}
+
+// RetryStats returns a snapshot of connection retry activity.
+func (lb *LoadBalancer) RetryStats() RetryStats {
+	return lb.retries.Stats()
+}
@@ -0,0 +1,58 @@
+package loadbalancer
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestRetryLimiterCapsPerSecond(t *testing.T) {
+	rl := newRetryLimiter(5)
+
+	allowed := 0
+	for range 100 {
+		if rl.Allow() {
+			allowed++
+		}
+	}
+
+	if allowed != 5 {
+		t.Errorf("allowed %d retries, want 5", allowed)
+	}
+	if stats := rl.Stats(); stats.TotalRetries != 5 || stats.Throttled != 95 || stats.RetriesPerSecond != 5 {
+		t.Errorf("stats = %+v, want 5 retries and 95 throttled", stats)
+	}
+}
+
+func TestGlobalRetryLimitUnderMassFailure(t *testing.T) {
+	var backends []config.BackendConfig
+	for range 10 {
+		backends = append(backends, config.BackendConfig{Address: closedAddr(t), Weight: 1})
+	}
+
+	const limit = 2
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		MaxRetriesPerSecond: limit,
+		Backends:            backends,
+	})
+
+	start := time.Now()
+	for range 5 {
+		conn := dial(t, addrs[0])
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		io.Copy(io.Discard, conn)
+		conn.Close()
+	}
+
+	stats := lb.RetryStats()
+	if stats.Throttled == 0 {
+		t.Error("no retries were throttled")
+	}
+	// Every connection wanted more retries than the limit, but within one window
+	// only the limit may be spent across all of them
+	if time.Since(start) < time.Second && stats.TotalRetries > limit {
+		t.Errorf("%d retries within a second, limit is %d", stats.TotalRetries, limit)
+	}
+}
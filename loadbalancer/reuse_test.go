@@ -0,0 +1,123 @@
+package loadbalancer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+// startLineBackend starts a backend that answers each line it reads with
+// "reply-N", numbering replies across all connections, after waiting delay. It
+// returns its address and a counter of accepted connections.
+func startLineBackend(t *testing.T, delay time.Duration) (string, *atomic.Int64) {
+	t.Helper()
+
+	var accepted, replies atomic.Int64
+	addr := startBackend(t, func(conn net.Conn) {
+		accepted.Add(1)
+		r := bufio.NewReader(conn)
+		for {
+			if _, err := r.ReadString('\n'); err != nil {
+				return
+			}
+			time.Sleep(delay)
+			fmt.Fprintf(conn, "reply-%d\n", replies.Add(1))
+		}
+	})
+
+	return addr, &accepted
+}
+
+// exchangeAndClose sends one request line through addr, half-closes the
+// connection and returns everything received until the load balancer closes it.
+func exchangeAndClose(t *testing.T, addr string, request string) string {
+	t.Helper()
+
+	conn := dial(t, addr)
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := conn.Write([]byte(request + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn.(*net.TCPConn).CloseWrite()
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(reply)
+}
+
+func TestConnectionReuseCountsReusedConnections(t *testing.T) {
+	addr, accepted := startLineBackend(t, 0)
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		ConnectionReuse: true,
+		Backends:        []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+	b := lb.pool.GetBackendByAddress(addr)
+
+	const clients = 3
+	for i := 1; i <= clients; i++ {
+		want := fmt.Sprintf("reply-%d\n", i)
+		if got := exchangeAndClose(t, addrs[0], fmt.Sprintf("request-%d", i)); got != want {
+			t.Fatalf("client %d got %q, want %q", i, got, want)
+		}
+
+		// The connection returns to the idle pool once the client has gone
+		waitFor(t, 2*time.Second, func() bool { return b.GetIdleConns() == 1 })
+	}
+
+	if n := accepted.Load(); n != 1 {
+		t.Errorf("backend accepted %d connections, want 1", n)
+	}
+	if n := b.GetReusedConnections(); n != clients-1 {
+		t.Errorf("reused connections = %d, want %d", n, clients-1)
+	}
+}
+
+func TestConnectionReuseWaitsForSlowReply(t *testing.T) {
+	// The reply arrives well after the client has finished sending
+	addr, accepted := startLineBackend(t, 150*time.Millisecond)
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		ConnectionReuse: true,
+		Backends:        []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+	b := lb.pool.GetBackendByAddress(addr)
+
+	if got := exchangeAndClose(t, addrs[0], "first"); got != "reply-1\n" {
+		t.Fatalf("first client got %q, want its own reply", got)
+	}
+	waitFor(t, 2*time.Second, func() bool { return b.GetIdleConns() == 1 })
+
+	// A late reply must not leak to the next client of the reused connection
+	if got := exchangeAndClose(t, addrs[0], "second"); got != "reply-2\n" {
+		t.Errorf("second client got %q, want only its own reply", got)
+	}
+	if n := accepted.Load(); n != 1 {
+		t.Errorf("backend accepted %d connections, want the first one reused", n)
+	}
+}
+
+func TestConnectionReuseDropsUnansweredConnection(t *testing.T) {
+	// A backend that never replies must not have its connection pooled
+	addr := startBackend(t, func(conn net.Conn) { io.Copy(io.Discard, conn) })
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		ConnectionReuse: true,
+		IdleTimeout:     300 * time.Millisecond,
+		Backends:        []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+
+	if got := exchangeAndClose(t, addrs[0], "request"); got != "" {
+		t.Fatalf("client got %q from a silent backend", got)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := lb.pool.GetBackendByAddress(addr).GetIdleConns(); n != 0 {
+		t.Errorf("%d idle connections pooled while a reply was still owed", n)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+
+package loadbalancer
+
+import (
+	"syscall"
+)
+
+// soReusePort is SO_REUSEPORT, which the syscall package does not define for
+// every Linux architecture. MIPS uses a different value and is not supported.
+const soReusePort = 0xf
+
+// reusePortControl sets SO_REUSEPORT on a listening socket before it is bound, so
+// several processes can listen on the same address during a handoff.
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
@@ -0,0 +1,36 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+
+package loadbalancer
+
+import (
+	"context"
+	"testing"
+
+	"tcp_lb/config"
+)
+
+func TestReusePortBindsSameAddressTwice(t *testing.T) {
+	lb := &LoadBalancer{config: &config.Config{ReusePort: true}}
+	lc := lb.listenConfig()
+
+	first, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	addr := first.Addr().String()
+
+	// A second instance binds the same address during a handoff
+	second, err := lc.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("second bind with reuse_port: %v", err)
+	}
+	second.Close()
+
+	// Without the option the address is taken
+	plain := (&LoadBalancer{config: &config.Config{}}).listenConfig()
+	if ln, err := plain.Listen(context.Background(), "tcp", addr); err == nil {
+		ln.Close()
+		t.Error("second bind without reuse_port succeeded")
+	}
+}
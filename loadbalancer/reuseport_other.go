@@ -0,0 +1,13 @@
+//go:build !linux || mips || mipsle || mips64 || mips64le
+
+package loadbalancer
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reusePortControl fails because SO_REUSEPORT is only supported on Linux.
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return errors.New("reuse_port is only supported on Linux")
+}
@@ -0,0 +1,94 @@
+package loadbalancer
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// persistedState is the operator state kept in the state file across restarts.
+type persistedState struct {
+	Paused []string `json:"paused"` // Addresses of manually paused backends
+}
+
+// ToggleManualPause pauses or resumes a backend for maintenance and returns
+// whether it is now paused. The change is saved to the state file, if one is
+// configured, so the backend keeps its state after a restart.
+func (lb *LoadBalancer) ToggleManualPause(address string) bool {
+	paused := lb.pool.ToggleManualPause(address)
+	lb.saveState()
+
+	return paused
+}
+
+// loadState pauses the backends recorded in the state file. A missing file is
+// ignored, and an unreadable or corrupt one is logged and ignored.
+func (lb *LoadBalancer) loadState() {
+	if lb.config.StateFile == "" {
+		return
+	}
+
+	data, err := os.ReadFile(lb.config.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		log.Printf("Ignoring state file %s: %v", lb.config.StateFile, err)
+		return
+	}
+
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("Ignoring corrupt state file %s: %v", lb.config.StateFile, err)
+		return
+	}
+
+	alreadyPaused := lb.pool.GetManualPauses()
+	for _, address := range state.Paused {
+		if lb.pool.GetBackendByAddress(address) == nil {
+			log.Printf("State file %s: backend %s is no longer configured", lb.config.StateFile, address)
+			continue
+		}
+		if !slices.Contains(alreadyPaused, address) {
+			lb.pool.ToggleManualPause(address)
+		}
+	}
+}
+
+// saveState writes the manually paused backends to the state file, replacing
+// it atomically so a crash mid-write cannot corrupt it.
+func (lb *LoadBalancer) saveState() {
+	if lb.config.StateFile == "" {
+		return
+	}
+
+	data, err := json.MarshalIndent(persistedState{Paused: lb.pool.GetManualPauses()}, "", "  ")
+	if err != nil {
+		log.Printf("Failed to encode state: %v", err)
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(lb.config.StateFile), ".state-*")
+	if err != nil {
+		log.Printf("Failed to save state file %s: %v", lb.config.StateFile, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		log.Printf("Failed to save state file %s: %v", lb.config.StateFile, err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		log.Printf("Failed to save state file %s: %v", lb.config.StateFile, err)
+		return
+	}
+
+	if err := os.Rename(tmp.Name(), lb.config.StateFile); err != nil {
+		log.Printf("Failed to save state file %s: %v", lb.config.StateFile, err)
+	}
+}
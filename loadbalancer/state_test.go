@@ -0,0 +1,64 @@
+package loadbalancer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"tcp_lb/backend"
+	"tcp_lb/config"
+)
+
+// stateConfig returns a configuration with two backends and the given state file.
+func stateConfig(stateFile string) *config.Config {
+	return &config.Config{
+		ListenAddr: "127.0.0.1:0",
+		StateFile:  stateFile,
+		Backends: []config.BackendConfig{
+			{Address: "127.0.0.1:9001", Weight: 1},
+			{Address: "127.0.0.1:9002", Weight: 1},
+		},
+	}
+}
+
+func TestDisabledBackendStaysDisabledAfterRestart(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+
+	lb := New(stateConfig(stateFile))
+	if !lb.ToggleManualPause("127.0.0.1:9002") {
+		t.Fatal("backend was not paused")
+	}
+
+	// A new load balancer reloads the state on startup
+	restarted := New(stateConfig(stateFile))
+	if got := restarted.pool.GetManualPauses(); !reflect.DeepEqual(got, []string{"127.0.0.1:9002"}) {
+		t.Errorf("paused after restart = %v, want [127.0.0.1:9002]", got)
+	}
+	if _, err := restarted.pool.GetBackendByAddress("127.0.0.1:9002").Dial(time.Second); !errors.Is(err, backend.ErrBackendDown) {
+		t.Errorf("dialing the disabled backend after restart: err = %v, want ErrBackendDown", err)
+	}
+
+	// Resuming is persisted too
+	restarted.ToggleManualPause("127.0.0.1:9002")
+	if got := New(stateConfig(stateFile)).pool.GetManualPauses(); len(got) != 0 {
+		t.Errorf("paused after resume and restart = %v, want none", got)
+	}
+}
+
+func TestMissingOrCorruptStateFileIgnored(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing.json"), corrupt} {
+		lb := New(stateConfig(path))
+		if got := lb.pool.GetManualPauses(); len(got) != 0 {
+			t.Errorf("%s: paused = %v, want none", filepath.Base(path), got)
+		}
+	}
+}
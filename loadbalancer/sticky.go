@@ -0,0 +1,63 @@
+package loadbalancer
+
+import (
+	"errors"
+	"hash/fnv"
+	"net"
+	"strings"
+	"time"
+
+	"tcp_lb/backend"
+	"tcp_lb/proxy"
+)
+
+// firstLineTimeout is how long a client has to send its first line in sticky mode.
+const firstLineTimeout = 5 * time.Second
+
+// defaultMaxFirstLineLength is the longest first line read as a session token
+// when sticky_max_line_bytes is unset.
+const defaultMaxFirstLineLength = 4096
+
+// peekFirstLine reads the client's first line as a session token. It returns the
+// token, empty if no complete line arrived in time, and a connection that replays
+// everything read so far. A first line longer than maxLen fails with
+// proxy.ErrLineTooLong.
+func peekFirstLine(conn net.Conn, maxLen int) (string, net.Conn, error) {
+	if maxLen <= 0 {
+		maxLen = defaultMaxFirstLineLength
+	}
+
+	conn.SetReadDeadline(time.Now().Add(firstLineTimeout))
+	line, replay, err := proxy.PeekLine(conn, maxLen)
+	conn.SetReadDeadline(time.Time{})
+
+	if errors.Is(err, proxy.ErrLineTooLong) {
+		return "", replay, err
+	}
+	if err != nil {
+		return "", replay, nil
+	}
+
+	return strings.TrimRight(string(line), "\r\n"), replay, nil
+}
+
+// backendForToken picks the selectable backend with the highest rendezvous hash
+// for the token, so a token keeps mapping to the same backend while it is
+// available and only tokens on a removed backend move.
+func backendForToken(pool *backend.Pool, token string) *backend.Backend {
+	var best *backend.Backend
+	var bestScore uint64
+	for _, b := range pool.GetSelectableBackends() {
+		h := fnv.New64a()
+		h.Write([]byte(token))
+		h.Write([]byte{0})
+		h.Write([]byte(b.Address))
+
+		if score := h.Sum64(); best == nil || score > bestScore {
+			best = b
+			bestScore = score
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,64 @@
+package loadbalancer
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+// startNamedEchoBackend starts a backend that writes name to each connection and
+// then echoes what it receives.
+func startNamedEchoBackend(t *testing.T, name string) string {
+	t.Helper()
+
+	return startBackend(t, func(conn net.Conn) {
+		conn.Write([]byte(name))
+		io.Copy(conn, conn)
+	})
+}
+
+// stickyExchange sends msg on a new connection to addr and returns the name of
+// the backend it reached, failing the test unless msg is echoed back intact.
+func stickyExchange(t *testing.T, addr, msg string) string {
+	t.Helper()
+
+	conn := dial(t, addr)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1+len(msg))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if echoed := string(buf[1:]); echoed != msg {
+		t.Fatalf("echo = %q, want %q including the peeked line", echoed, msg)
+	}
+
+	return string(buf[:1])
+}
+
+func TestStickyFirstLineSameTokenSameBackend(t *testing.T) {
+	_, addrs := startLoadBalancer(t, &config.Config{
+		StickyByFirstLine: true,
+		Backends: []config.BackendConfig{
+			{Address: startNamedEchoBackend(t, "a"), Weight: 1},
+			{Address: startNamedEchoBackend(t, "b"), Weight: 1},
+			{Address: startNamedEchoBackend(t, "c"), Weight: 1},
+		},
+	})
+
+	// Round robin alone would spread these over every backend
+	first := stickyExchange(t, addrs[0], "session-42\nhello")
+	for range 5 {
+		if got := stickyExchange(t, addrs[0], "session-42\nagain"); got != first {
+			t.Errorf("token went to %s, then %s", first, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestStopWaitsForActiveConnections(t *testing.T) {
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: startEchoBackend(t), Weight: 1}},
+	})
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "hello")
+
+	const hold = 300 * time.Millisecond
+	time.AfterFunc(hold, func() { conn.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := lb.Stop(ctx); err != nil {
+		t.Fatalf("Stop = %v, want clean drain", err)
+	}
+	if elapsed := time.Since(start); elapsed < hold {
+		t.Errorf("Stop returned after %v, before the connection finished", elapsed)
+	}
+	if n := lb.activeConnections(); n != 0 {
+		t.Errorf("%d connections still active after Stop", n)
+	}
+}
+
+func TestStopForcesConnectionsAtDeadline(t *testing.T) {
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: startEchoBackend(t), Weight: 1}},
+	})
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "hello")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if err := lb.Stop(ctx); !errors.Is(err, ErrForcedShutdown) {
+		t.Errorf("Stop = %v, want ErrForcedShutdown", err)
+	}
+}
+
+func TestCancelStopsAcceptAndHealthLoops(t *testing.T) {
+	echo := startEchoBackend(t)
+	frontend := closedAddr(t)
+	lb := New(&config.Config{
+		ListenAddr:          frontend,
+		HealthCheckInterval: 20 * time.Millisecond,
+		HealthCheckTimeout:  time.Second,
+		Backends:            []config.BackendConfig{{Address: echo, Weight: 1}},
+	})
+	d := &recordingDialer{}
+	lb.SetDialer(d)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- lb.StartContext(ctx) }()
+
+	// Wait until the frontend accepts and the health loop is probing
+	waitFor(t, 2*time.Second, func() bool {
+		conn, err := net.Dial("tcp", frontend)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil && d.count(echo) >= 2
+	})
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("StartContext = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("StartContext did not return after the context was cancelled")
+	}
+
+	if conn, err := net.DialTimeout("tcp", frontend, time.Second); err == nil {
+		conn.Close()
+		t.Error("frontend still accepting after cancel")
+	}
+
+	// Health checks in flight at cancel may still land, but no new ones start
+	time.Sleep(50 * time.Millisecond)
+	checks := d.count(echo)
+	time.Sleep(100 * time.Millisecond)
+	if n := d.count(echo); n != checks {
+		t.Errorf("%d health checks ran after cancel", n-checks)
+	}
+}
@@ -0,0 +1,61 @@
+package loadbalancer
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestIdleCloseCountedOnBackend(t *testing.T) {
+	addr := startEchoBackend(t)
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		IdleTimeout: 100 * time.Millisecond,
+		Backends:    []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+	b := lb.pool.GetBackendByAddress(addr)
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "hello")
+
+	waitFor(t, 2*time.Second, func() bool {
+		idle, lifetime := b.GetTimeouts()
+		return idle == 1 && lifetime == 0
+	})
+}
+
+func TestMaxConnectionDurationCutsOffActiveTransfer(t *testing.T) {
+	addr := startEchoBackend(t)
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		MaxConnectionDuration: time.Second,
+		Backends:              []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+	b := lb.pool.GetBackendByAddress(addr)
+
+	conn := dial(t, addrs[0])
+	start := time.Now()
+
+	// Keep the transfer busy so only the absolute deadline can end it
+	buf := make([]byte, 4)
+	for time.Since(start) < 3*time.Second {
+		conn.SetDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			break
+		}
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	elapsed := time.Since(start)
+	if elapsed < 900*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("connection ended after %v, want about 1s", elapsed)
+	}
+
+	waitFor(t, time.Second, func() bool {
+		_, lifetime := b.GetTimeouts()
+		return lifetime == 1
+	})
+}
@@ -0,0 +1,211 @@
+package loadbalancer
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"tcp_lb/backend"
+	"time"
+)
+
+// defaultUDPSessionTimeout is how long a UDP flow lives without traffic when
+// UDPSessionTimeout is not configured.
+const defaultUDPSessionTimeout = 30 * time.Second
+
+// udpBufferSize fits the largest possible UDP datagram.
+const udpBufferSize = 65535
+
+// udpLoadBalancer relays datagrams for one listener, mapping each client source
+// address to a backend chosen when its flow starts.
+type udpLoadBalancer struct {
+	lb       *LoadBalancer
+	l        *listener
+	conn     *net.UDPConn
+	timeout  time.Duration
+	mu       sync.Mutex
+	sessions map[string]*udpSession // Keyed by client address
+}
+
+// udpSession is a client flow bound to a backend.
+type udpSession struct {
+	client       *net.UDPAddr
+	backend      *backend.Backend
+	backendConn  net.Conn
+	start        time.Time    // When the flow started
+	lastActivity atomic.Int64 // Unix nanoseconds of the last datagram in either direction
+}
+
+// newUDPLoadBalancer creates a UDP relay for a listener bound to conn.
+func newUDPLoadBalancer(lb *LoadBalancer, l *listener, conn *net.UDPConn) *udpLoadBalancer {
+	timeout := lb.config.UDPSessionTimeout
+	if timeout <= 0 {
+		timeout = defaultUDPSessionTimeout
+	}
+
+	return &udpLoadBalancer{
+		lb:       lb,
+		l:        l,
+		conn:     conn,
+		timeout:  timeout,
+		sessions: make(map[string]*udpSession),
+	}
+}
+
+// serve reads client datagrams and forwards them until the listener is closed.
+func (u *udpLoadBalancer) serve() {
+	buf := make([]byte, udpBufferSize)
+
+	for {
+		n, clientAddr, err := u.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			log.Printf("UDP read error: %v\n", err)
+			continue
+		}
+
+		session := u.session(clientAddr)
+		if session == nil {
+			continue
+		}
+
+		session.lastActivity.Store(time.Now().UnixNano())
+		sent, err := session.backendConn.Write(buf[:n])
+		if err != nil {
+			log.Printf("UDP write to backend %s failed: %v", session.backend.Address, err)
+			continue
+		}
+		session.backend.AddBytes(int64(sent), 0)
+		if u.lb.globalStats != nil {
+			u.lb.globalStats.AddBytesSent(int64(sent))
+		}
+	}
+}
+
+// session returns the flow for a client, starting one on a newly selected
+// backend if none exists. It returns nil when no backend can be reached.
+func (u *udpLoadBalancer) session(clientAddr *net.UDPAddr) *udpSession {
+	key := clientAddr.String()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if session, ok := u.sessions[key]; ok {
+		return session
+	}
+
+	if u.lb.draining.Load() {
+		return nil
+	}
+
+	algorithm := u.l.algorithm
+	if algorithm == nil {
+		algorithm = u.lb.currentAlgorithm()
+	}
+
+	for attempt := 0; attempt < u.l.pool.Size(); attempt++ {
+		var nextBackend *backend.Backend
+		if attempt == 0 {
+			nextBackend = nextBackendFor(algorithm, u.l.pool, clientAddr.IP.String())
+		} else {
+			nextBackend = algorithm.NextBackend(u.l.pool)
+		}
+		if nextBackend == nil {
+			log.Println("No backend available for UDP flow")
+			return nil
+		}
+
+		if nextBackend.AtCapacity() {
+			continue
+		}
+
+		backendConn, err := nextBackend.DialNetworkContext(u.lb.ctx, "udp", u.lb.config.ConnectTimeout)
+		if err != nil {
+			log.Printf("UDP backend %s dial failed: %v", nextBackend.Address, err)
+			continue
+		}
+
+		session := &udpSession{client: clientAddr, backend: nextBackend, backendConn: backendConn, start: time.Now()}
+		session.lastActivity.Store(session.start.UnixNano())
+		u.sessions[key] = session
+
+		nextBackend.AddConnection(backendConn)
+		if u.lb.globalStats != nil {
+			u.lb.globalStats.IncrementConnections()
+		}
+
+		go u.relayReplies(session)
+		return session
+	}
+
+	return nil
+}
+
+// relayReplies forwards backend datagrams to the client until the flow has been
+// idle for the session timeout, has exceeded the maximum connection duration, or
+// its backend connection is closed.
+func (u *udpLoadBalancer) relayReplies(session *udpSession) {
+	defer u.endSession(session)
+
+	buf := make([]byte, udpBufferSize)
+	for {
+		session.backendConn.SetReadDeadline(u.deadline(session))
+
+		n, err := session.backendConn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			// Client datagrams may have extended the flow since the deadline was set
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if time.Now().Before(u.deadline(session)) {
+					continue
+				}
+				if maxDuration := u.lb.config.MaxConnectionDuration; maxDuration > 0 && time.Since(session.start) >= maxDuration {
+					session.backend.RecordLifetimeTimeout()
+				}
+			}
+			return
+		}
+
+		session.lastActivity.Store(time.Now().UnixNano())
+		received, err := u.conn.WriteToUDP(buf[:n], session.client)
+		if err != nil {
+			log.Printf("UDP write to client %s failed: %v", session.client, err)
+			continue
+		}
+		session.backend.AddBytes(0, int64(received))
+		if u.lb.globalStats != nil {
+			u.lb.globalStats.AddBytesReceived(int64(received))
+		}
+	}
+}
+
+// deadline returns when the flow ends: after the session timeout without
+// traffic, or once it reaches the maximum connection duration.
+func (u *udpLoadBalancer) deadline(session *udpSession) time.Time {
+	deadline := time.Unix(0, session.lastActivity.Load()).Add(u.timeout)
+
+	if maxDuration := u.lb.config.MaxConnectionDuration; maxDuration > 0 {
+		if lifetimeDeadline := session.start.Add(maxDuration); lifetimeDeadline.Before(deadline) {
+			deadline = lifetimeDeadline
+		}
+	}
+
+	return deadline
+}
+
+// endSession removes an expired flow and releases its backend connection.
+func (u *udpLoadBalancer) endSession(session *udpSession) {
+	u.mu.Lock()
+	delete(u.sessions, session.client.String())
+	u.mu.Unlock()
+
+	session.backendConn.Close()
+	session.backend.RemoveConnection(session.backendConn)
+	if u.lb.globalStats != nil {
+		u.lb.globalStats.DecrementActiveConnections()
+	}
+}
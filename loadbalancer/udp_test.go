@@ -0,0 +1,69 @@
+package loadbalancer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+// startUDPEchoBackend starts a UDP server that sends every datagram back to its
+// sender, prefixed with "echo:".
+func startUDPEchoBackend(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, udpBufferSize)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteToUDP(append([]byte("echo:"), buf[:n]...), addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestUDPDatagramRoundTrip(t *testing.T) {
+	addr := startUDPEchoBackend(t)
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Protocol: config.ProtocolUDP,
+		Backends: []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+
+	client, err := net.Dial("udp", addrs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(2 * time.Second))
+
+	// Two datagrams from the same client share one session
+	for _, msg := range []string{"first", "second"} {
+		if _, err := client.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+
+		buf := make([]byte, udpBufferSize)
+		n, err := client.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != "echo:"+msg {
+			t.Errorf("reply = %q, want %q", got, "echo:"+msg)
+		}
+	}
+
+	if _, _, _, total := lb.pool.GetBackendByAddress(addr).GetStats(); total != 1 {
+		t.Errorf("backend total connections = %d, want 1 session", total)
+	}
+}
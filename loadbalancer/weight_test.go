@@ -0,0 +1,62 @@
+package loadbalancer
+
+import (
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+)
+
+func TestZeroWeightDrainsConnections(t *testing.T) {
+	addr := startEchoBackend(t)
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		DrainOnZeroWeight:      true,
+		ZeroWeightDrainTimeout: 100 * time.Millisecond,
+		Backends: []config.BackendConfig{
+			{Address: addr, Weight: 1},
+			{Address: startEchoBackend(t), Weight: 1},
+		},
+	})
+	b := lb.pool.GetBackendByAddress(addr)
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "hello")
+	if b.GetActiveConnections() != 1 {
+		t.Fatal("first connection did not go to the backend")
+	}
+
+	if err := lb.SetBackendWeight(addr, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Draining starts in the background, and the existing connection is closed
+	// once the drain window passes
+	waitFor(t, time.Second, b.IsDraining)
+	waitFor(t, 2*time.Second, func() bool { return b.GetActiveConnections() == 0 })
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("connection still open after the drain window")
+	}
+
+	if err := lb.SetBackendWeight(addr, 1); err != nil {
+		t.Fatal(err)
+	}
+	if b.IsDraining() {
+		t.Error("backend still draining after a positive weight")
+	}
+}
+
+func TestZeroWeightWithoutDrainKeepsConnections(t *testing.T) {
+	addr := startEchoBackend(t)
+	lb, addrs := startLoadBalancer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+
+	conn := dial(t, addrs[0])
+	roundTrip(t, conn, "hello")
+
+	if err := lb.SetBackendWeight(addr, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	roundTrip(t, conn, "still open")
+}
@@ -0,0 +1,149 @@
+// Package loadgen opens client connections against the load balancer and reports
+// which backend each one landed on, for checking algorithm behaviour under load.
+package loadgen
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tcp_lb/backend"
+)
+
+// defaultTimeout bounds dialing and reading the banner when Options.Timeout is not set.
+const defaultTimeout = 5 * time.Second
+
+// Options controls a load generation run.
+type Options struct {
+	Addr        string        // Load balancer address to connect to
+	Concurrency int           // Maximum connections open at once, 0 means 1
+	Rate        float64       // New connections per second, 0 opens them as fast as Concurrency allows
+	Duration    time.Duration // How long to keep opening new connections
+	Hold        time.Duration // How long each connection stays open after its banner is read
+	Timeout     time.Duration // Limit on dialing and reading the banner, 0 means 5 seconds
+}
+
+// Result summarises a load generation run.
+type Result struct {
+	Connections int            // Connections attempted
+	Failed      int            // Connections that could not be dialed or sent no banner
+	Backends    map[string]int // Successful connections per backend address
+	Elapsed     time.Duration  // How long the run took, including held connections
+}
+
+// Run opens connections to opts.Addr until opts.Duration has passed or ctx is done,
+// then waits for open connections to finish and returns the results. The backend
+// of each connection is read from the welcome banner the echo backends send.
+func Run(ctx context.Context, opts Options) Result {
+	concurrency := max(opts.Concurrency, 1)
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	var tick <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	result := Result{Backends: make(map[string]int)}
+	var mu sync.Mutex // Protects result while connections finish
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	start := time.Now()
+
+	for {
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+			}
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			address, err := connect(opts.Addr, timeout, opts.Hold)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			result.Connections++
+			if err != nil {
+				result.Failed++
+				return
+			}
+			result.Backends[address]++
+		}()
+	}
+
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+
+	return result
+}
+
+// connect opens one connection, reads which backend it reached from the banner,
+// and holds it open for hold before closing it.
+func connect(addr string, timeout time.Duration, hold time.Duration) (string, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	banner, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	address, ok := backend.ParseWelcome(banner)
+	if !ok {
+		address = strings.TrimSpace(banner)
+	}
+
+	time.Sleep(hold)
+
+	return address, nil
+}
+
+// WriteSummary writes the connection counts and each backend's share to w,
+// sorted by backend address.
+func (r Result) WriteSummary(w io.Writer) {
+	succeeded := r.Connections - r.Failed
+	fmt.Fprintf(w, "Connections: %d (%d succeeded, %d failed) in %s\n",
+		r.Connections, succeeded, r.Failed, r.Elapsed.Round(time.Millisecond))
+
+	addresses := make([]string, 0, len(r.Backends))
+	for address := range r.Backends {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		count := r.Backends[address]
+		fmt.Fprintf(w, "  %-24s %6d  %5.1f%%\n", address, count, float64(count)/float64(succeeded)*100)
+	}
+}
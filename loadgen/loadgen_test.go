@@ -0,0 +1,124 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"tcp_lb/backend"
+	"tcp_lb/config"
+	"tcp_lb/loadbalancer"
+)
+
+// startBannerBackend starts a backend that sends the welcome banner and then waits
+// for the client to close. It returns the backend's address.
+func startBannerBackend(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	addr := ln.Addr().String()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte(backend.FormatWelcome(addr)))
+				conn.Read(make([]byte, 1))
+			}()
+		}
+	}()
+
+	return addr
+}
+
+// startLoadBalancer serves a round robin load balancer over backends and returns
+// its address. It stops when the test ends.
+func startLoadBalancer(t *testing.T, backends ...string) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	cfg := &config.Config{ListenAddr: addr, ConnectTimeout: time.Second}
+	for _, b := range backends {
+		cfg.Backends = append(cfg.Backends, config.BackendConfig{Address: b, Weight: 1})
+	}
+	lb := loadbalancer.New(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lb.StartContext(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// Wait for the listener to be bound
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("load balancer did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return addr
+}
+
+func TestRunReportsDistribution(t *testing.T) {
+	a, b := startBannerBackend(t), startBannerBackend(t)
+	lbAddr := startLoadBalancer(t, a, b)
+
+	result := Run(context.Background(), Options{
+		Addr:        lbAddr,
+		Concurrency: 4,
+		Rate:        100,
+		Duration:    300 * time.Millisecond,
+		Timeout:     time.Second,
+	})
+
+	if result.Connections < 10 {
+		t.Fatalf("only %d connections in 300ms at 100/s", result.Connections)
+	}
+	if result.Failed != 0 {
+		t.Errorf("%d of %d connections failed", result.Failed, result.Connections)
+	}
+	if total := result.Backends[a] + result.Backends[b]; total != result.Connections-result.Failed {
+		t.Errorf("backend counts %v add up to %d, want %d", result.Backends, total, result.Connections-result.Failed)
+	}
+	// Round robin alternates, so the counts differ by at most one
+	if diff := result.Backends[a] - result.Backends[b]; diff < -1 || diff > 1 {
+		t.Errorf("uneven round robin distribution %v", result.Backends)
+	}
+
+	var summary bytes.Buffer
+	result.WriteSummary(&summary)
+	for _, addr := range []string{a, b} {
+		if !strings.Contains(summary.String(), addr) {
+			t.Errorf("summary missing backend %s:\n%s", addr, summary.String())
+		}
+	}
+}
@@ -1,16 +1,77 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"time"
 
+	"tcp_lb/config"
+	"tcp_lb/headless"
+	"tcp_lb/loadgen"
 	"tcp_lb/tui"
 )
 
 func main() {
-	if err := tui.Run(); err != nil {
+	configPath := flag.String("config", "config.json",
+		"path to the JSON configuration file, or empty to configure headless mode from the environment only")
+	validateOnly := flag.Bool("validate", false, "load and validate the configuration, then exit")
+	mode := flag.String("mode", "tui",
+		"run with the interactive dashboard (tui), without it (headless), or generate load against a running load balancer (loadgen)")
+	loadAddr := flag.String("addr", "localhost:8080", "loadgen: load balancer address to connect to")
+	loadConns := flag.Int("conns", 10, "loadgen: maximum concurrent connections")
+	loadRate := flag.Float64("rate", 0, "loadgen: new connections per second, 0 for as fast as -conns allows")
+	loadDuration := flag.Duration("duration", 10*time.Second, "loadgen: how long to keep opening connections")
+	loadHold := flag.Duration("hold", 0, "loadgen: how long each connection stays open")
+	flag.Parse()
+
+	if *validateOnly {
+		load := func() (*config.Config, error) { return config.LoadConfig(*configPath) }
+		if *configPath == "" {
+			load = config.LoadConfigFromEnv
+		}
+		if _, err := load(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: configuration is valid\n", *configPath)
+		return
+	}
+
+	var run func(string) error
+	switch *mode {
+	case "tui":
+		run = tui.Run
+	case "headless":
+		run = headless.Run
+	case "loadgen":
+		run = func(string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			result := loadgen.Run(ctx, loadgen.Options{
+				Addr:        *loadAddr,
+				Concurrency: *loadConns,
+				Rate:        *loadRate,
+				Duration:    *loadDuration,
+				Hold:        *loadHold,
+			})
+			result.WriteSummary(os.Stdout)
+			return nil
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown mode %q, expected tui, headless or loadgen\n", *mode)
+		os.Exit(2)
+	}
+
+	if err := run(*configPath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("Goodbye!")
+
+	if *mode == "tui" {
+		fmt.Println("Goodbye!")
+	}
 }
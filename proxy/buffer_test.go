@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// writerOnly hides any ReaderFrom implementation of the wrapped writer, so the
+// copy goes through the buffer.
+type writerOnly struct {
+	io.Writer
+}
+
+// benchmarkPayload is the data copied per benchmark iteration, a short exchange
+// typical of high connection churn.
+var benchmarkPayload = bytes.Repeat([]byte("x"), 4*1024)
+
+// BenchmarkCopyAllocating copies the way io.Copy does, with a new 32KB buffer per copy.
+func BenchmarkCopyAllocating(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		buf := make([]byte, DefaultBufferSize)
+		if _, err := io.CopyBuffer(writerOnly{io.Discard}, readerOnly{bytes.NewReader(benchmarkPayload)}, buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCopyPooled copies through a pooled buffer, as the proxy does.
+func BenchmarkCopyPooled(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := copyPooled(writerOnly{io.Discard}, bytes.NewReader(benchmarkPayload), DefaultBufferSize); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
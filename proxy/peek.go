@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+)
+
+// ErrLineTooLong is returned by PeekLine when no newline arrives within the limit.
+var ErrLineTooLong = errors.New("line exceeds size limit")
+
+// minPeekSize is the smallest buffer bufio allows; smaller limits are raised to it.
+const minPeekSize = 16
+
+// replayConn is a connection whose first bytes have already been read; reads
+// return those bytes before continuing with the connection.
+type replayConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+// Read reads the already consumed bytes first, then from the connection.
+func (c *replayConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// CloseWrite half-closes the underlying connection when it supports it.
+func (c *replayConn) CloseWrite() error {
+	if cw, ok := c.Conn.(closeWriter); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// PeekLine reads conn up to and including the first newline, buffering at most
+// maxLen bytes so a client cannot make it allocate more. It returns the line
+// and a connection that replays everything read before continuing with conn.
+// Without a newline in the first maxLen bytes it returns ErrLineTooLong; read
+// errors, such as deadlines set on conn, are returned as they occur. The replay
+// connection is valid in either case.
+func PeekLine(conn net.Conn, maxLen int) ([]byte, net.Conn, error) {
+	reader := bufio.NewReaderSize(conn, max(maxLen, minPeekSize))
+
+	line, err := reader.ReadSlice('\n')
+	if len(line) > maxLen {
+		// bufio's minimum size let more than maxLen through
+		err = bufio.ErrBufferFull
+	}
+	if errors.Is(err, bufio.ErrBufferFull) {
+		err = ErrLineTooLong
+	}
+
+	peeked := bytes.Clone(line)
+	replay := &replayConn{Conn: conn, reader: io.MultiReader(bytes.NewReader(peeked), reader)}
+
+	return peeked, replay, err
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestPeekLineReplaysPeekedBytes(t *testing.T) {
+	client, server := tcpPair(t)
+
+	go func() {
+		client.Write([]byte("token-1\nrest of the stream"))
+		client.Close()
+	}()
+
+	line, replay, err := PeekLine(server, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(line) != "token-1\n" {
+		t.Errorf("line = %q, want %q", line, "token-1\n")
+	}
+
+	replay.SetReadDeadline(time.Now().Add(2 * time.Second))
+	all, err := io.ReadAll(replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(all) != "token-1\nrest of the stream" {
+		t.Errorf("replay = %q, want the whole stream", all)
+	}
+}
+
+func TestPeekLineRejectsOversizedLine(t *testing.T) {
+	client, server := tcpPair(t)
+
+	// Far more than the limit, with no newline
+	payload := bytes.Repeat([]byte("x"), 8<<20)
+	go func() {
+		client.Write(payload)
+		client.Close()
+	}()
+
+	const limit = 1024
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	server.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, _, err := PeekLine(server, limit)
+	runtime.ReadMemStats(&after)
+
+	if !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("err = %v, want ErrLineTooLong", err)
+	}
+	if len(line) > limit {
+		t.Errorf("peeked %d bytes, want at most %d", len(line), limit)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("allocated %d bytes for an oversized line, want well under the %d sent", allocated, len(payload))
+	}
+}
+
+func TestPeekLineSmallLimitRaisedToMinimum(t *testing.T) {
+	client, server := tcpPair(t)
+
+	go client.Write([]byte("0123456789abcdefghij\n"))
+
+	// bufio's minimum buffer lets more than the limit in, which is still rejected
+	server.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := PeekLine(server, 4); !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("err = %v, want ErrLineTooLong", err)
+	}
+}
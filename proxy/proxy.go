@@ -1,87 +1,415 @@
 package proxy
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Proxy copies data bidirectionally between client and backend connections.
+// When one direction finishes, the destination's write side is half-closed so
+// the peer sees EOF and the other direction can finish too.
 func Proxy(client net.Conn, backend net.Conn) error {
-	var wg sync.WaitGroup
-	wg.Add(2)
-	errCh := make(chan error, 2)
-
-	// Client -> Backend
-	go func() {
-		defer wg.Done()
-		_, err := io.Copy(backend, client)
-		// When client closes, close backend write side to unblock the backend server
-		if tcpConn, ok := backend.(*net.TCPConn); ok {
-			tcpConn.CloseWrite()
-		}
-		if err != nil && err != io.EOF {
-			errCh <- err
-		}
-	}()
-
-	// Backend -> Client
-	go func() {
-		defer wg.Done()
-		_, err := io.Copy(client, backend)
-		// When backend closes, close client write side
-		if tcpConn, ok := client.(*net.TCPConn); ok {
-			tcpConn.CloseWrite()
-		}
-		if err != nil && err != io.EOF {
-			errCh <- err
-		}
-	}()
+	_, _, err := proxyReaders(client, backend, client, backend, backend, client, Options{})
+	return err
+}
 
-	wg.Wait()
-	close(errCh)
+// closeWriter is implemented by connections that support half-closing, such as
+// *net.TCPConn, *net.UnixConn and *tls.Conn.
+type closeWriter interface {
+	CloseWrite() error
+}
 
-	for err := range errCh {
-		return err
+// closeWrite half-closes conn's write side if it supports it, signalling EOF to
+// the peer while still allowing reads.
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(closeWriter); ok {
+		cw.CloseWrite()
 	}
-
-	return nil
 }
 
 type countingWriter struct {
-	w     io.Writer
-	count int64
+	w       io.Writer
+	count   int64
+	err     error         // The last write error, telling write failures from read failures
+	onWrite func(n int64) // Called with each write's byte count, if set
 }
 
 func (cw *countingWriter) Write(p []byte) (int, error) {
 	n, err := cw.w.Write(p)
 	cw.count += int64(n)
+	cw.err = err
+	if cw.onWrite != nil && n > 0 {
+		cw.onWrite(int64(n))
+	}
 	return n, err
 }
 
 // ProxyWithStats proxies connections while tracking bytes transferred.
 func ProxyWithStats(client net.Conn, backend net.Conn) (bytesSent int64, bytesReceived int64, err error) {
-	toBackend := &countingWriter{w: backend}
-	toClient := &countingWriter{w: client}
+	return proxyReaders(client, backend, client, backend, backend, client, Options{})
+}
+
+// ProxyWithIdleTimeout proxies connections while tracking bytes transferred, closing
+// both connections once no data has moved in either direction for the idle window.
+func ProxyWithIdleTimeout(client net.Conn, backend net.Conn, idle time.Duration) (bytesSent int64, bytesReceived int64, err error) {
+	return ProxyWithDeadlines(client, backend, idle, 0)
+}
+
+// ProxyWithDeadlines proxies connections while tracking bytes transferred, enforcing
+// both an idle timeout and an absolute lifetime, whichever fires first. A zero value
+// disables the corresponding limit. The returned error is ErrIdleTimeout or
+// ErrLifetimeExceeded when the connection was closed by one of the limits.
+func ProxyWithDeadlines(client net.Conn, backend net.Conn, idle time.Duration, lifetime time.Duration) (bytesSent int64, bytesReceived int64, err error) {
+	return ProxyWithOptions(client, backend, Options{IdleTimeout: idle, Lifetime: lifetime})
+}
+
+// Options controls how ProxyWithOptions handles a proxied connection.
+type Options struct {
+	IdleTimeout time.Duration // Close once no data moves in either direction for this long, 0 for no limit
+	Lifetime    time.Duration // Close once the connection has been open this long, 0 for no limit
+
+	// ReadTimeout closes the connection once either side has sent nothing for this
+	// long, even while the other side is active; 0 for no limit. WriteTimeout closes
+	// it once a write to either side blocks this long, e.g. because the peer stopped
+	// reading; 0 for no limit.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// CloseOnEOF tears down both directions as soon as either side reaches EOF,
+	// suiting request/response protocols. By default the proxy half-closes and
+	// waits for both directions to finish.
+	CloseOnEOF bool
+
+	// BufferSize is the size of the pooled buffer used for each copy direction,
+	// 0 for DefaultBufferSize.
+	BufferSize int
+
+	// BytesPerSecond caps the rate data is written to each side, so one bulk
+	// transfer cannot saturate a link; 0 for no limit.
+	BytesPerSecond int64
+
+	// OnTransfer is called as data is written, with the bytes just sent to the
+	// backend or received from it, so traffic can be counted while the
+	// connection is open. Both directions call it, possibly concurrently. The
+	// calls add up to the totals the proxy returns.
+	OnTransfer func(sent, received int64)
+
+	settleBackend func() // Set by ProxyReusable to settle the backend for reuse instead of half-closing it
+}
+
+// ProxyWithOptions proxies connections while tracking bytes transferred, applying
+// the deadlines and EOF handling in opts. The returned error is ErrIdleTimeout,
+// ErrLifetimeExceeded, ErrReadTimeout or ErrWriteTimeout when the connection was
+// closed by one of the limits.
+func ProxyWithOptions(client net.Conn, backend net.Conn, opts Options) (bytesSent int64, bytesReceived int64, err error) {
+	if opts.IdleTimeout <= 0 && opts.Lifetime <= 0 && opts.ReadTimeout <= 0 && opts.WriteTimeout <= 0 {
+		return proxyReaders(client, backend, client, backend, backend, client, opts)
+	}
+
+	state := newDeadlineState(opts)
+	fromClient := &deadlineReader{conn: client, peer: backend, state: state}
+	fromBackend := &deadlineReader{conn: backend, peer: client, state: state, backend: true}
+	toBackend := &deadlineWriter{conn: backend, peer: client, state: state, backend: true}
+	toClient := &deadlineWriter{conn: client, peer: backend, state: state}
+
+	bytesSent, bytesReceived, err = proxyReaders(client, backend, fromClient, fromBackend, toBackend, toClient, opts)
+
+	// Report the limit that fired rather than the resulting closed-connection error
+	if state.closeErr != nil {
+		err = state.closeErr
+	}
+
+	return bytesSent, bytesReceived, err
+}
+
+// ErrIdleTimeout is returned when a proxied connection is closed for being idle.
+var ErrIdleTimeout = errors.New("connection idle timeout")
+
+// ErrLifetimeExceeded is returned when a proxied connection is closed for exceeding its maximum lifetime.
+var ErrLifetimeExceeded = errors.New("connection lifetime exceeded")
+
+// ErrReadTimeout is returned when a proxied connection is closed because one side sent nothing for too long.
+var ErrReadTimeout = errors.New("connection read timeout")
+
+// ErrWriteTimeout is returned when a proxied connection is closed because a write to one side stalled.
+var ErrWriteTimeout = errors.New("connection write timeout")
+
+// ErrBackendFailed wraps errors from the backend side of a proxied connection: a
+// failed read from or write to the backend, or the backend timing out a read or
+// write. Errors on the client side are returned without it.
+var ErrBackendFailed = errors.New("backend failed")
+
+// backendError marks err as coming from the backend side.
+func backendError(err error) error {
+	if err == nil || errors.Is(err, ErrBackendFailed) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrBackendFailed, err)
+}
+
+// deadlineState is the deadline bookkeeping shared by both directions of a proxied connection.
+type deadlineState struct {
+	idle         time.Duration // How long both directions may be idle, 0 for no limit
+	lifetime     time.Duration // How long the connection may stay open, 0 for no limit
+	readTimeout  time.Duration // How long each side may send nothing, 0 for no limit
+	writeTimeout time.Duration // How long a single write may block, 0 for no limit
+	start        time.Time     // When proxying started
+	lastActivity atomic.Int64  // Unix nanoseconds of the last read in either direction
+	closeOnce    sync.Once
+	closeErr     error        // Which limit closed the connection, set within closeOnce
+	clientDone   atomic.Int64 // Unix nanoseconds the client finished sending, 0 before then, for connection reuse
+	lastRequest  atomic.Int64 // Unix nanoseconds data was last read from the client, 0 before the first read
+	lastReply    atomic.Int64 // Unix nanoseconds data was last read from the backend, 0 before the first read
+}
+
+// newDeadlineState starts deadline bookkeeping for the limits in opts.
+func newDeadlineState(opts Options) *deadlineState {
+	state := &deadlineState{
+		idle:         opts.IdleTimeout,
+		lifetime:     opts.Lifetime,
+		readTimeout:  opts.ReadTimeout,
+		writeTimeout: opts.WriteTimeout,
+		start:        time.Now(),
+	}
+	state.lastActivity.Store(state.start.UnixNano())
+
+	return state
+}
+
+// closeFor closes both connections because of the limit reason, recording the
+// first limit to fire.
+func (ds *deadlineState) closeFor(reason error, conn net.Conn, peer net.Conn) {
+	ds.closeOnce.Do(func() {
+		ds.closeErr = reason
+		conn.Close()
+		peer.Close()
+	})
+}
+
+// deadline returns the nearer of the idle and lifetime deadlines.
+func (ds *deadlineState) deadline() time.Time {
+	var deadline time.Time
+
+	if ds.idle > 0 {
+		deadline = time.Unix(0, ds.lastActivity.Load()).Add(ds.idle)
+	}
+
+	if ds.lifetime > 0 {
+		lifetimeDeadline := ds.start.Add(ds.lifetime)
+		if deadline.IsZero() || lifetimeDeadline.Before(deadline) {
+			deadline = lifetimeDeadline
+		}
+	}
+
+	return deadline
+}
+
+// deadlineReader reads from a connection, extending its read deadline while data
+// flows in either direction of the proxied connection.
+type deadlineReader struct {
+	conn        net.Conn
+	peer        net.Conn // The other side, closed together with conn when a limit fires
+	state       *deadlineState
+	lastRead    time.Time     // When data was last read from conn, zero before the first read
+	activity    *atomic.Int64 // Also records when data was last read, nil when not tracked
+	backend     bool          // Whether conn is the backend, so its read timeouts are backend failures
+	settles     bool          // Whether conn is a backend settled for reuse once the client is done
+	readStopped bool          // Whether reading stopped with the backend settled at a clean boundary
+}
+
+// deadline returns the shared deadline, brought forward to when this side's read
+// timeout expires, or to the end of settling, if that is sooner.
+func (dr *deadlineReader) deadline() time.Time {
+	deadline := dr.state.deadline()
+
+	if dr.state.readTimeout > 0 {
+		if readDeadline := dr.readSince().Add(dr.state.readTimeout); deadline.IsZero() || readDeadline.Before(deadline) {
+			deadline = readDeadline
+		}
+	}
+
+	if dr.settling() {
+		if settleDeadline := dr.state.settleDeadline(); deadline.IsZero() || settleDeadline.Before(deadline) {
+			deadline = settleDeadline
+		}
+	}
+
+	return deadline
+}
+
+// settling reports whether this reader is a backend being settled for reuse.
+func (dr *deadlineReader) settling() bool {
+	return dr.settles && dr.state.clientDone.Load() != 0
+}
+
+// readSince returns when data was last read from conn, or when proxying started.
+func (dr *deadlineReader) readSince() time.Time {
+	if dr.lastRead.IsZero() {
+		return dr.state.start
+	}
+	return dr.lastRead
+}
+
+func (dr *deadlineReader) Read(p []byte) (int, error) {
+	for {
+		settling := dr.settling()
+		dr.conn.SetReadDeadline(dr.deadline())
+
+		// The client may have finished after the deadline was computed, in which
+		// case its wake-up was overwritten
+		if !settling && dr.settling() {
+			continue
+		}
+
+		n, err := dr.conn.Read(p)
+		if n > 0 {
+			dr.lastRead = time.Now()
+			dr.state.lastActivity.Store(dr.lastRead.UnixNano())
+			if dr.activity != nil {
+				dr.activity.Store(dr.lastRead.UnixNano())
+			}
+		}
+
+		var netErr net.Error
+		if n == 0 && errors.As(err, &netErr) && netErr.Timeout() {
+			if dr.settling() && !time.Now().Before(dr.state.settleDeadline()) {
+				dr.readStopped = !dr.state.awaitingReply()
+				return 0, errReadsStopped
+			}
+
+			// The other direction may have been active since the deadline was set
+			if time.Now().Before(dr.deadline()) {
+				continue
+			}
+
+			reason := ErrIdleTimeout
+			if dr.state.readTimeout > 0 && !time.Now().Before(dr.readSince().Add(dr.state.readTimeout)) {
+				reason = ErrReadTimeout
+			}
+			if dr.state.lifetime > 0 && time.Since(dr.state.start) >= dr.state.lifetime {
+				reason = ErrLifetimeExceeded
+			}
+			if reason == ErrReadTimeout && dr.backend {
+				reason = backendError(reason)
+			}
+
+			dr.state.closeFor(reason, dr.conn, dr.peer)
+			return 0, reason
+		}
+
+		return n, err
+	}
+}
+
+// deadlineWriter writes to a connection, closing both sides of the proxied
+// connection when a single write blocks longer than the write timeout.
+type deadlineWriter struct {
+	conn    net.Conn
+	peer    net.Conn // The other side, closed together with conn when the write times out
+	state   *deadlineState
+	backend bool // Whether conn is the backend, so its write timeouts are backend failures
+}
+
+func (dw *deadlineWriter) Write(p []byte) (int, error) {
+	if dw.state.writeTimeout <= 0 {
+		return dw.conn.Write(p)
+	}
+
+	dw.conn.SetWriteDeadline(time.Now().Add(dw.state.writeTimeout))
+	n, err := dw.conn.Write(p)
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		reason := ErrWriteTimeout
+		if dw.backend {
+			reason = backendError(reason)
+		}
+		dw.state.closeFor(reason, dw.conn, dw.peer)
+		return n, reason
+	}
+
+	return n, err
+}
+
+// proxyReaders copies data between client and backend, reading and writing through
+// the given readers and writers with pooled buffers, and returns the bytes written
+// to each side. With opts.CloseOnEOF set, both connections are closed as soon as
+// either direction finishes, and with opts.BytesPerSecond set each direction is
+// throttled to that rate.
+func proxyReaders(client net.Conn, backend net.Conn, fromClient io.Reader, fromBackend io.Reader, writeBackend io.Writer, writeClient io.Writer, opts Options) (bytesSent int64, bytesReceived int64, err error) {
+	if opts.BytesPerSecond > 0 {
+		writeBackend = newThrottledWriter(writeBackend, opts.BytesPerSecond)
+		writeClient = newThrottledWriter(writeClient, opts.BytesPerSecond)
+	}
+
+	toBackend := &countingWriter{w: writeBackend}
+	toClient := &countingWriter{w: writeClient}
+	if opts.OnTransfer != nil {
+		toBackend.onWrite = func(n int64) { opts.OnTransfer(n, 0) }
+		toClient.onWrite = func(n int64) { opts.OnTransfer(0, n) }
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(2)
 
 	errCh := make(chan error, 2)
 
+	var tornDown atomic.Bool
+	var teardownOnce sync.Once
+	teardown := func() {
+		teardownOnce.Do(func() {
+			tornDown.Store(true)
+			client.Close()
+			backend.Close()
+		})
+	}
+
 	go func() {
 		defer wg.Done()
-		_, copyErr := io.Copy(toBackend, client)
-		if copyErr != nil {
+		_, copyErr := copyPooled(toBackend, fromClient, opts.BufferSize)
+		switch {
+		case opts.settleBackend == nil:
+			// When client closes, close backend write side to unblock the backend server
+			closeWrite(backend)
+		case copyErr == nil:
+			// Keep the backend open for reuse, relaying the rest of its reply
+			opts.settleBackend()
+		default:
+			// A failed client leaves the backend in an unknown state
+			teardown()
+		}
+		if opts.CloseOnEOF {
+			teardown()
+		}
+		// Errors caused by our own teardown are expected
+		if copyErr != nil && !(tornDown.Load() && errors.Is(copyErr, net.ErrClosed)) {
+			if toBackend.err != nil {
+				copyErr = backendError(copyErr)
+			}
 			errCh <- copyErr
 		}
 	}()
 
 	go func() {
 		defer wg.Done()
-		_, copyErr := io.Copy(toClient, backend)
-		if copyErr != nil {
+		_, copyErr := copyPooled(toClient, fromBackend, opts.BufferSize)
+		if errors.Is(copyErr, errReadsStopped) {
+			copyErr = nil
+		}
+		// When backend closes, close client write side
+		closeWrite(client)
+		if opts.CloseOnEOF {
+			teardown()
+		}
+		if copyErr != nil && !(tornDown.Load() && errors.Is(copyErr, net.ErrClosed)) {
+			if toClient.err == nil {
+				copyErr = backendError(copyErr)
+			}
 			errCh <- copyErr
 		}
 	}()
@@ -109,9 +437,7 @@ func copyData(dst net.Conn, src net.Conn, wg *sync.WaitGroup, errCh chan<- error
 	}
 
 	// Signal EOF to the other end
-	if tcpConn, ok := dst.(*net.TCPConn); ok {
-		tcpConn.CloseWrite()
-	}
+	closeWrite(dst)
 }
 
 // copyDataWithBuffer copies data with a custom buffer size.
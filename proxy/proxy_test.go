@@ -0,0 +1,456 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tcpPair returns the two ends of a loopback TCP connection. Both are closed
+// when the test ends.
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+
+	dialed, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, ok := <-accepted
+	if !ok {
+		t.Fatal("accept failed")
+	}
+
+	t.Cleanup(func() {
+		dialed.Close()
+		other.Close()
+	})
+
+	return dialed, other
+}
+
+// proxyConns sets up a client and a backend connection for the proxy to join,
+// returning the test's end of the client, the proxy's ends of the client and
+// backend, and the test's end of the backend.
+func proxyConns(t *testing.T) (client, proxyClient, proxyBackend, backend net.Conn) {
+	t.Helper()
+
+	client, proxyClient = tcpPair(t)
+	proxyBackend, backend = tcpPair(t)
+
+	return client, proxyClient, proxyBackend, backend
+}
+
+// echo copies everything conn receives back to it until EOF.
+func echo(conn net.Conn) {
+	io.Copy(conn, conn)
+}
+
+// roundTrip writes msg to conn and reads it back, failing the test if it differs.
+func roundTrip(t *testing.T, conn net.Conn, msg string) {
+	t.Helper()
+
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != msg {
+		t.Fatalf("echo = %q, want %q", buf, msg)
+	}
+}
+
+// proxyResult is what a proxy function returned.
+type proxyResult struct {
+	sent     int64
+	received int64
+	err      error
+}
+
+// waitResult waits for a proxy run to finish, failing the test after timeout.
+func waitResult(t *testing.T, done <-chan proxyResult, timeout time.Duration) proxyResult {
+	t.Helper()
+
+	select {
+	case res := <-done:
+		return res
+	case <-time.After(timeout):
+		t.Fatal("proxy did not return")
+		return proxyResult{}
+	}
+}
+
+func TestIdleConnectionReaped(t *testing.T) {
+	client, proxyClient, proxyBackend, backend := proxyConns(t)
+	go echo(backend)
+
+	const idle = 150 * time.Millisecond
+	done := make(chan proxyResult, 1)
+	go func() {
+		sent, received, err := ProxyWithIdleTimeout(proxyClient, proxyBackend, idle)
+		done <- proxyResult{sent, received, err}
+	}()
+
+	// Traffic spaced closer than the idle window keeps the connection open
+	// well past a single window
+	var lastTraffic time.Time
+	for range 4 {
+		roundTrip(t, client, "ping")
+		lastTraffic = time.Now()
+		time.Sleep(idle / 2)
+	}
+
+	res := waitResult(t, done, 2*time.Second)
+	if !errors.Is(res.err, ErrIdleTimeout) {
+		t.Errorf("err = %v, want ErrIdleTimeout", res.err)
+	}
+	// The proxy saw the last reply just before the client did, so allow some slack
+	if quiet := time.Since(lastTraffic); quiet < idle*3/4 {
+		t.Errorf("reaped %v after the last traffic, before the idle window passed", quiet)
+	}
+	if res.sent != 16 || res.received != 16 {
+		t.Errorf("bytes = %d sent, %d received, want 16 each", res.sent, res.received)
+	}
+
+	// The client sees the connection close
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := client.Read(make([]byte, 1)); err == nil {
+		t.Error("client connection still open")
+	}
+}
+
+func TestIdleLimitFiresBeforeLifetime(t *testing.T) {
+	_, proxyClient, proxyBackend, backend := proxyConns(t)
+	go echo(backend)
+
+	done := make(chan proxyResult, 1)
+	start := time.Now()
+	go func() {
+		sent, received, err := ProxyWithDeadlines(proxyClient, proxyBackend, 100*time.Millisecond, 5*time.Second)
+		done <- proxyResult{sent, received, err}
+	}()
+
+	res := waitResult(t, done, 2*time.Second)
+	if !errors.Is(res.err, ErrIdleTimeout) {
+		t.Errorf("err = %v, want ErrIdleTimeout", res.err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("closed after %v, want about the idle limit", elapsed)
+	}
+}
+
+func TestLifetimeLimitFiresBeforeIdle(t *testing.T) {
+	client, proxyClient, proxyBackend, backend := proxyConns(t)
+	go echo(backend)
+
+	const lifetime = 400 * time.Millisecond
+	done := make(chan proxyResult, 1)
+	start := time.Now()
+	go func() {
+		sent, received, err := ProxyWithDeadlines(proxyClient, proxyBackend, 150*time.Millisecond, lifetime)
+		done <- proxyResult{sent, received, err}
+	}()
+
+	// Keep the connection busy so the idle limit never fires
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		buf := make([]byte, 4)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(30 * time.Millisecond):
+			}
+			if _, err := client.Write([]byte("ping")); err != nil {
+				return
+			}
+			if _, err := io.ReadFull(client, buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	res := waitResult(t, done, 2*time.Second)
+	if !errors.Is(res.err, ErrLifetimeExceeded) {
+		t.Errorf("err = %v, want ErrLifetimeExceeded", res.err)
+	}
+	if elapsed := time.Since(start); elapsed < lifetime {
+		t.Errorf("closed after %v, before the lifetime limit", elapsed)
+	}
+}
+
+func TestWaitForBothDirectionsByDefault(t *testing.T) {
+	client, proxyClient, proxyBackend, backend := proxyConns(t)
+
+	// The backend only replies once the client has finished sending
+	go func() {
+		io.Copy(io.Discard, backend)
+		time.Sleep(50 * time.Millisecond)
+		backend.Write([]byte("late reply"))
+		backend.Close()
+	}()
+
+	done := make(chan proxyResult, 1)
+	go func() {
+		sent, received, err := ProxyWithOptions(proxyClient, proxyBackend, Options{})
+		done <- proxyResult{sent, received, err}
+	}()
+
+	client.Write([]byte("request"))
+	client.(*net.TCPConn).CloseWrite()
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	reply, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reply) != "late reply" {
+		t.Errorf("reply = %q, want the backend's reply after the client's EOF", reply)
+	}
+
+	if res := waitResult(t, done, 2*time.Second); res.err != nil {
+		t.Errorf("err = %v", res.err)
+	}
+}
+
+func TestOnTransferCountsWhileOpen(t *testing.T) {
+	client, proxyClient, proxyBackend, backend := proxyConns(t)
+	go func() {
+		echo(backend)
+		backend.Close()
+	}()
+
+	var sent, received atomic.Int64
+	opts := Options{OnTransfer: func(s, r int64) {
+		sent.Add(s)
+		received.Add(r)
+	}}
+
+	done := make(chan proxyResult, 1)
+	go func() {
+		s, r, err := ProxyWithOptions(proxyClient, proxyBackend, opts)
+		done <- proxyResult{s, r, err}
+	}()
+
+	roundTrip(t, client, "hello")
+
+	// Counted before the connection closes, not only once it does
+	deadline := time.Now().Add(2 * time.Second)
+	for sent.Load() != 5 || received.Load() != 5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("OnTransfer counted %d sent, %d received while open, want 5 and 5", sent.Load(), received.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	client.Close()
+	res := waitResult(t, done, 2*time.Second)
+	if res.sent != sent.Load() || res.received != received.Load() {
+		t.Errorf("returned %d sent, %d received, but OnTransfer counted %d and %d",
+			res.sent, res.received, sent.Load(), received.Load())
+	}
+}
+
+func TestBackendFailuresMarked(t *testing.T) {
+	tests := []struct {
+		name        string
+		resetClient bool
+		wantBackend bool
+	}{
+		{"client reset", true, false},
+		{"backend reset", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, proxyClient, proxyBackend, backend := proxyConns(t)
+
+			done := make(chan proxyResult, 1)
+			go func() {
+				sent, received, err := ProxyWithOptions(proxyClient, proxyBackend, Options{})
+				done <- proxyResult{sent, received, err}
+			}()
+
+			// Reset one side and close the other normally
+			reset, other := backend, client
+			if tt.resetClient {
+				reset, other = client, backend
+			}
+			reset.(*net.TCPConn).SetLinger(0)
+			reset.Close()
+			io.Copy(io.Discard, other)
+			other.Close()
+
+			res := waitResult(t, done, 2*time.Second)
+			if res.err == nil {
+				t.Fatal("err = nil after a reset")
+			}
+			if got := errors.Is(res.err, ErrBackendFailed); got != tt.wantBackend {
+				t.Errorf("err = %v, backend failure = %v, want %v", res.err, got, tt.wantBackend)
+			}
+		})
+	}
+}
+
+func TestCloseOnEOFTearsDownBothDirections(t *testing.T) {
+	client, proxyClient, proxyBackend, backend := proxyConns(t)
+
+	// The backend floods data and never finishes on its own
+	go func() {
+		chunk := make([]byte, 32*1024)
+		for {
+			if _, err := backend.Write(chunk); err != nil {
+				return
+			}
+		}
+	}()
+
+	done := make(chan proxyResult, 1)
+	go func() {
+		sent, received, err := ProxyWithOptions(proxyClient, proxyBackend, Options{CloseOnEOF: true})
+		done <- proxyResult{sent, received, err}
+	}()
+
+	// A slow client sends its request and finishes without reading the flood
+	client.Write([]byte("request"))
+	client.(*net.TCPConn).CloseWrite()
+
+	res := waitResult(t, done, 2*time.Second)
+	if res.sent != int64(len("request")) {
+		t.Errorf("sent = %d, want %d", res.sent, len("request"))
+	}
+}
+
+func TestProxyReturnsAfterOneSidedClose(t *testing.T) {
+	client, proxyClient, proxyBackend, backend := proxyConns(t)
+
+	// A sink backend that only reads, finishing once it sees EOF
+	received := make(chan []byte, 1)
+	go func() {
+		data, _ := io.ReadAll(backend)
+		received <- data
+		backend.Close()
+	}()
+
+	done := make(chan error, 1)
+	go func() { done <- Proxy(proxyClient, proxyBackend) }()
+
+	// The client sends, half-closes and never reads
+	client.Write([]byte("one way"))
+	client.(*net.TCPConn).CloseWrite()
+
+	select {
+	case data := <-received:
+		if string(data) != "one way" {
+			t.Errorf("backend received %q, want %q", data, "one way")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("backend never saw EOF, the client's close was not passed on")
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Proxy = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Proxy did not return, a copy goroutine is stuck")
+	}
+}
+
+func TestStalledWriteTimedOut(t *testing.T) {
+	client, proxyClient, proxyBackend, backend := proxyConns(t)
+
+	// The backend streams data the client never reads, so once the socket
+	// buffers fill, the proxy's write to the client blocks
+	go func() {
+		chunk := make([]byte, 64*1024)
+		for {
+			if _, err := backend.Write(chunk); err != nil {
+				return
+			}
+		}
+	}()
+
+	const writeTimeout = 200 * time.Millisecond
+	done := make(chan proxyResult, 1)
+	go func() {
+		sent, received, err := ProxyWithOptions(proxyClient, proxyBackend, Options{WriteTimeout: writeTimeout})
+		done <- proxyResult{sent, received, err}
+	}()
+
+	res := waitResult(t, done, 5*time.Second)
+	if !errors.Is(res.err, ErrWriteTimeout) {
+		t.Errorf("err = %v, want ErrWriteTimeout", res.err)
+	}
+	if res.received == 0 {
+		t.Error("nothing was written to the client before it stalled")
+	}
+
+	// Both sides are closed, so the stalled client is not pinned
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.Copy(io.Discard, client); err != nil {
+		t.Errorf("client read err = %v, want EOF after the close", err)
+	}
+}
+
+func TestReadTimeoutFiresWhileOtherSideActive(t *testing.T) {
+	client, proxyClient, proxyBackend, backend := proxyConns(t)
+
+	// The backend keeps talking, but the client never sends anything
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+			if _, err := backend.Write([]byte("tick")); err != nil {
+				return
+			}
+		}
+	}()
+	go io.Copy(io.Discard, client)
+
+	const readTimeout = 150 * time.Millisecond
+	done := make(chan proxyResult, 1)
+	start := time.Now()
+	go func() {
+		sent, received, err := ProxyWithOptions(proxyClient, proxyBackend, Options{ReadTimeout: readTimeout})
+		done <- proxyResult{sent, received, err}
+	}()
+
+	res := waitResult(t, done, 2*time.Second)
+	if !errors.Is(res.err, ErrReadTimeout) {
+		t.Errorf("err = %v, want ErrReadTimeout", res.err)
+	}
+	if elapsed := time.Since(start); elapsed < readTimeout {
+		t.Errorf("closed after %v, before the read timeout", elapsed)
+	}
+}
@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+)
+
+// ProxyProtocolHeader returns a PROXY protocol v1 header describing a connection
+// from src to dst. Non-TCP addresses produce the "PROXY UNKNOWN" form.
+func ProxyProtocolHeader(src net.Addr, dst net.Addr) string {
+	srcTCP, srcOK := src.(*net.TCPAddr)
+	dstTCP, dstOK := dst.(*net.TCPAddr)
+	if !srcOK || !dstOK {
+		return "PROXY UNKNOWN\r\n"
+	}
+
+	// Use TCP4 only when both ends are IPv4 (including IPv4-mapped IPv6)
+	srcIP4, dstIP4 := srcTCP.IP.To4(), dstTCP.IP.To4()
+	if srcIP4 != nil && dstIP4 != nil {
+		return fmt.Sprintf("PROXY TCP4 %s %s %d %d\r\n", srcIP4, dstIP4, srcTCP.Port, dstTCP.Port)
+	}
+
+	return fmt.Sprintf("PROXY TCP6 %s %s %d %d\r\n", srcTCP.IP.To16(), dstTCP.IP.To16(), srcTCP.Port, dstTCP.Port)
+}
+
+// WriteProxyProtocolHeader writes a PROXY protocol v1 header for the client
+// connection to w, typically the backend connection before proxying starts.
+func WriteProxyProtocolHeader(w io.Writer, client net.Conn) error {
+	header := ProxyProtocolHeader(client.RemoteAddr(), client.LocalAddr())
+	_, err := io.WriteString(w, header)
+	return err
+}
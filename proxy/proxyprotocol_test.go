@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"net"
+	"testing"
+)
+
+func TestProxyProtocolHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		src  net.Addr
+		dst  net.Addr
+		want string
+	}{
+		{
+			"ipv4",
+			&net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 51000},
+			&net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 8080},
+			"PROXY TCP4 192.0.2.10 198.51.100.1 51000 8080\r\n",
+		},
+		{
+			"ipv6",
+			&net.TCPAddr{IP: net.ParseIP("2001:db8::10"), Port: 51000},
+			&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 8080},
+			"PROXY TCP6 2001:db8::10 2001:db8::1 51000 8080\r\n",
+		},
+		{
+			"ipv4-mapped",
+			&net.TCPAddr{IP: net.ParseIP("::ffff:192.0.2.10"), Port: 51000},
+			&net.TCPAddr{IP: net.ParseIP("::ffff:198.51.100.1"), Port: 8080},
+			"PROXY TCP4 192.0.2.10 198.51.100.1 51000 8080\r\n",
+		},
+		{
+			"non-tcp",
+			&net.UnixAddr{Name: "/tmp/client", Net: "unix"},
+			&net.UnixAddr{Name: "/tmp/server", Net: "unix"},
+			"PROXY UNKNOWN\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := ProxyProtocolHeader(tt.src, tt.dst); got != tt.want {
+			t.Errorf("%s: header = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// errReadsStopped ends a read once ProxyReusable has stopped reading the backend.
+var errReadsStopped = errors.New("reads stopped")
+
+// reuseQuietPeriod is how long a backend must send nothing after replying to the
+// client's last request before its connection is considered settled for reuse.
+const reuseQuietPeriod = 100 * time.Millisecond
+
+// reuseReplyTimeout is how long to wait for a backend to reply after the client
+// finished, before giving up on reusing its connection.
+const reuseReplyTimeout = 5 * time.Second
+
+// ProxyReusable proxies like ProxyWithOptions, but leaves the backend connection
+// open so it can be reused for another client. The backend is never half-closed:
+// once the client closes its side, the backend's reply is still relayed until it
+// has been quiet for a short period. The backend is reusable only when it replied
+// to the client's last request and the connection finished cleanly; a backend
+// that has not replied within reuseReplyTimeout is not reused.
+//
+// This only suits request/response protocols. Anything a backend sends once when
+// a connection opens, such as a greeting, only reaches the first client.
+func ProxyReusable(client net.Conn, backend net.Conn, opts Options) (bytesSent int64, bytesReceived int64, reusable bool, err error) {
+	state := newDeadlineState(opts)
+
+	fromClient := &deadlineReader{conn: client, peer: backend, state: state, activity: &state.lastRequest}
+	fromBackend := &deadlineReader{conn: backend, peer: client, state: state, activity: &state.lastReply, backend: true, settles: true}
+	toBackend := &deadlineWriter{conn: backend, peer: client, state: state, backend: true}
+	toClient := &deadlineWriter{conn: client, peer: backend, state: state}
+
+	opts.settleBackend = func() {
+		state.clientDone.Store(time.Now().UnixNano())
+		// Wake the backend reader so it picks up the settle deadline
+		backend.SetReadDeadline(time.Now())
+	}
+
+	bytesSent, bytesReceived, err = proxyReaders(client, backend, fromClient, fromBackend, toBackend, toClient, opts)
+
+	if state.closeErr != nil {
+		return bytesSent, bytesReceived, false, state.closeErr
+	}
+
+	reusable = err == nil && fromBackend.readStopped && !opts.CloseOnEOF
+	if reusable {
+		backend.SetDeadline(time.Time{})
+	}
+
+	return bytesSent, bytesReceived, reusable, err
+}
+
+// awaitingReply reports whether the client sent a request the backend has not
+// replied to yet.
+func (ds *deadlineState) awaitingReply() bool {
+	lastRequest := ds.lastRequest.Load()
+	return lastRequest != 0 && ds.lastReply.Load() <= lastRequest
+}
+
+// settleDeadline returns when reading a backend being settled for reuse stops:
+// a quiet period after its last reply, or the reply timeout while it still owes
+// the client a reply.
+func (ds *deadlineState) settleDeadline() time.Time {
+	done := ds.clientDone.Load()
+	if ds.awaitingReply() {
+		return time.Unix(0, done).Add(reuseReplyTimeout)
+	}
+
+	return time.Unix(0, max(ds.lastReply.Load(), done)).Add(reuseQuietPeriod)
+}
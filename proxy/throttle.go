@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"io"
+	"time"
+)
+
+// throttleBursts is how many bursts make up one second of a throttled rate; a
+// smaller burst paces writes more evenly at the cost of more, smaller writes.
+const throttleBursts = 10
+
+// throttledWriter paces writes to the underlying writer to a rate in bytes per
+// second using a token bucket holding at most a tenth of a second's worth of bytes.
+// It is used by a single copy goroutine, so it needs no locking.
+type throttledWriter struct {
+	w      io.Writer
+	rate   float64   // Bytes per second
+	burst  float64   // Bucket capacity, and the largest chunk written at once
+	tokens float64   // Bytes that may be written without waiting
+	last   time.Time // When tokens was last refilled
+}
+
+// newThrottledWriter wraps w so that no more than bytesPerSecond are written per second.
+func newThrottledWriter(w io.Writer, bytesPerSecond int64) *throttledWriter {
+	rate := float64(bytesPerSecond)
+	burst := rate / throttleBursts
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &throttledWriter{
+		w:     w,
+		rate:  rate,
+		burst: burst,
+		last:  time.Now(),
+	}
+}
+
+// Write writes p in chunks of at most one burst, waiting before each chunk until
+// the bucket holds enough tokens for it.
+func (tw *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		chunk := len(p) - written
+		if float64(chunk) > tw.burst {
+			chunk = int(tw.burst)
+		}
+		tw.wait(chunk)
+
+		n, err := tw.w.Write(p[written : written+chunk])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// wait refills the bucket for the time elapsed and sleeps until it holds n
+// tokens, then takes them.
+func (tw *throttledWriter) wait(n int) {
+	now := time.Now()
+	tw.tokens += now.Sub(tw.last).Seconds() * tw.rate
+	if tw.tokens > tw.burst {
+		tw.tokens = tw.burst
+	}
+	tw.last = now
+
+	if missing := float64(n) - tw.tokens; missing > 0 {
+		delay := time.Duration(missing / tw.rate * float64(time.Second))
+		time.Sleep(delay)
+		tw.tokens += delay.Seconds() * tw.rate
+		tw.last = tw.last.Add(delay)
+	}
+
+	tw.tokens -= float64(n)
+}
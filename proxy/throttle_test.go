@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestThrottledWriterPacesToRate(t *testing.T) {
+	const rate = 100_000
+	payload := bytes.Repeat([]byte("x"), 50_000)
+
+	var out bytes.Buffer
+	tw := newThrottledWriter(&out, rate)
+
+	start := time.Now()
+	n, err := tw.Write(payload)
+	elapsed := time.Since(start)
+
+	if err != nil || n != len(payload) || out.Len() != len(payload) {
+		t.Fatalf("Write = %d, %v; %d bytes arrived, want %d", n, err, out.Len(), len(payload))
+	}
+
+	// The bucket starts empty, so 50KB at 100KB/s takes half a second
+	want := 500 * time.Millisecond
+	if elapsed < want*8/10 || elapsed > want*16/10 {
+		t.Errorf("wrote %d bytes in %v, want about %v", len(payload), elapsed, want)
+	}
+}
+
+func TestPerConnectionRateLimit(t *testing.T) {
+	client, proxyClient, proxyBackend, backend := proxyConns(t)
+
+	const rate = 100_000
+	payload := bytes.Repeat([]byte("y"), 40_000)
+	go func() {
+		backend.Write(payload)
+		backend.Close()
+	}()
+
+	done := make(chan proxyResult, 1)
+	go func() {
+		sent, received, err := ProxyWithOptions(proxyClient, proxyBackend, Options{BytesPerSecond: rate})
+		done <- proxyResult{sent, received, err}
+	}()
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+	got, err := io.ReadAll(client)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	if len(got) != len(payload) {
+		t.Fatalf("client received %d bytes, want %d", len(got), len(payload))
+	}
+	want := 400 * time.Millisecond
+	if elapsed < want*8/10 || elapsed > want*2 {
+		t.Errorf("transferred %d bytes in %v at %d B/s, want about %v", len(got), elapsed, rate, want)
+	}
+
+	if res := waitResult(t, done, 2*time.Second); res.received != int64(len(payload)) {
+		t.Errorf("proxy counted %d bytes received, want %d", res.received, len(payload))
+	}
+}
@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"tcp_lb/backend"
+	"time"
+)
+
+// labelEscaper escapes label values for the Prometheus text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// backendLabels formats the Prometheus labels of a backend's series: its address
+// followed by its configured labels in name order.
+func backendLabels(b backend.BackendStats) string {
+	var labels strings.Builder
+	fmt.Fprintf(&labels, "address=\"%s\"", labelEscaper.Replace(b.Address))
+	for _, name := range slices.Sorted(maps.Keys(b.Labels)) {
+		fmt.Fprintf(&labels, ",%s=\"%s\"", name, labelEscaper.Replace(b.Labels[name]))
+	}
+
+	return labels.String()
+}
+
+// handleMetrics handles /metrics requests and returns Prometheus text-format metrics.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	backendStats := s.pool.GetAllStats()
+
+	var out strings.Builder
+
+	out.WriteString("# HELP tcp_lb_backend_active_connections Number of active connections to the backend.\n")
+	out.WriteString("# TYPE tcp_lb_backend_active_connections gauge\n")
+	for _, b := range backendStats {
+		fmt.Fprintf(&out, "tcp_lb_backend_active_connections{%s} %d\n", backendLabels(b), b.ActiveConnections)
+	}
+
+	out.WriteString("# HELP tcp_lb_backend_total_connections Total connections handled by the backend.\n")
+	out.WriteString("# TYPE tcp_lb_backend_total_connections counter\n")
+	for _, b := range backendStats {
+		fmt.Fprintf(&out, "tcp_lb_backend_total_connections{%s} %d\n", backendLabels(b), b.TotalConnections)
+	}
+
+	out.WriteString("# HELP tcp_lb_backend_up Whether the backend is healthy (1) or down (0).\n")
+	out.WriteString("# TYPE tcp_lb_backend_up gauge\n")
+	for _, b := range backendStats {
+		up := 0
+		if b.Alive {
+			up = 1
+		}
+		fmt.Fprintf(&out, "tcp_lb_backend_up{%s} %d\n", backendLabels(b), up)
+	}
+
+	out.WriteString("# HELP tcp_lb_backend_dial_latency_seconds Latency of successful backend dials from client connections and health checks.\n")
+	out.WriteString("# TYPE tcp_lb_backend_dial_latency_seconds summary\n")
+	for _, b := range backendStats {
+		labels := backendLabels(b)
+		latency := b.DialLatency
+		fmt.Fprintf(&out, "tcp_lb_backend_dial_latency_seconds{%s,quantile=\"0.5\"} %g\n", labels, latency.P50.Seconds())
+		fmt.Fprintf(&out, "tcp_lb_backend_dial_latency_seconds{%s,quantile=\"0.95\"} %g\n", labels, latency.P95.Seconds())
+		fmt.Fprintf(&out, "tcp_lb_backend_dial_latency_seconds{%s,quantile=\"0.99\"} %g\n", labels, latency.P99.Seconds())
+		fmt.Fprintf(&out, "tcp_lb_backend_dial_latency_seconds_sum{%s} %g\n", labels, latency.Sum.Seconds())
+		fmt.Fprintf(&out, "tcp_lb_backend_dial_latency_seconds_count{%s} %d\n", labels, latency.Count)
+	}
+
+	if s.lb != nil {
+		limitStats := s.lb.ConnectionLimitStats()
+
+		out.WriteString("# HELP tcp_lb_in_flight_connections Client connections currently being handled.\n")
+		out.WriteString("# TYPE tcp_lb_in_flight_connections gauge\n")
+		fmt.Fprintf(&out, "tcp_lb_in_flight_connections %d\n", limitStats.InFlight)
+
+		out.WriteString("# HELP tcp_lb_shed_connections_total Connections refused because the concurrent connection cap was reached.\n")
+		out.WriteString("# TYPE tcp_lb_shed_connections_total counter\n")
+		fmt.Fprintf(&out, "tcp_lb_shed_connections_total %d\n", limitStats.Shed)
+	}
+
+	out.WriteString("# HELP tcp_lb_uptime_seconds Seconds since the stats server was created.\n")
+	out.WriteString("# TYPE tcp_lb_uptime_seconds gauge\n")
+	fmt.Fprintf(&out, "tcp_lb_uptime_seconds %d\n", int64(time.Since(s.startTime).Seconds()))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(out.String()))
+}
@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tcp_lb/backend"
+)
+
+// scrapeMetrics fetches /metrics from s and returns each sample's value keyed by
+// its series, e.g. `tcp_lb_backend_up{address="a:1"}`.
+func scrapeMetrics(t *testing.T, s *Server) map[string]float64 {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	samples := map[string]float64{}
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			t.Fatalf("malformed sample %q", line)
+		}
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("sample %q: %v", line, err)
+		}
+		samples[line[:i]] = value
+	}
+
+	return samples
+}
+
+func TestMetricsTwoBackendPool(t *testing.T) {
+	pool := backend.NewPool()
+	b1 := backend.NewBackendWithWeight("10.0.0.1:80", 1)
+	b2 := backend.NewBackendWithWeight("10.0.0.2:80", 1)
+	pool.AddBackend(b1)
+	pool.AddBackend(b2)
+
+	// Two active connections on the first backend, one finished on the second
+	for range 2 {
+		conn, _ := net.Pipe()
+		b1.AddConnection(conn)
+	}
+	conn, _ := net.Pipe()
+	b2.AddConnection(conn)
+	b2.RemoveConnection(conn)
+	b2.SetAlive(false)
+
+	samples := scrapeMetrics(t, NewServer(pool, ""))
+
+	want := map[string]float64{
+		`tcp_lb_backend_active_connections{address="10.0.0.1:80"}`: 2,
+		`tcp_lb_backend_active_connections{address="10.0.0.2:80"}`: 0,
+		`tcp_lb_backend_total_connections{address="10.0.0.1:80"}`:  2,
+		`tcp_lb_backend_total_connections{address="10.0.0.2:80"}`:  1,
+		`tcp_lb_backend_up{address="10.0.0.1:80"}`:                 1,
+		`tcp_lb_backend_up{address="10.0.0.2:80"}`:                 0,
+	}
+	for series, value := range want {
+		got, ok := samples[series]
+		if !ok {
+			t.Errorf("missing series %s", series)
+		} else if got != value {
+			t.Errorf("%s = %g, want %g", series, got, value)
+		}
+	}
+
+	if _, ok := samples["tcp_lb_uptime_seconds"]; !ok {
+		t.Error("missing tcp_lb_uptime_seconds")
+	}
+}
+
+func TestMetricsBackendLabels(t *testing.T) {
+	pool := backend.NewPool()
+	b := backend.NewBackendWithWeight("10.0.0.1:80", 1)
+	b.Labels = map[string]string{"team": "x", "env": `pr"od`}
+	pool.AddBackend(b)
+
+	samples := scrapeMetrics(t, NewServer(pool, ""))
+
+	// Labels follow the address in name order, with values escaped
+	series := `tcp_lb_backend_up{address="10.0.0.1:80",env="pr\"od",team="x"}`
+	if got, ok := samples[series]; !ok {
+		t.Errorf("missing series %s", series)
+	} else if got != 1 {
+		t.Errorf("%s = %g, want 1", series, got)
+	}
+}
@@ -3,50 +3,144 @@ package stats
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"tcp_lb/backend"
+	"tcp_lb/config"
+	"tcp_lb/loadbalancer"
 	"time"
 )
 
+// defaultHealthCheckTimeout is used for the health check run on newly added backends.
+const defaultHealthCheckTimeout = 5 * time.Second
+
+// defaultDrainTimeout is how long a removed backend's connections may take to finish.
+const defaultDrainTimeout = 30 * time.Second
+
+// defaultShutdownTimeout is how long Stop waits for in-flight requests to finish.
+const defaultShutdownTimeout = 5 * time.Second
+
+// LoadBalancer is the subset of load balancer operations used by the admin endpoints.
+type LoadBalancer interface {
+	EffectiveConfig() loadbalancer.EffectiveConfig
+	ScalingRecommendation() (loadbalancer.ScalingRecommendation, float64)
+	RetryStats() loadbalancer.RetryStats
+	SetAlgorithm(algo loadbalancer.Algorithm)
+	ConnectionLimitStats() loadbalancer.ConnectionLimitStats
+	AcceptStats() loadbalancer.AcceptStats
+	CheckAllBackends() loadbalancer.HealthStatus
+	BeginDrain() loadbalancer.DrainStatus
+	GetDrainStatus() loadbalancer.DrainStatus
+	SetBackendWeight(address string, weight int) error
+	NewBackend(bc config.BackendConfig) *backend.Backend
+	AddBackend(b *backend.Backend) error
+	DrainBackend(address string, timeout time.Duration) (int, error)
+}
+
 // Server provides an HTTP endpoint for viewing load balancer statistics.
 type Server struct {
-	pool       *backend.Pool
-	listenAddr string
-	server     *http.Server
-	startTime  time.Time
+	pool               *backend.Pool
+	lb                 LoadBalancer // Optional, enables endpoints that need the load balancer
+	globalStats        *GlobalStats // Optional, totals across all backends
+	listenAddr         string
+	server             *http.Server
+	startTime          time.Time
+	healthCheckTimeout time.Duration // Timeout for the health check run when a backend is added
+	drainTimeout       time.Duration // How long a removed backend's connections may take to finish
+	shutdownTimeout    time.Duration // How long Stop waits for in-flight requests to finish
+	streamInterval     time.Duration // How often /stats/stream pushes a snapshot
+	stopping           chan struct{} // Closed by Stop to end open streams
+	stopOnce           sync.Once
 }
 
 // NewServer creates a new stats server.
 func NewServer(pool *backend.Pool, listenAddr string) *Server {
 	return &Server{
-		pool:       pool,
-		listenAddr: listenAddr,
-		startTime:  time.Now(),
+		pool:               pool,
+		listenAddr:         listenAddr,
+		startTime:          time.Now(),
+		healthCheckTimeout: defaultHealthCheckTimeout,
+		drainTimeout:       defaultDrainTimeout,
+		shutdownTimeout:    defaultShutdownTimeout,
+		streamInterval:     defaultStreamInterval,
+		stopping:           make(chan struct{}),
+	}
+}
+
+// SetHealthCheckTimeout sets the timeout used when health checking newly added backends.
+func (s *Server) SetHealthCheckTimeout(timeout time.Duration) {
+	s.healthCheckTimeout = timeout
+}
+
+// SetDrainTimeout sets how long DELETE /backends waits for a backend's active
+// connections to finish before closing them.
+func (s *Server) SetDrainTimeout(timeout time.Duration) {
+	s.drainTimeout = timeout
+}
+
+// SetShutdownTimeout sets how long Stop waits for in-flight requests, such as slow
+// /metrics scrapes, before closing their connections. A non-positive timeout
+// restores the 5 second default.
+func (s *Server) SetShutdownTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
 	}
+	s.shutdownTimeout = timeout
+}
+
+// SetLoadBalancer sets the load balancer used by the admin endpoints.
+func (s *Server) SetLoadBalancer(lb LoadBalancer) {
+	s.lb = lb
+}
+
+// SetGlobalStats sets the global statistics reported by /stats.
+func (s *Server) SetGlobalStats(globalStats *GlobalStats) {
+	s.globalStats = globalStats
 }
 
 // Start begins serving HTTP requests for statistics.
 func (s *Server) Start() error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/stats", s.handleStats)
-	mux.HandleFunc("/health", s.handleHealth)
-
 	s.server = &http.Server{
 		Addr:    s.listenAddr,
-		Handler: mux,
+		Handler: s.Handler(),
 	}
 
 	return s.server.ListenAndServe()
 }
 
-// Stop gracefully shuts down the stats server.
+// Handler returns the handler serving the stats and admin endpoints, for serving
+// them from an existing HTTP server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", s.handleStats)
+	mux.HandleFunc("/stats/stream", s.handleStatsStream)
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/live", s.handleLive)
+	mux.HandleFunc("/ready", s.handleHealth)
+	mux.HandleFunc("/backends", s.handleBackends)
+	mux.HandleFunc("/backends/{addr}/weight", s.handleBackendWeight)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/config", s.handleConfig)
+	mux.HandleFunc("/scaling", s.handleScaling)
+	mux.HandleFunc("/algorithm", s.handleAlgorithm)
+	mux.HandleFunc("/healthcheck", s.handleHealthCheck)
+	mux.HandleFunc("/drain", s.handleDrain)
+
+	return mux
+}
+
+// Stop gracefully shuts down the stats server, ending open streams and waiting up
+// to the shutdown timeout for other in-flight requests to finish.
 func (s *Server) Stop() error {
 	if s.server == nil {
 		return nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	s.stopOnce.Do(func() { close(s.stopping) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
 	defer cancel()
 
 	return s.server.Shutdown(ctx)
@@ -54,21 +148,95 @@ func (s *Server) Stop() error {
 
 // StatsResponse is the JSON response for /stats endpoint.
 type StatsResponse struct {
-	UptimeSeconds   int64                  `json:"uptime_seconds"`
-	TotalBackends   int                    `json:"total_backends"`
-	HealthyBackends int                    `json:"healthy_backends"`
-	Backends        []BackendStatsResponse `json:"backends"`
+	UptimeSeconds      int64                    `json:"uptime_seconds"`
+	TotalBackends      int                      `json:"total_backends"`
+	HealthyBackends    int                      `json:"healthy_backends"`
+	TotalConnections   int64                    `json:"total_connections"`
+	ActiveConnections  int64                    `json:"active_connections"`
+	TotalBytesSent     int64                    `json:"total_bytes_sent"`
+	TotalBytesReceived int64                    `json:"total_bytes_received"`
+	BytesPerSecondSent float64                  `json:"bytes_per_second_sent"`
+	BytesPerSecondRecv float64                  `json:"bytes_per_second_received"`
+	Backends           []BackendStatsResponse   `json:"backends"`
+	Retries            *RetryStatsResponse      `json:"retries,omitempty"`
+	ConnectionLimit    *ConnectionLimitResponse `json:"connection_limit,omitempty"`
+	AcceptErrors       *AcceptErrorsResponse    `json:"accept_errors,omitempty"`
+}
+
+// AcceptErrorsResponse is the JSON response for listener accept errors in /stats.
+type AcceptErrorsResponse struct {
+	Temporary int64 `json:"temporary"`
+	Permanent int64 `json:"permanent"`
+}
+
+// ConnectionLimitResponse is the JSON response for the concurrent connection cap in /stats.
+type ConnectionLimitResponse struct {
+	InFlight int64 `json:"in_flight"`
+	Limit    int   `json:"limit"`
+	Shed     int64 `json:"shed"`
+}
+
+// RetryStatsResponse is the JSON response for connection retry activity in /stats.
+type RetryStatsResponse struct {
+	RetriesPerSecond int   `json:"retries_per_second"`
+	TotalRetries     int64 `json:"total_retries"`
+	Throttled        int64 `json:"throttled"`
 }
 
 // BackendStatsResponse is the JSON response for each backend in /stats.
 type BackendStatsResponse struct {
-	Address           string `json:"address"`
-	Alive             bool   `json:"alive"`
-	ActiveConnections int    `json:"active_connections"`
-	TotalConnections  int64  `json:"total_connections"`
+	Address           string            `json:"address"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Alive             bool              `json:"alive"`
+	ActiveConnections int               `json:"active_connections"`
+	TotalConnections  int64             `json:"total_connections"`
+	BytesSent         int64             `json:"bytes_sent"`
+	BytesReceived     int64             `json:"bytes_received"`
+	Reason            string            `json:"reason,omitempty"`
+	HealthCheckMs     float64           `json:"health_check_ms"`
+	IdleTimeouts      int64             `json:"idle_timeouts"`
+	LifetimeTimeouts  int64             `json:"lifetime_timeouts"`
+	DialFailures      int64             `json:"dial_failures"`
+	ReusedConnections int64             `json:"reused_connections"`
+	DialLatency       LatencyResponse   `json:"dial_latency"`
 }
 
-// handleStats handles /stats requests and returns backend statistics.
+// LatencyResponse is the JSON response for a backend's dial latency percentiles in /stats.
+type LatencyResponse struct {
+	Count int64   `json:"count"`
+	P50Ms float64 `json:"p50_ms"`
+	P95Ms float64 `json:"p95_ms"`
+	P99Ms float64 `json:"p99_ms"`
+}
+
+// newBackendStatsResponse converts a backend's statistics snapshot for /stats.
+func newBackendStatsResponse(b backend.BackendStats) BackendStatsResponse {
+	return BackendStatsResponse{
+		Address:           b.Address,
+		Labels:            b.Labels,
+		Alive:             b.Alive,
+		ActiveConnections: b.ActiveConnections,
+		TotalConnections:  b.TotalConnections,
+		BytesSent:         b.BytesSent,
+		BytesReceived:     b.BytesReceived,
+		Reason:            string(b.Reason),
+		HealthCheckMs:     float64(b.HealthCheckTime) / float64(time.Millisecond),
+		IdleTimeouts:      b.IdleTimeouts,
+		LifetimeTimeouts:  b.LifetimeTimeouts,
+		DialFailures:      b.DialFailures,
+		ReusedConnections: b.ReusedConnections,
+		DialLatency: LatencyResponse{
+			Count: b.DialLatency.Count,
+			P50Ms: float64(b.DialLatency.P50) / float64(time.Millisecond),
+			P95Ms: float64(b.DialLatency.P95) / float64(time.Millisecond),
+			P99Ms: float64(b.DialLatency.P99) / float64(time.Millisecond),
+		},
+	}
+}
+
+// handleStats handles /stats requests and returns backend statistics. With an
+// address query parameter, only that backend's statistics are returned, and with
+// label=key=value parameters only backends carrying every given label are included.
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -77,6 +245,65 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 
 	backendStats := s.pool.GetAllStats()
 
+	if address := r.URL.Query().Get("address"); address != "" {
+		for _, b := range backendStats {
+			if b.Address == address {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(newBackendStatsResponse(b))
+				return
+			}
+		}
+
+		http.Error(w, "Backend not found", http.StatusNotFound)
+		return
+	}
+
+	backendStats, err := filterByLabels(backendStats, r.URL.Query()["label"])
+	if err != nil {
+		http.Error(w, "Invalid label filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.statsResponse(backendStats))
+}
+
+// filterByLabels returns the backends carrying every label in selectors, each
+// given as key=value.
+func filterByLabels(backendStats []backend.BackendStats, selectors []string) ([]backend.BackendStats, error) {
+	if len(selectors) == 0 {
+		return backendStats, nil
+	}
+
+	want := make(map[string]string, len(selectors))
+	for _, selector := range selectors {
+		key, value, ok := strings.Cut(selector, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("label %q must be key=value", selector)
+		}
+		want[key] = value
+	}
+
+	var matched []backend.BackendStats
+	for _, b := range backendStats {
+		matches := true
+		for key, value := range want {
+			if got, ok := b.Labels[key]; !ok || got != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			matched = append(matched, b)
+		}
+	}
+
+	return matched, nil
+}
+
+// statsResponse builds the /stats response from the backends' statistics and
+// the global and load balancer totals, where available.
+func (s *Server) statsResponse(backendStats []backend.BackendStats) StatsResponse {
 	healthyCount := 0
 	backendResponses := make([]BackendStatsResponse, 0, len(backendStats))
 
@@ -85,12 +312,7 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 			healthyCount++
 		}
 
-		backendResponses = append(backendResponses, BackendStatsResponse{
-			Address:           b.Address,
-			Alive:             b.Alive,
-			ActiveConnections: b.ActiveConnections,
-			TotalConnections:  b.TotalConnections,
-		})
+		backendResponses = append(backendResponses, newBackendStatsResponse(b))
 	}
 
 	response := StatsResponse{
@@ -100,16 +322,60 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		Backends:        backendResponses,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	if s.globalStats != nil {
+		snapshot := s.globalStats.GetSnapshot()
+		response.TotalConnections = snapshot.TotalConnections
+		response.ActiveConnections = snapshot.ActiveConnections
+		response.TotalBytesSent = snapshot.TotalBytesSent
+		response.TotalBytesReceived = snapshot.TotalBytesReceived
+		response.BytesPerSecondSent = snapshot.BytesPerSecondSent
+		response.BytesPerSecondRecv = snapshot.BytesPerSecondReceived
+	}
+
+	if s.lb != nil {
+		retryStats := s.lb.RetryStats()
+		response.Retries = &RetryStatsResponse{
+			RetriesPerSecond: retryStats.RetriesPerSecond,
+			TotalRetries:     retryStats.TotalRetries,
+			Throttled:        retryStats.Throttled,
+		}
+
+		limitStats := s.lb.ConnectionLimitStats()
+		response.ConnectionLimit = &ConnectionLimitResponse{
+			InFlight: limitStats.InFlight,
+			Limit:    limitStats.Limit,
+			Shed:     limitStats.Shed,
+		}
+
+		acceptStats := s.lb.AcceptStats()
+		response.AcceptErrors = &AcceptErrorsResponse{
+			Temporary: acceptStats.Temporary,
+			Permanent: acceptStats.Permanent,
+		}
+	}
+
+	return response
 }
 
-// HealthResponse is the JSON response for /health endpoint.
+// HealthResponse is the JSON response for /health, /live and /ready endpoints.
 type HealthResponse struct {
 	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"` // Why the status is unhealthy
 }
 
-// handleHealth handles /health requests and reports overall health status.
+// handleLive handles /live requests, reporting healthy whenever the process can serve them.
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthResponse{Status: "healthy"})
+}
+
+// handleHealth handles /health and /ready requests, reporting healthy only while
+// at least one backend can receive connections.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -125,10 +391,426 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(HealthResponse{Status: "healthy"})
 	} else {
 		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(HealthResponse{Status: "unhealthy"})
+		json.NewEncoder(w).Encode(HealthResponse{Status: "unhealthy", Reason: "no healthy backends"})
 	}
 }
 
+// BackendRequest is the JSON request body for POST, PUT and DELETE /backends.
+type BackendRequest struct {
+	Address       string   `json:"address"`
+	HealthAddress string   `json:"health_address"`
+	Weight        int      `json:"weight"`
+	Tags          []string `json:"tags"` // Routes the backend to listeners filtering on these tags
+}
+
+// BackendResponse is the JSON response for each backend in /backends.
+type BackendResponse struct {
+	Address      string  `json:"address"`
+	Weight       int     `json:"weight"`
+	Alive        bool    `json:"alive"`
+	Reason       string  `json:"reason,omitempty"`
+	Availability float64 `json:"availability"`
+}
+
+// handleBackends handles /backends requests for adding, reweighting and removing
+// backends at runtime. Removal drains the backend first, waiting up to the drain
+// timeout for its connections.
+func (s *Server) handleBackends(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleListBackends(w, r)
+	case http.MethodPost:
+		s.handleAddBackend(w, r)
+	case http.MethodPut:
+		s.handleSetBackendWeight(w, r)
+	case http.MethodDelete:
+		s.handleRemoveBackend(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleListBackends returns every backend with its availability since start.
+func (s *Server) handleListBackends(w http.ResponseWriter, r *http.Request) {
+	backends := s.pool.GetBackends()
+	responses := make([]BackendResponse, 0, len(backends))
+
+	for _, b := range backends {
+		responses = append(responses, BackendResponse{
+			Address:      b.Address,
+			Weight:       b.GetWeight(),
+			Alive:        b.IsAlive(),
+			Reason:       string(b.GetDownReason()),
+			Availability: b.GetAvailability(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+// handleAddBackend adds a backend to the load balancer, with the same settings as
+// configured backends, and health checks it before it is routed to.
+func (s *Server) handleAddBackend(w http.ResponseWriter, r *http.Request) {
+	if s.lb == nil {
+		http.Error(w, "Load balancer not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Weight defaults to 1 when omitted from the request body
+	req := BackendRequest{Weight: 1}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Address == "" {
+		http.Error(w, "Address is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Weight < 0 {
+		http.Error(w, "Weight must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	// Skip the health check for an address that is already configured
+	if s.pool.GetBackendByAddress(req.Address) != nil {
+		http.Error(w, "Backend already exists", http.StatusConflict)
+		return
+	}
+
+	b := s.lb.NewBackend(config.BackendConfig{
+		Address:       req.Address,
+		Weight:        req.Weight,
+		HealthAddress: req.HealthAddress,
+		Tags:          req.Tags,
+	})
+
+	// Check health before adding so the backend doesn't receive traffic while unreachable
+	alive := b.CheckHealth(s.healthCheckTimeout)
+	if err := s.lb.AddBackend(b); err != nil {
+		http.Error(w, "Backend already exists", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(BackendResponse{
+		Address:      req.Address,
+		Weight:       req.Weight,
+		Alive:        alive,
+		Reason:       string(b.GetDownReason()),
+		Availability: b.GetAvailability(),
+	})
+}
+
+// handleSetBackendWeight changes a backend's weight, which the weighted algorithms
+// pick up on their next selection.
+func (s *Server) handleSetBackendWeight(w http.ResponseWriter, r *http.Request) {
+	if s.lb == nil {
+		http.Error(w, "Load balancer not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	req := BackendRequest{Weight: -1}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	s.setBackendWeight(w, req.Address, req.Weight)
+}
+
+// WeightRequest is the JSON request body for PUT /backends/{addr}/weight.
+type WeightRequest struct {
+	Weight *int `json:"weight"`
+}
+
+// handleBackendWeight handles PUT /backends/{addr}/weight requests, changing one
+// backend's weight so traffic can be shifted gradually, e.g. during canary rollouts.
+func (s *Server) handleBackendWeight(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.lb == nil {
+		http.Error(w, "Load balancer not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req WeightRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Weight == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	s.setBackendWeight(w, r.PathValue("addr"), *req.Weight)
+}
+
+// setBackendWeight changes a backend's weight and responds with the backend.
+func (s *Server) setBackendWeight(w http.ResponseWriter, address string, weight int) {
+	if weight < 0 {
+		http.Error(w, "Weight must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.lb.SetBackendWeight(address, weight); err != nil {
+		http.Error(w, "Backend not found", http.StatusNotFound)
+		return
+	}
+
+	b := s.pool.GetBackendByAddress(address)
+	if b == nil {
+		http.Error(w, "Backend not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BackendResponse{
+		Address:      address,
+		Weight:       b.GetWeight(),
+		Alive:        b.IsAlive(),
+		Reason:       string(b.GetDownReason()),
+		Availability: b.GetAvailability(),
+	})
+}
+
+// handleRemoveBackend drains a backend and removes it from the load balancer.
+func (s *Server) handleRemoveBackend(w http.ResponseWriter, r *http.Request) {
+	if s.lb == nil {
+		http.Error(w, "Load balancer not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req BackendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Let active connections finish before the backend is removed
+	b := s.pool.GetBackendByAddress(req.Address)
+	if b == nil {
+		http.Error(w, "Backend not found", http.StatusNotFound)
+		return
+	}
+	if _, err := s.lb.DrainBackend(req.Address, s.drainTimeout); err != nil {
+		http.Error(w, "Backend not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BackendResponse{
+		Address:      req.Address,
+		Weight:       b.GetWeight(),
+		Alive:        b.IsAlive(),
+		Reason:       string(b.GetDownReason()),
+		Availability: b.GetAvailability(),
+	})
+}
+
+// ConfigResponse is the JSON response for /config endpoint.
+type ConfigResponse struct {
+	ListenAddr                 string                   `json:"listen_addr"`
+	Listeners                  []ListenerConfigResponse `json:"listeners"`
+	Backends                   []BackendConfigResponse  `json:"backends"`
+	HealthCheckIntervalSeconds float64                  `json:"health_check_interval_seconds"`
+	ConnectTimeoutSeconds      float64                  `json:"connect_timeout_seconds"`
+	Algorithm                  string                   `json:"algorithm"`
+	Draining                   bool                     `json:"draining"`
+}
+
+// ListenerConfigResponse is the JSON response for each listener in /config.
+type ListenerConfigResponse struct {
+	ListenAddr string   `json:"listen_addr"`
+	Algorithm  string   `json:"algorithm,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// BackendConfigResponse is the JSON response for each backend in /config.
+type BackendConfigResponse struct {
+	Address        string            `json:"address"`
+	Weight         int               `json:"weight"`
+	Tags           []string          `json:"tags,omitempty"`
+	Zone           string            `json:"zone,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	MaxConnections int               `json:"max_connections"`
+	Cost           int               `json:"cost"`
+}
+
+// handleConfig handles /config requests and returns the effective running configuration.
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.lb == nil {
+		http.Error(w, "Load balancer not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	effective := s.lb.EffectiveConfig()
+	cfg := effective.Config
+
+	listeners := make([]ListenerConfigResponse, 0, len(cfg.Listeners))
+	for _, l := range cfg.Listeners {
+		listeners = append(listeners, ListenerConfigResponse{
+			ListenAddr: l.ListenAddr,
+			Algorithm:  l.Algorithm,
+			Tags:       l.Tags,
+		})
+	}
+
+	backends := make([]BackendConfigResponse, 0, len(cfg.Backends))
+	for _, b := range cfg.Backends {
+		backends = append(backends, BackendConfigResponse{
+			Address:        b.Address,
+			Weight:         b.Weight,
+			Tags:           b.Tags,
+			Zone:           b.Zone,
+			Labels:         b.Labels,
+			MaxConnections: b.MaxConnections,
+			Cost:           b.Cost,
+		})
+	}
+
+	response := ConfigResponse{
+		ListenAddr:                 cfg.ListenAddr,
+		Listeners:                  listeners,
+		Backends:                   backends,
+		HealthCheckIntervalSeconds: cfg.HealthCheckInterval.Seconds(),
+		ConnectTimeoutSeconds:      cfg.ConnectTimeout.Seconds(),
+		Algorithm:                  effective.Algorithm,
+		Draining:                   effective.Draining,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ScalingResponse is the JSON response for /scaling endpoint.
+type ScalingResponse struct {
+	Recommendation string  `json:"recommendation"`
+	Utilization    float64 `json:"utilization"`
+}
+
+// handleScaling handles /scaling requests and returns the current autoscaling recommendation.
+func (s *Server) handleScaling(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.lb == nil {
+		http.Error(w, "Load balancer not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	recommendation, utilization := s.lb.ScalingRecommendation()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ScalingResponse{
+		Recommendation: string(recommendation),
+		Utilization:    utilization,
+	})
+}
+
+// AlgorithmRequest is the JSON request body for PUT /algorithm.
+type AlgorithmRequest struct {
+	Algorithm string `json:"algorithm"`
+}
+
+// AlgorithmResponse is the JSON response for /algorithm endpoint.
+type AlgorithmResponse struct {
+	Algorithm string `json:"algorithm"`
+}
+
+// handleAlgorithm handles /algorithm requests for viewing (GET) and changing (PUT)
+// the load balancing algorithm.
+func (s *Server) handleAlgorithm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.lb == nil {
+		http.Error(w, "Load balancer not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		var req AlgorithmRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		algo, err := loadbalancer.NewAlgorithm(req.Algorithm)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s.lb.SetAlgorithm(algo)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AlgorithmResponse{Algorithm: s.lb.EffectiveConfig().Algorithm})
+}
+
+// HealthCheckResponse is the JSON response for POST /healthcheck.
+type HealthCheckResponse struct {
+	TotalBackends   int                     `json:"total_backends"`
+	HealthyBackends int                     `json:"healthy_backends"`
+	Backends        []BackendHealthResponse `json:"backends"`
+}
+
+// BackendHealthResponse is the JSON response for each backend in /healthcheck.
+type BackendHealthResponse struct {
+	Address        string    `json:"address"`
+	Alive          bool      `json:"alive"`
+	Reason         string    `json:"reason,omitempty"`
+	LastCheck      time.Time `json:"last_check"`
+	ResponseTimeMs float64   `json:"response_time_ms"`
+}
+
+// handleHealthCheck handles POST /healthcheck requests by health checking every
+// backend immediately and returning the results.
+func (s *Server) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.lb == nil {
+		http.Error(w, "Load balancer not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	status := s.lb.CheckAllBackends()
+
+	response := HealthCheckResponse{
+		TotalBackends:   status.TotalBackends,
+		HealthyBackends: status.HealthyBackends,
+		Backends:        make([]BackendHealthResponse, 0, len(status.Backends)),
+	}
+	for _, b := range status.Backends {
+		response.Backends = append(response.Backends, BackendHealthResponse{
+			Address:        b.Address,
+			Alive:          b.Alive,
+			Reason:         string(b.Reason),
+			LastCheck:      b.LastCheck,
+			ResponseTimeMs: float64(b.ResponseTime) / float64(time.Millisecond),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // GlobalStats tracks statistics across all backends.
 type GlobalStats struct {
 	TotalConnections   int64
@@ -136,7 +818,13 @@ type GlobalStats struct {
 	TotalBytesSent     int64
 	TotalBytesReceived int64
 	StartTime          time.Time
-	mu                 sync.RWMutex
+
+	// Throughput over a short rolling window, kept up to date by RunThroughputSampler
+	BytesPerSecondSent     float64
+	BytesPerSecondReceived float64
+
+	samples []byteSample
+	mu      sync.RWMutex
 }
 
 // NewGlobalStats creates a new GlobalStats instance.
@@ -194,6 +882,9 @@ func (gs *GlobalStats) GetSnapshot() GlobalStats {
 		TotalBytesSent:     gs.TotalBytesSent,
 		TotalBytesReceived: gs.TotalBytesReceived,
 		StartTime:          gs.StartTime,
+
+		BytesPerSecondSent:     gs.BytesPerSecondSent,
+		BytesPerSecondReceived: gs.BytesPerSecondReceived,
 	}
 }
 
@@ -204,3 +895,39 @@ func (gs *GlobalStats) Uptime() time.Duration {
 
 	return time.Since(gs.StartTime)
 }
+
+// DrainResponse is the JSON response for /drain.
+type DrainResponse struct {
+	Draining bool  `json:"draining"`
+	InFlight int64 `json:"in_flight"`
+	Drained  bool  `json:"drained"`
+}
+
+// handleDrain handles /drain requests. POST puts the load balancer into draining
+// state, refusing new connections while existing ones finish, and GET polls how
+// many connections remain.
+func (s *Server) handleDrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.lb == nil {
+		http.Error(w, "Load balancer not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var status loadbalancer.DrainStatus
+	if r.Method == http.MethodPost {
+		status = s.lb.BeginDrain()
+	} else {
+		status = s.lb.GetDrainStatus()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(DrainResponse{
+		Draining: status.Draining,
+		InFlight: status.InFlight,
+		Drained:  status.Drained(),
+	})
+}
@@ -0,0 +1,534 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"tcp_lb/backend"
+	"tcp_lb/config"
+	"tcp_lb/loadbalancer"
+)
+
+// newTestServer starts the admin endpoints for a load balancer built from cfg.
+func newTestServer(t *testing.T, cfg *config.Config) (*httptest.Server, *loadbalancer.LoadBalancer) {
+	t.Helper()
+
+	lb := loadbalancer.New(cfg)
+	s := NewServer(lb.GetPool(), "")
+	s.SetLoadBalancer(lb)
+	s.SetHealthCheckTimeout(time.Second)
+	s.SetDrainTimeout(time.Second)
+
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+
+	return ts, lb
+}
+
+// listenBackend starts a TCP listener that accepts and closes connections, so
+// health checks against it pass.
+func listenBackend(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// doJSON sends a request with body encoded as JSON and returns the response.
+func doJSON(t *testing.T, method, url string, body any) *http.Response {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func TestAddBackendHealthChecksAndAppliesConfig(t *testing.T) {
+	ts, lb := newTestServer(t, &config.Config{FailureThreshold: 1, BreakerCooldown: time.Minute})
+	addr := listenBackend(t)
+
+	resp := doJSON(t, http.MethodPost, ts.URL+"/backends", BackendRequest{Address: addr, Weight: 2})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+
+	var got BackendResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Alive || got.Weight != 2 {
+		t.Errorf("response = %+v, want alive with weight 2", got)
+	}
+
+	b := lb.GetPool().GetBackendByAddress(addr)
+	if b == nil {
+		t.Fatal("backend was not added to the pool")
+	}
+
+	// The configured circuit breaker applies to added backends too
+	b.RecordDialFailure()
+	if state := b.GetBreakerState(); state != backend.BreakerOpen {
+		t.Errorf("breaker state = %v, want open", state)
+	}
+}
+
+func TestAddBackendUnreachableIsDown(t *testing.T) {
+	ts, lb := newTestServer(t, &config.Config{})
+
+	// Reserve a port and close it so nothing is listening there
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	resp := doJSON(t, http.MethodPost, ts.URL+"/backends", BackendRequest{Address: addr, Weight: 1})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+
+	if b := lb.GetPool().GetBackendByAddress(addr); b == nil || b.IsAlive() {
+		t.Error("unreachable backend should be added marked down")
+	}
+}
+
+func TestAddBackendDuplicateConflicts(t *testing.T) {
+	addr := listenBackend(t)
+	ts, lb := newTestServer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+
+	resp := doJSON(t, http.MethodPost, ts.URL+"/backends", BackendRequest{Address: addr, Weight: 1})
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+	if n := lb.GetPool().Size(); n != 1 {
+		t.Errorf("pool size = %d, want 1", n)
+	}
+}
+
+func TestRemoveBackend(t *testing.T) {
+	addr := listenBackend(t)
+	ts, lb := newTestServer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+
+	resp := doJSON(t, http.MethodDelete, ts.URL+"/backends", BackendRequest{Address: "127.0.0.1:1"})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown address: status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+
+	resp = doJSON(t, http.MethodDelete, ts.URL+"/backends", BackendRequest{Address: addr})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if lb.GetPool().GetBackendByAddress(addr) != nil {
+		t.Error("backend is still in the pool")
+	}
+}
+
+func TestStatsReportsGlobalTotals(t *testing.T) {
+	globalStats := NewGlobalStats()
+	globalStats.IncrementConnections()
+	globalStats.IncrementConnections()
+	globalStats.DecrementActiveConnections()
+	globalStats.AddBytesSent(100)
+	globalStats.AddBytesReceived(250)
+
+	s := NewServer(backend.NewPool(), "")
+	s.SetGlobalStats(globalStats)
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	var got StatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.TotalConnections != 2 || got.ActiveConnections != 1 || got.TotalBytesSent != 100 || got.TotalBytesReceived != 250 {
+		t.Errorf("totals = %d connections (%d active), %d sent, %d received, want 2 (1), 100, 250",
+			got.TotalConnections, got.ActiveConnections, got.TotalBytesSent, got.TotalBytesReceived)
+	}
+}
+
+func TestLiveAndReady(t *testing.T) {
+	pool := backend.NewPool()
+	s := NewServer(pool, "")
+
+	get := func(path string) (int, HealthResponse) {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var got HealthResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		return rec.Code, got
+	}
+
+	// No healthy backends: live but not ready
+	down := backend.NewBackend("127.0.0.1:9001")
+	down.SetAlive(false)
+	pool.AddBackend(down)
+
+	if code, _ := get("/live"); code != http.StatusOK {
+		t.Errorf("/live with no healthy backends: status = %d, want %d", code, http.StatusOK)
+	}
+	code, got := get("/ready")
+	if code != http.StatusServiceUnavailable || got.Reason != "no healthy backends" {
+		t.Errorf("/ready with no healthy backends = %d %+v, want %d with a reason", code, got, http.StatusServiceUnavailable)
+	}
+
+	// One healthy backend: both report healthy
+	pool.AddBackend(backend.NewBackend("127.0.0.1:9002"))
+
+	for _, path := range []string{"/live", "/ready"} {
+		if code, got := get(path); code != http.StatusOK || got.Status != "healthy" {
+			t.Errorf("%s with a healthy backend = %d %+v, want %d healthy", path, code, got, http.StatusOK)
+		}
+	}
+}
+
+func TestAlgorithmEndpoint(t *testing.T) {
+	ts, _ := newTestServer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: "127.0.0.1:9001", Weight: 1}},
+	})
+
+	resp, err := http.Get(ts.URL + "/algorithm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var got AlgorithmResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || got.Algorithm != "round_robin" {
+		t.Errorf("GET = %d %q, want %d round_robin", resp.StatusCode, got.Algorithm, http.StatusOK)
+	}
+
+	for _, name := range []string{"least_connections", "weighted_round_robin", "ip_hash", "round_robin"} {
+		resp := doJSON(t, http.MethodPut, ts.URL+"/algorithm", AlgorithmRequest{Algorithm: name})
+
+		var got AlgorithmResponse
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || got.Algorithm != name {
+			t.Errorf("PUT %s = %d %q, want %d %s", name, resp.StatusCode, got.Algorithm, http.StatusOK, name)
+		}
+	}
+
+	resp = doJSON(t, http.MethodPut, ts.URL+"/algorithm", AlgorithmRequest{Algorithm: "fastest"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("PUT unknown algorithm: status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestForcedHealthCheckUpdatesBackends(t *testing.T) {
+	up := listenBackend(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := ln.Addr().String()
+	ln.Close()
+
+	ts, lb := newTestServer(t, &config.Config{
+		ConnectTimeout: time.Second,
+		Backends: []config.BackendConfig{
+			{Address: up, Weight: 1},
+			{Address: down, Weight: 1},
+		},
+	})
+
+	// Start from the opposite of the real states
+	lb.GetPool().GetBackendByAddress(up).SetAlive(false)
+
+	resp, err := http.Post(ts.URL+"/healthcheck", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var got HealthCheckResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || got.TotalBackends != 2 || got.HealthyBackends != 1 {
+		t.Fatalf("response = %d %+v, want 200 with 1 of 2 healthy", resp.StatusCode, got)
+	}
+
+	alive := map[string]bool{}
+	for _, b := range got.Backends {
+		alive[b.Address] = b.Alive
+	}
+	if !alive[up] || alive[down] {
+		t.Errorf("reported alive = %v, want only %s", alive, up)
+	}
+	if !lb.GetPool().GetBackendByAddress(up).IsAlive() || lb.GetPool().GetBackendByAddress(down).IsAlive() {
+		t.Error("backend states in the pool were not updated")
+	}
+
+	resp, err = http.Get(ts.URL + "/healthcheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestStatsFilterByAddress(t *testing.T) {
+	pool := backend.NewPool()
+	pool.AddBackend(backend.NewBackend("127.0.0.1:9001"))
+	pool.AddBackend(backend.NewBackend("127.0.0.1:9002"))
+	s := NewServer(pool, "")
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get("/stats?address=127.0.0.1:9002")
+	var one BackendStatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&one); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || one.Address != "127.0.0.1:9002" {
+		t.Errorf("matched address = %d %+v, want 200 with that backend's stats", rec.Code, one)
+	}
+
+	if rec := get("/stats?address=127.0.0.1:9999"); rec.Code != http.StatusNotFound {
+		t.Errorf("unmatched address: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = get("/stats")
+	var all StatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&all); err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Backends) != 2 {
+		t.Errorf("unfiltered stats list %d backends, want 2", len(all.Backends))
+	}
+}
+
+func TestStatsFilterByLabel(t *testing.T) {
+	pool := backend.NewPool()
+	for addr, labels := range map[string]map[string]string{
+		"127.0.0.1:9001": {"team": "x", "env": "prod"},
+		"127.0.0.1:9002": {"team": "x", "env": "staging"},
+		"127.0.0.1:9003": {"team": "y", "env": "prod"},
+		"127.0.0.1:9004": nil,
+	} {
+		b := backend.NewBackend(addr)
+		b.Labels = labels
+		pool.AddBackend(b)
+	}
+	s := NewServer(pool, "")
+
+	tests := []struct {
+		name   string
+		target string
+		want   []string
+	}{
+		{"one label", "/stats?label=team=x", []string{"127.0.0.1:9001", "127.0.0.1:9002"}},
+		{"every label", "/stats?label=team=x&label=env=prod", []string{"127.0.0.1:9001"}},
+		{"no match", "/stats?label=team=z", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+
+			var resp StatsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, b := range resp.Backends {
+				got = append(got, b.Address)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("backends = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats?label=team", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed label: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestDrainEndpoint(t *testing.T) {
+	ts, lb := newTestServer(t, &config.Config{})
+
+	get := func() DrainResponse {
+		t.Helper()
+
+		resp := doJSON(t, http.MethodGet, ts.URL+"/drain", nil)
+		var dr DrainResponse
+		if err := json.NewDecoder(resp.Body).Decode(&dr); err != nil {
+			t.Fatal(err)
+		}
+		return dr
+	}
+
+	if dr := get(); dr.Draining {
+		t.Fatalf("GET /drain = %+v before draining", dr)
+	}
+
+	resp := doJSON(t, http.MethodPost, ts.URL+"/drain", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /drain status = %d", resp.StatusCode)
+	}
+	var dr DrainResponse
+	if err := json.NewDecoder(resp.Body).Decode(&dr); err != nil {
+		t.Fatal(err)
+	}
+	if !dr.Draining || dr.InFlight != 0 || !dr.Drained {
+		t.Errorf("POST /drain = %+v, want drained with nothing in flight", dr)
+	}
+	if !lb.GetDrainStatus().Draining {
+		t.Error("load balancer not draining after POST /drain")
+	}
+	if dr := get(); !dr.Draining || !dr.Drained {
+		t.Errorf("GET /drain = %+v after POST, want drained", dr)
+	}
+
+	if resp := doJSON(t, http.MethodDelete, ts.URL+"/drain", nil); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE /drain status = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestSetBackendWeight(t *testing.T) {
+	addr := "10.0.0.1:80"
+	ts, lb := newTestServer(t, &config.Config{
+		Backends: []config.BackendConfig{{Address: addr, Weight: 1}},
+	})
+	b := lb.GetPool().GetBackendByAddress(addr)
+
+	tests := []struct {
+		name       string
+		addr       string
+		body       any
+		wantStatus int
+		wantWeight int
+	}{
+		{"valid change", addr, map[string]int{"weight": 5}, http.StatusOK, 5},
+		{"zero weight", addr, map[string]int{"weight": 0}, http.StatusOK, 0},
+		{"negative weight", addr, map[string]int{"weight": -1}, http.StatusBadRequest, 0},
+		{"missing weight", addr, map[string]int{}, http.StatusBadRequest, 0},
+		{"unknown address", "10.0.0.2:80", map[string]int{"weight": 3}, http.StatusNotFound, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := doJSON(t, http.MethodPut, ts.URL+"/backends/"+tt.addr+"/weight", tt.body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if w := b.GetWeight(); w != tt.wantWeight {
+				t.Errorf("weight = %d, want %d", w, tt.wantWeight)
+			}
+		})
+	}
+
+	if resp := doJSON(t, http.MethodPost, ts.URL+"/backends/"+addr+"/weight", map[string]int{"weight": 2}); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestStopRespectsShutdownTimeout(t *testing.T) {
+	s := NewServer(backend.NewPool(), "")
+	if s.shutdownTimeout != defaultShutdownTimeout {
+		t.Errorf("default shutdown timeout = %v, want %v", s.shutdownTimeout, defaultShutdownTimeout)
+	}
+	s.SetShutdownTimeout(-time.Second)
+	if s.shutdownTimeout != defaultShutdownTimeout {
+		t.Errorf("shutdown timeout after a negative value = %v, want the default", s.shutdownTimeout)
+	}
+
+	const timeout = 150 * time.Millisecond
+	s.SetShutdownTimeout(timeout)
+
+	// Serve a handler that outlives the shutdown timeout, standing in for a slow scrape
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.server = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+	go s.server.Serve(ln)
+
+	go http.Get("http://" + ln.Addr().String())
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("request never reached the handler")
+	}
+
+	start := time.Now()
+	err = s.Stop()
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop = %v, want the shutdown timeout to expire", err)
+	}
+	if elapsed < timeout || elapsed > 2*time.Second {
+		t.Errorf("Stop returned after %v, want about %v", elapsed, timeout)
+	}
+}
@@ -0,0 +1,68 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultStreamInterval is how often /stats/stream pushes a snapshot.
+const defaultStreamInterval = time.Second
+
+// SetStreamInterval sets how often /stats/stream pushes a stats snapshot. A
+// non-positive interval restores the 1 second default.
+func (s *Server) SetStreamInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultStreamInterval
+	}
+	s.streamInterval = interval
+}
+
+// handleStatsStream handles /stats/stream requests, pushing the /stats response
+// as a server-sent event every stream interval until the client disconnects or
+// the server stops. Like /stats, it accepts label=key=value filters.
+func (s *Server) handleStatsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	selectors := r.URL.Query()["label"]
+	if _, err := filterByLabels(nil, selectors); err != nil {
+		http.Error(w, "Invalid label filter: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ticker := time.NewTicker(s.streamInterval)
+	defer ticker.Stop()
+
+	for {
+		backendStats, _ := filterByLabels(s.pool.GetAllStats(), selectors)
+		data, err := json.Marshal(s.statsResponse(backendStats))
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+		}
+	}
+}
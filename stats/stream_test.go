@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tcp_lb/backend"
+)
+
+// startStreamServer serves s's endpoints, counting handlers that are still running
+// in active so tests can check streams end.
+func startStreamServer(t *testing.T, s *Server, active *atomic.Int64) *httptest.Server {
+	t.Helper()
+
+	handler := s.Handler()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		active.Add(1)
+		defer active.Add(-1)
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// readEvent reads the next server-sent event from r and decodes its data.
+func readEvent(t *testing.T, r *bufio.Reader) StatsResponse {
+	t.Helper()
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading event: %v", err)
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+
+		var resp StatsResponse
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
+			t.Fatalf("decoding event %q: %v", data, err)
+		}
+		return resp
+	}
+}
+
+func TestStatsStreamPushesSnapshots(t *testing.T) {
+	pool := backend.NewPool()
+	pool.AddBackend(backend.NewBackend("10.0.0.1:80"))
+	s := NewServer(pool, "")
+	s.SetStreamInterval(20 * time.Millisecond)
+
+	var active atomic.Int64
+	ts := startStreamServer(t, s, &active)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/stats/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	for i := range 2 {
+		if event := readEvent(t, r); len(event.Backends) != 1 {
+			t.Errorf("event %d has %d backends, want 1", i+1, len(event.Backends))
+		}
+	}
+
+	// Disconnecting ends the handler instead of leaking it
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for active.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("stream handler still running after the client disconnected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStatsStreamEndsOnStop(t *testing.T) {
+	s := NewServer(backend.NewPool(), "")
+	s.SetStreamInterval(time.Hour)
+
+	var active atomic.Int64
+	ts := startStreamServer(t, s, &active)
+
+	resp, err := http.Get(ts.URL + "/stats/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	readEvent(t, bufio.NewReader(resp.Body))
+
+	// The test owns the HTTP server, so end open streams the way Stop does
+	s.stopOnce.Do(func() { close(s.stopping) })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for active.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("stream handler still running after stop")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
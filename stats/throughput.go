@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"context"
+	"time"
+)
+
+// Throughput sampling defaults: the byte counters are sampled every
+// throughputInterval and rates are averaged over the last throughputSamples
+// samples, a five second window.
+const (
+	throughputInterval = time.Second
+	throughputSamples  = 6
+)
+
+// byteSample is the value of the global byte counters at one point in time.
+type byteSample struct {
+	at       time.Time
+	sent     int64
+	received int64
+}
+
+// RunThroughputSampler samples the byte counters every second until ctx is done,
+// keeping BytesPerSecondSent and BytesPerSecondReceived up to date. Bytes of TCP
+// connections are counted when the connection closes, so long transfers show up
+// in the rate once they finish.
+func (gs *GlobalStats) RunThroughputSampler(ctx context.Context) {
+	ticker := time.NewTicker(throughputInterval)
+	defer ticker.Stop()
+
+	gs.sampleThroughput(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			gs.sampleThroughput(now)
+		}
+	}
+}
+
+// sampleThroughput records the current byte counters at now and recomputes the
+// rates from the oldest sample still in the window.
+func (gs *GlobalStats) sampleThroughput(now time.Time) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.samples = append(gs.samples, byteSample{at: now, sent: gs.TotalBytesSent, received: gs.TotalBytesReceived})
+	if len(gs.samples) > throughputSamples {
+		gs.samples = gs.samples[len(gs.samples)-throughputSamples:]
+	}
+
+	oldest, newest := gs.samples[0], gs.samples[len(gs.samples)-1]
+	elapsed := newest.at.Sub(oldest.at).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	gs.BytesPerSecondSent = float64(newest.sent-oldest.sent) / elapsed
+	gs.BytesPerSecondReceived = float64(newest.received-oldest.received) / elapsed
+}
@@ -0,0 +1,63 @@
+package stats
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// approx reports whether got is within 1% of want.
+func approx(got, want float64) bool {
+	return math.Abs(got-want) <= want/100
+}
+
+func TestThroughputRate(t *testing.T) {
+	gs := NewGlobalStats()
+	now := time.Now()
+
+	// One sample gives no rate yet
+	gs.sampleThroughput(now)
+	if s := gs.GetSnapshot(); s.BytesPerSecondSent != 0 || s.BytesPerSecondReceived != 0 {
+		t.Fatalf("rate after one sample = %v/%v, want 0", s.BytesPerSecondSent, s.BytesPerSecondReceived)
+	}
+
+	// 1000 bytes sent and 500 received every second
+	for range 3 {
+		now = now.Add(time.Second)
+		gs.AddBytesSent(1000)
+		gs.AddBytesReceived(500)
+		gs.sampleThroughput(now)
+	}
+	if s := gs.GetSnapshot(); !approx(s.BytesPerSecondSent, 1000) || !approx(s.BytesPerSecondReceived, 500) {
+		t.Errorf("rate = %v sent, %v received, want 1000 and 500", s.BytesPerSecondSent, s.BytesPerSecondReceived)
+	}
+
+	// Once the window has moved past the old traffic only the new rate counts
+	for range throughputSamples {
+		now = now.Add(time.Second)
+		gs.AddBytesSent(4000)
+		gs.sampleThroughput(now)
+	}
+	if s := gs.GetSnapshot(); !approx(s.BytesPerSecondSent, 4000) || s.BytesPerSecondReceived != 0 {
+		t.Errorf("rate = %v sent, %v received, want 4000 and 0", s.BytesPerSecondSent, s.BytesPerSecondReceived)
+	}
+}
+
+func TestThroughputSamplerStopsWithContext(t *testing.T) {
+	gs := NewGlobalStats()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		gs.RunThroughputSampler(ctx)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sampler did not stop after the context was cancelled")
+	}
+}
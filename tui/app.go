@@ -6,6 +6,8 @@ import (
 	"math/rand"
 	"net"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"tcp_lb/backend"
@@ -18,28 +20,43 @@ import (
 
 // App represents the TUI application.
 type App struct {
-	app            *tview.Application
-	lb             *loadbalancer.LoadBalancer
-	pool           *backend.Pool
-	config         *config.Config
-	lbAddr         string
+	app    *tview.Application
+	lb     *loadbalancer.LoadBalancer
+	pool   *backend.Pool
+	config *config.Config
+	lbAddr string
 
 	// UI components
-	mainLayout     *tview.Flex
-	backendTable   *tview.Table
-	logView        *tview.TextView
-	statusBar      *tview.TextView
-	timersView     *tview.TextView
-	serverInfo     *tview.TextView
+	mainLayout   *tview.Flex
+	backendTable *tview.Table
+	logView      *tview.TextView
+	statusBar    *tview.TextView
+	timersView   *tview.TextView
+	serverInfo   *tview.TextView
 
 	// State
 	logs            []string
 	lastHealthCheck time.Time
-	currentAlgo     string
+	connHistory     *history           // Total active connections sampled every refresh tick
+	refreshInterval time.Duration      // How often refreshLoop redraws, changed from the UI goroutine
+	intervalChanges chan time.Duration // Passes refresh interval changes to refreshLoop
+	refreshFrozen   atomic.Bool        // Whether automatic refreshing is paused
+	done            chan struct{}      // Closed when the app stops, ending refreshLoop
+	stopOnce        sync.Once
 }
 
 // NewApp creates a new TUI application.
 func NewApp(lb *loadbalancer.LoadBalancer, cfg *config.Config) *App {
+	samples := cfg.SparklineSamples
+	if samples <= 0 {
+		samples = defaultSparklineSamples
+	}
+
+	refresh := cfg.TUIRefreshInterval
+	if refresh <= 0 {
+		refresh = defaultRefreshInterval
+	}
+
 	return &App{
 		app:             tview.NewApplication(),
 		lb:              lb,
@@ -48,7 +65,10 @@ func NewApp(lb *loadbalancer.LoadBalancer, cfg *config.Config) *App {
 		lbAddr:          cfg.ListenAddr,
 		logs:            make([]string, 0),
 		lastHealthCheck: time.Now(),
-		currentAlgo:     "Round Robin",
+		connHistory:     newHistory(samples),
+		refreshInterval: clampRefreshInterval(refresh),
+		intervalChanges: make(chan time.Duration, 1),
+		done:            make(chan struct{}),
 	}
 }
 
@@ -58,7 +78,7 @@ func (a *App) Run() error {
 	header := tview.NewTextView().
 		SetTextAlign(tview.AlignCenter).
 		SetDynamicColors(true).
-		SetText("[yellow::b]TCP LOAD BALANCER DASHBOARD[-:-:-]\n[gray]Press: [white]1[-] +1 conn | [white]2[-] +10 conn | [white]3[-] Algorithm | [white]r[-] Restart sim | [white]q[-] Quit")
+		SetText("[yellow::b]TCP LOAD BALANCER DASHBOARD[-:-:-]\n[gray]Press: [white]1[-] +1 conn | [white]2[-] +10 conn | [white]3[-] Algorithm | [white]p[-] Pause/resume selected | [white]r[-] Restart sim | [white]+/-[-] Refresh rate | [white]f[-] Freeze | [white]q[-] Quit")
 	header.SetBorder(true).SetBorderColor(tcell.ColorDarkCyan)
 
 	// Create server info panel
@@ -70,7 +90,8 @@ func (a *App) Run() error {
 	// Create backend table
 	a.backendTable = tview.NewTable().
 		SetBorders(true).
-		SetSelectable(false, false)
+		SetSelectable(true, false).
+		SetFixed(1, 0)
 	a.backendTable.SetTitle(" [::b]Backends ").SetBorder(true).SetBorderColor(tcell.ColorDarkCyan)
 	a.setupTableHeaders()
 
@@ -99,7 +120,7 @@ func (a *App) Run() error {
 
 	// Right panel with timers and log
 	rightPanel := tview.NewFlex().SetDirection(tview.FlexRow).
-		AddItem(a.timersView, 8, 0, false).
+		AddItem(a.timersView, 9, 0, false).
 		AddItem(a.logView, 0, 1, false)
 
 	// Main content area
@@ -119,7 +140,7 @@ func (a *App) Run() error {
 		case tcell.KeyRune:
 			switch event.Rune() {
 			case 'q', 'Q':
-				a.app.Stop()
+				a.Stop()
 				return nil
 			case '1':
 				go a.sendTraffic()
@@ -130,12 +151,24 @@ func (a *App) Run() error {
 			case '3':
 				a.showAlgorithmModal()
 				return nil
+			case 'p', 'P':
+				a.toggleSelectedPause()
+				return nil
 			case 'r', 'R':
 				a.restartSimulation()
 				return nil
+			case '+':
+				a.setRefreshInterval(a.refreshInterval / 2)
+				return nil
+			case '-':
+				a.setRefreshInterval(a.refreshInterval * 2)
+				return nil
+			case 'f', 'F':
+				a.toggleRefreshFrozen()
+				return nil
 			}
 		case tcell.KeyEscape:
-			a.app.Stop()
+			a.Stop()
 			return nil
 		}
 		return event
@@ -149,6 +182,12 @@ func (a *App) Run() error {
 				a.addLog(fmt.Sprintf("[red]💥 Server CRASHED: %s[-] [gray](LB unaware, status still Healthy)[-]", event.Backend))
 			case backend.EventBackendRecovered:
 				a.addLog(fmt.Sprintf("[yellow]⏳ Server READY: %s[-] [gray](awaiting health check)[-]", event.Backend))
+			case backend.EventBackendUnhealthy:
+				a.addLog(fmt.Sprintf("[red]✗ Server UNHEALTHY: %s[-] [gray](connection or health check failed, marked down)[-]", event.Backend))
+			case backend.EventBackendPaused:
+				a.addLog(fmt.Sprintf("[fuchsia]⏸ Server PAUSED: %s[-] [gray](manual)[-]", event.Backend))
+			case backend.EventBackendResumed:
+				a.addLog(fmt.Sprintf("[fuchsia]▶ Server RESUMED: %s[-] [gray](manual, awaiting health check)[-]", event.Backend))
 			}
 		})
 	})
@@ -160,14 +199,25 @@ func (a *App) Run() error {
 	a.addLog(fmt.Sprintf("[gray]Load balancer on %s[-]", a.lbAddr))
 
 	// Start background refresh
-	go a.refreshLoop()
+	go a.refreshLoop(a.refreshInterval)
+
+	err := a.app.SetRoot(a.mainLayout, true).SetFocus(a.backendTable).EnableMouse(true).Run()
 
-	return a.app.SetRoot(a.mainLayout, true).EnableMouse(true).Run()
+	// The application can also stop on its own, e.g. on Ctrl-C
+	a.Stop()
+	return err
+}
+
+// Stop stops the TUI application and its background refresh. It is safe to call
+// more than once.
+func (a *App) Stop() {
+	a.stopOnce.Do(func() { close(a.done) })
+	a.app.Stop()
 }
 
 // setupTableHeaders creates the table header row.
 func (a *App) setupTableHeaders() {
-	headers := []string{"Address", "Status", "Active", "Share", "Total", "Last Check"}
+	headers := []string{"Address", "Status", "Weight", "Active", "Share", "Total", "Last Check"}
 	for i, h := range headers {
 		a.backendTable.SetCell(0, i,
 			tview.NewTableCell(h).
@@ -178,17 +228,43 @@ func (a *App) setupTableHeaders() {
 	}
 }
 
-// refreshLoop updates the UI periodically.
-func (a *App) refreshLoop() {
-	ticker := time.NewTicker(200 * time.Millisecond)
-	defer ticker.Stop()
-
-	for range ticker.C {
+// refreshLoop updates the UI every interval, or the interval last set with
+// setRefreshInterval, until the app stops, skipping ticks while refreshing is frozen.
+func (a *App) refreshLoop(interval time.Duration) {
+	a.runRefreshLoop(interval, func() {
 		a.app.QueueUpdateDraw(func() {
 			a.refreshBackends()
 			a.refreshTimers()
+			a.recordActiveConnections()
+			a.refreshServerInfo()
 			a.updateStatusBar()
 		})
+	})
+}
+
+// runRefreshLoop calls redraw every interval until the app stops, resetting its
+// ticker when the interval changes and skipping ticks while refreshing is frozen.
+// The interval is passed in rather than read from the App, which the UI goroutine
+// may be changing.
+func (a *App) runRefreshLoop(interval time.Duration, redraw func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.done:
+			return
+		case interval := <-a.intervalChanges:
+			ticker.Reset(interval)
+			continue
+		case <-ticker.C:
+		}
+
+		if a.refreshFrozen.Load() {
+			continue
+		}
+
+		redraw()
 	}
 }
 
@@ -221,17 +297,25 @@ func (a *App) refreshBackends() {
 		status := "[green]Healthy[-]"
 		if !alive {
 			status = "[red]Down[-]"
+			if reason := b.GetDownReason(); reason != backend.ReasonNone {
+				status = fmt.Sprintf("[red]Down[-] [gray](%s)[-]", reason)
+			}
 		}
 		a.backendTable.SetCell(row, 1,
 			tview.NewTableCell(status).
 				SetAlign(tview.AlignCenter))
 
+		// Configured weight
+		a.backendTable.SetCell(row, 2,
+			tview.NewTableCell(fmt.Sprintf("%d", b.GetWeight())).
+				SetAlign(tview.AlignCenter))
+
 		// Active connections with highlight if > 0
 		activeStr := fmt.Sprintf("%d", active)
 		if active > 0 {
 			activeStr = fmt.Sprintf("[yellow::b]%d[-:-:-]", active)
 		}
-		a.backendTable.SetCell(row, 2,
+		a.backendTable.SetCell(row, 3,
 			tview.NewTableCell(activeStr).
 				SetAlign(tview.AlignCenter))
 
@@ -241,18 +325,18 @@ func (a *App) refreshBackends() {
 			share := float64(active) / float64(totalActive) * 100
 			shareStr = fmt.Sprintf("%.1f%%", share)
 		}
-		a.backendTable.SetCell(row, 3,
+		a.backendTable.SetCell(row, 4,
 			tview.NewTableCell(shareStr).
 				SetAlign(tview.AlignCenter))
 
 		// Total connections
-		a.backendTable.SetCell(row, 4,
+		a.backendTable.SetCell(row, 5,
 			tview.NewTableCell(fmt.Sprintf("%d", total)).
 				SetAlign(tview.AlignCenter))
 
 		// Last check (relative time)
 		ago := time.Since(lastCheck).Round(time.Second)
-		a.backendTable.SetCell(row, 5,
+		a.backendTable.SetCell(row, 6,
 			tview.NewTableCell(fmt.Sprintf("%v ago", ago)).
 				SetAlign(tview.AlignCenter).
 				SetTextColor(tcell.ColorGray))
@@ -264,33 +348,40 @@ func (a *App) refreshTimers() {
 	var text strings.Builder
 
 	// Health Check Timer
-	elapsed := time.Since(a.lastHealthCheck)
-	remaining := a.config.HealthCheckInterval - elapsed
-	if remaining < 0 {
-		remaining = 0
-	}
-
-	progress := float64(elapsed) / float64(a.config.HealthCheckInterval)
-	if progress > 1 {
-		progress = 1
-	}
 	barWidth := 16
-	filled := int(progress * float64(barWidth))
-	healthBar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+	text.WriteString(fmt.Sprintf("[yellow::b]Health Check[-:-:-]\n"))
 
-	healthColor := "green"
-	if remaining < 2*time.Second {
-		healthColor = "yellow"
-	}
+	if a.config.HealthCheckInterval <= 0 {
+		text.WriteString("[gray]Disabled[-]\n\n")
+	} else {
+		elapsed := time.Since(a.lastHealthCheck)
+		remaining := a.config.HealthCheckInterval - elapsed
+		if remaining < 0 {
+			remaining = 0
+		}
 
-	text.WriteString(fmt.Sprintf("[yellow::b]Health Check[-:-:-]\n"))
-	text.WriteString(fmt.Sprintf("[%s]%v[-] %s\n\n", healthColor, remaining.Round(time.Second), healthBar))
+		progress := float64(elapsed) / float64(a.config.HealthCheckInterval)
+		if progress > 1 {
+			progress = 1
+		}
+		filled := int(progress * float64(barWidth))
+		healthBar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+
+		healthColor := "green"
+		if remaining < 2*time.Second {
+			healthColor = "yellow"
+		}
+
+		text.WriteString(fmt.Sprintf("[%s]%v[-] %s\n\n", healthColor, remaining.Round(time.Second), healthBar))
+	}
 
 	// Server Pause Timer
 	pausedBackend, pauseStart, pauseDuration, nextPause := a.pool.GetPauseState()
 
 	text.WriteString("[yellow::b]Server Pause[-:-:-]\n")
-	if pausedBackend != "" {
+	if a.config.SimDisabled {
+		text.WriteString("[gray]Simulation disabled[-]")
+	} else if pausedBackend != "" {
 		// Currently paused - show recovery countdown
 		pauseElapsed := time.Since(pauseStart)
 		pauseRemaining := pauseDuration - pauseElapsed
@@ -316,6 +407,11 @@ func (a *App) refreshTimers() {
 		text.WriteString(fmt.Sprintf("[gray]Next pause in %v[-]", untilNextPause.Round(time.Second)))
 	}
 
+	// Manual pauses are shown separately from the random simulation
+	if manual := a.pool.GetManualPauses(); len(manual) > 0 {
+		text.WriteString(fmt.Sprintf("\n[fuchsia]Manually paused:[-] %s", strings.Join(manual, ", ")))
+	}
+
 	a.timersView.SetText(text.String())
 }
 
@@ -324,18 +420,29 @@ func (a *App) updateStatusBar() {
 	backends := a.pool.GetBackends()
 	healthy := 0
 	totalConns := 0
+	totalWeight := 0
 	for _, b := range backends {
 		if b.IsAlive() {
 			healthy++
 		}
 		totalConns += b.GetActiveConnections()
+		totalWeight += b.GetWeight()
 	}
 
-	status := fmt.Sprintf(" [green]●[-] %d/%d backends | [yellow]%d[-] active connections | Algorithm: [cyan]%s[-] ",
-		healthy, len(backends), totalConns, a.currentAlgo)
+	status := fmt.Sprintf(" [green]●[-] %d/%d backends | [yellow]%d[-] active connections [yellow]%s[-] | Total weight: [cyan]%d[-] | Algorithm: [cyan]%s[-] | Refresh: %s ",
+		healthy, len(backends), totalConns, sparkline(a.connHistory.values()), totalWeight, a.algorithmName(), a.refreshStatus())
 	a.statusBar.SetText(status)
 }
 
+// recordActiveConnections samples the total active connections for the sparkline.
+func (a *App) recordActiveConnections() {
+	total := 0
+	for _, b := range a.pool.GetBackends() {
+		total += b.GetActiveConnections()
+	}
+	a.connHistory.add(total)
+}
+
 // refreshServerInfo updates the server info display.
 func (a *App) refreshServerInfo() {
 	a.serverInfo.SetText(fmt.Sprintf(
@@ -345,7 +452,7 @@ func (a *App) refreshServerInfo() {
 			"[white]Health Interval:[gray] %v\n"+
 			"[white]Connect Timeout:[gray] %v",
 		a.lbAddr,
-		a.currentAlgo,
+		a.algorithmName(),
 		a.config.HealthCheckInterval,
 		a.config.ConnectTimeout,
 	))
@@ -370,8 +477,10 @@ func (a *App) sendTraffic() {
 		return
 	}
 
-	welcome = strings.TrimSpace(welcome)
-	backendAddr := strings.TrimPrefix(welcome, "Connected to Backend ")
+	backendAddr, ok := backend.ParseWelcome(welcome)
+	if !ok {
+		backendAddr = strings.TrimSpace(welcome)
+	}
 
 	// Random duration 10-70 seconds
 	duration := time.Duration(10+rand.Intn(61)) * time.Second
@@ -393,12 +502,61 @@ func (a *App) sendBurstTraffic(count int) {
 	}
 }
 
+// toggleSelectedPause manually pauses or resumes the backend highlighted in the table.
+func (a *App) toggleSelectedPause() {
+	row, _ := a.backendTable.GetSelection()
+	backends := a.pool.GetBackends()
+	if row < 1 || row > len(backends) {
+		return
+	}
+
+	a.lb.ToggleManualPause(backends[row-1].Address)
+}
+
+// reportBindErrors logs each backend echo server that could not bind its address,
+// so it is not mistaken for a backend that went down at runtime.
+func (a *App) reportBindErrors(errs <-chan error) {
+	for err := range errs {
+		a.app.QueueUpdateDraw(func() {
+			a.addLog(fmt.Sprintf("[red]✗ Server FAILED TO BIND: %v[-]", err))
+		})
+	}
+}
+
 // restartSimulation restarts the failure simulation.
 func (a *App) restartSimulation() {
+	if a.config.SimDisabled {
+		a.addLog("[gray]Simulation is disabled[-]")
+		return
+	}
+
 	a.pool.RestartSimulation()
 	a.addLog("[cyan]↻ Simulation restarted[-]")
 }
 
+// algorithmDisplayNames maps algorithm names to how the TUI shows them.
+var algorithmDisplayNames = map[string]string{
+	"round_robin":                "Round Robin",
+	"least_connections":          "Least Connections",
+	"weighted_round_robin":       "Weighted Round Robin",
+	"weighted_least_connections": "Weighted Least Connections",
+	"lowest_cost":                "Lowest Cost",
+	"weighted_random":            "Weighted Random",
+	"least_loaded":               "Least Loaded",
+	"ip_hash":                    "IP Hash",
+}
+
+// algorithmName returns the display name of the load balancer's active algorithm.
+// It is read from the load balancer on every render, so the configured algorithm
+// and changes made through the stats API show up too.
+func (a *App) algorithmName() string {
+	name := a.lb.AlgorithmName()
+	if display, ok := algorithmDisplayNames[name]; ok {
+		return display
+	}
+	return name
+}
+
 // showAlgorithmModal displays a modal to select the load balancing algorithm.
 func (a *App) showAlgorithmModal() {
 	algorithms := []struct {
@@ -408,12 +566,16 @@ func (a *App) showAlgorithmModal() {
 		{"Round Robin", loadbalancer.NewRoundRobin()},
 		{"Least Connections", loadbalancer.NewLeastConnections()},
 		{"Weighted Round Robin", loadbalancer.NewWeightedRoundRobin()},
+		{"Weighted Least Connections", loadbalancer.NewWeightedLeastConnections()},
+		{"Weighted Random", loadbalancer.NewWeightedRandom()},
+		{"Least Loaded", loadbalancer.NewLeastLoaded(loadbalancer.DefaultLoadWeights)},
+		{"IP Hash", loadbalancer.NewIPHash()},
 	}
 
 	list := tview.NewList()
 	for i, alg := range algorithms {
 		name := alg.name
-		if name == a.currentAlgo {
+		if alg.algo.Name() == a.lb.AlgorithmName() {
 			name = "[cyan]" + name + " (active)[-]"
 		}
 		list.AddItem(name, "", rune('1'+i), nil)
@@ -422,15 +584,14 @@ func (a *App) showAlgorithmModal() {
 	list.SetSelectedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
 		selected := algorithms[index]
 		a.lb.SetAlgorithm(selected.algo)
-		a.currentAlgo = selected.name
 		a.refreshServerInfo()
 		a.addLog(fmt.Sprintf("[green]Algorithm changed to: %s[-]", selected.name))
-		a.app.SetRoot(a.mainLayout, true)
+		a.app.SetRoot(a.mainLayout, true).SetFocus(a.backendTable)
 	})
 
 	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyEscape {
-			a.app.SetRoot(a.mainLayout, true)
+			a.app.SetRoot(a.mainLayout, true).SetFocus(a.backendTable)
 			return nil
 		}
 		return event
@@ -443,7 +604,7 @@ func (a *App) showAlgorithmModal() {
 		AddItem(nil, 0, 1, false).
 		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
 			AddItem(nil, 0, 1, false).
-			AddItem(list, 7, 0, true).
+			AddItem(list, len(algorithms)*2+2, 0, true).
 			AddItem(nil, 0, 1, false), 40, 0, true).
 		AddItem(nil, 0, 1, false)
 
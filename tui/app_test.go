@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	"tcp_lb/config"
+	"tcp_lb/loadbalancer"
+)
+
+// newLoopApp returns an App with only the state refreshLoop uses, frozen so that
+// ticks never reach the (absent) tview application.
+func newLoopApp() *App {
+	a := &App{
+		refreshInterval: defaultRefreshInterval,
+		intervalChanges: make(chan time.Duration, 1),
+		done:            make(chan struct{}),
+	}
+	a.refreshFrozen.Store(true)
+	return a
+}
+
+func TestRefreshLoopReturnsWhenStopped(t *testing.T) {
+	a := newLoopApp()
+
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		a.refreshLoop(time.Millisecond)
+	}()
+
+	// Let it tick a few times, then stop it the way Stop does
+	time.Sleep(20 * time.Millisecond)
+	a.stopOnce.Do(func() { close(a.done) })
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("refreshLoop still running after the app stopped")
+	}
+
+	// Stopping again is a no-op rather than a double close
+	a.stopOnce.Do(func() { close(a.done) })
+}
+
+func TestAlgorithmNameFollowsLoadBalancer(t *testing.T) {
+	lb := loadbalancer.New(&config.Config{
+		ListenAddr: "127.0.0.1:0",
+		Backends:   []config.BackendConfig{{Address: "127.0.0.1:9001", Weight: 1}},
+	})
+	a := &App{lb: lb}
+
+	if got := a.algorithmName(); got != "Round Robin" {
+		t.Errorf("algorithmName = %q, want Round Robin", got)
+	}
+
+	// Changed outside the TUI, e.g. through the stats API
+	lb.SetAlgorithm(loadbalancer.NewIPHash())
+	if got := a.algorithmName(); got != "IP Hash" {
+		t.Errorf("algorithmName = %q after switching to ip_hash, want IP Hash", got)
+	}
+}
@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+)
+
+// Dashboard refresh intervals. The +/- keys halve or double the interval within
+// these bounds.
+const (
+	defaultRefreshInterval = 200 * time.Millisecond
+	minRefreshInterval     = 50 * time.Millisecond
+	maxRefreshInterval     = 5 * time.Second
+)
+
+// clampRefreshInterval keeps interval within the supported refresh bounds.
+func clampRefreshInterval(interval time.Duration) time.Duration {
+	return min(max(interval, minRefreshInterval), maxRefreshInterval)
+}
+
+// setRefreshInterval changes how often refreshLoop redraws the dashboard. It must
+// be called from the UI goroutine.
+func (a *App) setRefreshInterval(interval time.Duration) {
+	a.queueRefreshInterval(interval)
+
+	a.addLog(fmt.Sprintf("[cyan]⟳ Refreshing every %v[-]", a.refreshInterval))
+	a.updateStatusBar()
+}
+
+// queueRefreshInterval clamps and records interval, and passes it on to
+// refreshLoop, which recreates its ticker at the next opportunity.
+func (a *App) queueRefreshInterval(interval time.Duration) {
+	a.refreshInterval = clampRefreshInterval(interval)
+
+	// Replace any change refreshLoop has not picked up yet, only the latest matters
+	select {
+	case <-a.intervalChanges:
+	default:
+	}
+	a.intervalChanges <- a.refreshInterval
+}
+
+// toggleRefreshFrozen pauses or resumes automatic refreshing, so the view can be
+// inspected without it changing.
+func (a *App) toggleRefreshFrozen() {
+	frozen := !a.refreshFrozen.Load()
+	a.refreshFrozen.Store(frozen)
+
+	if frozen {
+		a.addLog("[cyan]❄ Auto-refresh frozen[-]")
+	} else {
+		a.addLog("[cyan]⟳ Auto-refresh resumed[-]")
+	}
+	a.updateStatusBar()
+}
+
+// refreshStatus describes the refresh state for the status bar.
+func (a *App) refreshStatus() string {
+	if a.refreshFrozen.Load() {
+		return "[red]FROZEN[-]"
+	}
+	return fmt.Sprintf("every [cyan]%v[-]", a.refreshInterval)
+}
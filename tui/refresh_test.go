@@ -0,0 +1,99 @@
+package tui
+
+import (
+	"testing"
+	"time"
+)
+
+// startRefreshLoop runs a's refresh loop at interval with a redraw that reports
+// each call on the returned channel. The loop is stopped when the test ends.
+func startRefreshLoop(t *testing.T, a *App, interval time.Duration) <-chan time.Time {
+	t.Helper()
+
+	redraws := make(chan time.Time, 100)
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		a.runRefreshLoop(interval, func() {
+			select {
+			case redraws <- time.Now():
+			default:
+			}
+		})
+	}()
+	t.Cleanup(func() {
+		a.stopOnce.Do(func() { close(a.done) })
+		<-returned
+	})
+
+	return redraws
+}
+
+func TestQueueRefreshIntervalClampsAndKeepsLatest(t *testing.T) {
+	a := newLoopApp()
+
+	a.queueRefreshInterval(time.Millisecond)
+	if a.refreshInterval != minRefreshInterval {
+		t.Errorf("interval = %v, want it clamped to %v", a.refreshInterval, minRefreshInterval)
+	}
+
+	// A second change before the loop picks up the first replaces it
+	a.queueRefreshInterval(time.Minute)
+	if a.refreshInterval != maxRefreshInterval {
+		t.Errorf("interval = %v, want it clamped to %v", a.refreshInterval, maxRefreshInterval)
+	}
+	if got := <-a.intervalChanges; got != maxRefreshInterval {
+		t.Errorf("queued interval = %v, want %v", got, maxRefreshInterval)
+	}
+	select {
+	case got := <-a.intervalChanges:
+		t.Errorf("stale interval %v still queued", got)
+	default:
+	}
+}
+
+func TestRefreshIntervalChangeResetsTicker(t *testing.T) {
+	a := newLoopApp()
+	a.refreshFrozen.Store(false)
+	redraws := startRefreshLoop(t, a, maxRefreshInterval)
+
+	select {
+	case <-redraws:
+		t.Fatal("redrew before the initial interval passed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The new, much shorter interval takes effect without waiting out the old one
+	a.queueRefreshInterval(minRefreshInterval)
+	start := time.Now()
+	var last time.Time
+	for range 4 {
+		select {
+		case last = <-redraws:
+		case <-time.After(time.Second):
+			t.Fatal("no redraw at the new interval")
+		}
+	}
+
+	if avg := last.Sub(start) / 4; avg < minRefreshInterval*3/4 || avg > 3*minRefreshInterval {
+		t.Errorf("redraws every %v on average, want about %v", avg, minRefreshInterval)
+	}
+}
+
+func TestFrozenRefreshSkipsRedraws(t *testing.T) {
+	a := newLoopApp()
+	redraws := startRefreshLoop(t, a, minRefreshInterval)
+
+	select {
+	case <-redraws:
+		t.Fatal("redrew while frozen")
+	case <-time.After(4 * minRefreshInterval):
+	}
+
+	a.refreshFrozen.Store(false)
+	select {
+	case <-redraws:
+	case <-time.After(time.Second):
+		t.Fatal("no redraw after unfreezing")
+	}
+}
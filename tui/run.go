@@ -1,19 +1,30 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"tcp_lb/backend"
 	"tcp_lb/config"
 	"tcp_lb/loadbalancer"
+	"tcp_lb/stats"
 )
 
-// Run starts the TUI application with all required components.
-func Run() error {
+// drainTimeout is how long active connections may take to finish on SIGTERM.
+const drainTimeout = 30 * time.Second
+
+// shutdownTimeout is how long active connections may take to finish after quitting.
+const shutdownTimeout = 5 * time.Second
+
+// Run starts the TUI application with all required components, loading
+// configuration from configPath.
+func Run(configPath string) error {
 	// Ensure TERM is set for WSL2 compatibility
 	if os.Getenv("TERM") == "" {
 		os.Setenv("TERM", "xterm-256color")
@@ -24,15 +35,31 @@ func Run() error {
 	log.SetFlags(0)
 
 	// Load configuration
-	cfg, err := config.LoadConfig("config.json")
+	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
-		fmt.Printf("Could not load config.json: %v\n", err)
+		fmt.Printf("Could not load %s: %v\n", configPath, err)
 		fmt.Println("Using default configuration")
 		cfg = config.DefaultConfig()
 	}
 
 	// Create and start load balancer
 	lb := loadbalancer.New(cfg)
+	globalStats := stats.NewGlobalStats()
+	lb.SetGlobalStats(globalStats)
+
+	// Serve stats alongside the dashboard when configured
+	var statsServer *stats.Server
+	if cfg.StatsAddr != "" {
+		statsServer = stats.NewServer(lb.GetPool(), cfg.StatsAddr)
+		statsServer.SetLoadBalancer(lb)
+		statsServer.SetShutdownTimeout(cfg.StatsShutdownTimeout)
+		statsServer.SetStreamInterval(cfg.StatsStreamInterval)
+		statsServer.SetGlobalStats(globalStats)
+		samplerCtx, stopSampler := context.WithCancel(context.Background())
+		defer stopSampler()
+		go globalStats.RunThroughputSampler(samplerCtx)
+		go statsServer.Start()
+	}
 	go func() {
 		if err := lb.Start(); err != nil {
 			fmt.Fprintf(os.Stderr, "Load balancer error: %v\n", err)
@@ -40,27 +67,61 @@ func Run() error {
 		}
 	}()
 
-	// Start backend servers (using pool backends for shared state)
-	for _, b := range lb.GetPool().GetBackends() {
-		go func(b *backend.Backend) {
-			backend.StartServer(b)
-		}(b)
+	// Start backend servers (using pool backends for shared state), unless the
+	// simulation is disabled to monitor real backends
+	backends := lb.GetPool().GetBackends()
+	bindErrs := make(chan error, len(backends))
+	if !cfg.SimDisabled {
+		for _, b := range backends {
+			go func(b *backend.Backend) {
+				// StartServer only returns when the address could not be bound
+				if err := backend.StartServer(b); err != nil {
+					bindErrs <- err
+				}
+			}(b)
+		}
 	}
 
 	// Give servers and lb time to start
 	time.Sleep(200 * time.Millisecond)
 
-	// Start backend failure simulation
-	go lb.GetPool().SimulateRandomBackendFailureAndRecoveryLoop()
+	// Start backend failure simulation, stopped when the TUI exits
+	simCtx, stopSimulation := context.WithCancel(context.Background())
+	defer stopSimulation()
+	if !cfg.SimDisabled {
+		lb.GetPool().SetSimulationTiming(cfg.SimInitialDelay, cfg.SimPauseMin, cfg.SimPauseMax, cfg.SimGap)
+		if cfg.SimSeed != 0 {
+			lb.GetPool().SetSimulationSeed(cfg.SimSeed)
+		}
+		go lb.GetPool().SimulateRandomBackendFailureAndRecoveryLoop(simCtx)
+	}
 
 	// Create and run TUI
 	app := NewApp(lb, cfg)
-	if err := app.Run(); err != nil {
-		lb.Stop()
-		return err
-	}
+	go app.reportBindErrors(bindErrs)
+
+	// Drain active connections on SIGTERM before shutting down
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		lb.Drain(drainTimeout)
+		app.Stop()
+	}()
+
+	runErr := app.Run()
 
 	// Cleanup
-	lb.Stop()
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := lb.Stop(ctx); err != nil {
+		fmt.Printf("Shutdown: %v\n", err)
+	}
+
+	if statsServer != nil {
+		statsServer.Stop()
+	}
+
+	return runErr
 }
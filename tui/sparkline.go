@@ -0,0 +1,56 @@
+package tui
+
+import "strings"
+
+// defaultSparklineSamples is how many samples the connection sparkline shows when
+// sparkline_samples is not set.
+const defaultSparklineSamples = 40
+
+// sparkLevels are the bar heights of a sparkline, lowest first.
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// history is a fixed-size ring buffer of samples, overwriting the oldest once full.
+type history struct {
+	samples []int
+	next    int  // Index the next sample is written to
+	full    bool // Whether every slot holds a sample
+}
+
+// newHistory creates a history holding up to size samples.
+func newHistory(size int) *history {
+	return &history{samples: make([]int, size)}
+}
+
+// add records a sample, dropping the oldest when the history is full.
+func (h *history) add(value int) {
+	h.samples[h.next] = value
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// values returns the recorded samples, oldest first.
+func (h *history) values() []int {
+	if !h.full {
+		return append([]int(nil), h.samples[:h.next]...)
+	}
+
+	return append(append([]int(nil), h.samples[h.next:]...), h.samples[:h.next]...)
+}
+
+// sparkline renders values as a row of bars scaled to the largest value.
+func sparkline(values []int) string {
+	peak := 1
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+
+	var line strings.Builder
+	for _, v := range values {
+		level := v * (len(sparkLevels) - 1) / peak
+		line.WriteRune(sparkLevels[level])
+	}
+
+	return line.String()
+}